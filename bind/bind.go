@@ -0,0 +1,293 @@
+package bind
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/oldercn/restclient-go-sdk/mychain/mychain-sdk-go/common/codec/contract/abi"
+)
+
+// TransactOpts carries the per call parameters every contract call of RestClient needs.
+type TransactOpts struct {
+	BizId    string
+	OrderId  string
+	Account  string
+	TenantId string
+	KmsId    string
+	Gas      int64 // 0表示不受限
+	IsLocal  bool  // 本地执行,不发送交易
+}
+
+type tmplData struct {
+	Package  string
+	Type     string
+	InputABI string
+	InputBin string
+	Methods  []*tmplMethod
+	Events   []*tmplEvent
+	Structs  []*tmplStruct
+}
+
+type tmplMethod struct {
+	Name      string
+	Original  string
+	Signature string
+	Const     bool // called locally, see RestClient.CallSolcContractLocal
+	Inputs    []*tmplField
+	Outputs   []*tmplField
+}
+
+type tmplEvent struct {
+	Name      string
+	Original  string
+	Signature string
+	Fields    []*tmplField
+	Indexed   []*tmplField
+}
+
+type tmplField struct {
+	Name    string
+	Param   string
+	Type    string
+	Raw     string
+	Index   int
+	Indexed bool
+}
+
+type tmplStruct struct {
+	Name   string
+	Fields []*tmplField
+}
+
+// Bind generates a typed Go package wrapping the contract described by abiJSON.
+// bytecode is optional, the deploy function is only generated when it is given and
+// the constructor takes no arguments.
+func Bind(pkg, typeName, abiJSON, bytecode string) (string, error) {
+	parsed, err := abi.JSON(strings.NewReader(abiJSON))
+	if err != nil {
+		return "", fmt.Errorf("fail to parse abi,err:%+v", err)
+	}
+	if pkg == "" {
+		return "", fmt.Errorf("package name is empty")
+	}
+	if typeName == "" {
+		return "", fmt.Errorf("type name is empty")
+	}
+	typeName = capitalise(typeName)
+	if bytecode != "" && len(parsed.Constructor.Inputs) > 0 {
+		// DEPLOYCONTRACTFORBIZ only takes the bytecode, Deploy could not pass the inputs
+		return "", fmt.Errorf("constructor takes %d arguments,deploying it is not supported,generate the binding without bytecode", len(parsed.Constructor.Inputs))
+	}
+
+	structs := make(map[string]*tmplStruct)
+	data := &tmplData{
+		Package:  pkg,
+		Type:     typeName,
+		InputABI: strings.TrimSpace(abiJSON),
+		InputBin: strings.TrimPrefix(strings.TrimSpace(bytecode), "0x"),
+	}
+
+	sigs := make([]string, 0, len(parsed.Methods))
+	for sig := range parsed.Methods {
+		sigs = append(sigs, sig)
+	}
+	sort.Strings(sigs)
+	goNames := make(map[string]bool)
+	for _, sig := range sigs {
+		method := parsed.Methods[sig]
+		goName := uniqueName(goNames, capitalise(method.Name))
+		m := &tmplMethod{
+			Name:      goName,
+			Original:  method.Name,
+			Signature: method.Sig(),
+			Const:     method.IsConstant(),
+		}
+		for i, input := range method.Inputs {
+			m.Inputs = append(m.Inputs, &tmplField{
+				Name:  capitalise(input.Name),
+				Param: paramName(input.Name, i),
+				Type:  bindType(input.Type, structs),
+				Raw:   input.Name,
+				Index: i,
+			})
+		}
+		for i, output := range method.Outputs {
+			name := capitalise(output.Name)
+			if name == "" {
+				name = fmt.Sprintf("Output%d", i)
+			}
+			m.Outputs = append(m.Outputs, &tmplField{
+				Name:  name,
+				Type:  bindType(output.Type, structs),
+				Raw:   output.Name,
+				Index: i,
+			})
+		}
+		data.Methods = append(data.Methods, m)
+	}
+
+	sigs = sigs[:0]
+	for sig := range parsed.Events {
+		sigs = append(sigs, sig)
+	}
+	sort.Strings(sigs)
+	goNames = make(map[string]bool)
+	for _, sig := range sigs {
+		event := parsed.Events[sig]
+		e := &tmplEvent{
+			Name:      uniqueName(goNames, capitalise(event.Name)),
+			Original:  event.Name,
+			Signature: sig,
+		}
+		nonIndexed := 0
+		for i, input := range event.Inputs {
+			fieldName := capitalise(input.Name)
+			if fieldName == "" {
+				fieldName = fmt.Sprintf("Arg%d", i)
+			}
+			field := &tmplField{
+				Name:    fieldName,
+				Param:   paramName(input.Name, i),
+				Raw:     input.Name,
+				Indexed: input.Indexed,
+			}
+			if input.Indexed {
+				// dynamic indexed values only leave their hash in the topics
+				if isDynamic(input.Type) {
+					field.Type = "domain.Hash"
+				} else {
+					field.Type = bindType(input.Type, structs)
+				}
+				field.Index = len(e.Indexed)
+				e.Indexed = append(e.Indexed, field)
+			} else {
+				field.Type = bindType(input.Type, structs)
+				field.Index = nonIndexed
+				nonIndexed++
+			}
+			e.Fields = append(e.Fields, field)
+		}
+		data.Events = append(data.Events, e)
+	}
+
+	structNames := make([]string, 0, len(structs))
+	for key := range structs {
+		structNames = append(structNames, key)
+	}
+	sort.Strings(structNames)
+	for _, key := range structNames {
+		data.Structs = append(data.Structs, structs[key])
+	}
+
+	buffer := new(bytes.Buffer)
+	tmpl := template.Must(template.New("").Parse(tmplSource))
+	if err := tmpl.Execute(buffer, data); err != nil {
+		return "", err
+	}
+	code, err := format.Source(buffer.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("%v\n%s", err, buffer)
+	}
+	return string(code), nil
+}
+
+// uniqueName returns name, with a numeric suffix for overloads, e.g. Transfer,
+// Transfer0, Transfer1, and marks it taken.
+func uniqueName(taken map[string]bool, name string) string {
+	goName := name
+	for idx := 0; taken[goName]; idx++ {
+		goName = fmt.Sprintf("%s%d", name, idx)
+	}
+	taken[goName] = true
+	return goName
+}
+
+// bindType converts an abi type into the go type name used by the generated code.
+func bindType(t abi.Type, structs map[string]*tmplStruct) string {
+	switch t.T {
+	case abi.IntTy, abi.UintTy:
+		if t.Type.Kind() == reflect.Ptr {
+			return "*big.Int"
+		}
+		return t.Type.String()
+	case abi.BoolTy:
+		return "bool"
+	case abi.StringTy:
+		return "string"
+	case abi.BytesTy:
+		return "[]byte"
+	case abi.FixedBytesTy:
+		return fmt.Sprintf("[%d]byte", t.Size)
+	case abi.FunctionTy:
+		return "[24]byte"
+	case abi.IdentityTy:
+		return "domain.Identity"
+	case abi.HashTy:
+		return "domain.Hash"
+	case abi.SliceTy:
+		return "[]" + bindType(*t.Elem, structs)
+	case abi.ArrayTy:
+		return fmt.Sprintf("[%d]", t.Size) + bindType(*t.Elem, structs)
+	case abi.TupleTy:
+		key := t.String()
+		if s, ok := structs[key]; ok {
+			return s.Name
+		}
+		s := &tmplStruct{Name: fmt.Sprintf("Struct%d", len(structs))}
+		structs[key] = s
+		for i, elem := range t.TupleElems {
+			s.Fields = append(s.Fields, &tmplField{
+				Name: capitalise(t.TupleRawNames[i]),
+				Type: bindType(*elem, structs),
+				Raw:  t.TupleRawNames[i],
+			})
+		}
+		return s.Name
+	}
+	return "interface{}"
+}
+
+func isDynamic(t abi.Type) bool {
+	switch t.T {
+	case abi.StringTy, abi.BytesTy, abi.SliceTy:
+		return true
+	case abi.ArrayTy:
+		return isDynamic(*t.Elem)
+	case abi.TupleTy:
+		for _, elem := range t.TupleElems {
+			if isDynamic(*elem) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func capitalise(input string) string {
+	input = abi.ToCamelCase(input)
+	if input == "" {
+		return ""
+	}
+	return strings.ToUpper(input[:1]) + input[1:]
+}
+
+func paramName(name string, index int) string {
+	name = strings.Trim(name, "_")
+	if name == "" {
+		return fmt.Sprintf("arg%d", index)
+	}
+	runes := []rune(abi.ToCamelCase(name))
+	runes[0] = unicode.ToLower(runes[0])
+	param := string(runes)
+	if token.Lookup(param).IsKeyword() || param == "opts" || param == "contract" {
+		param = param + "_"
+	}
+	return param
+}
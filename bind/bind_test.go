@@ -0,0 +1,148 @@
+package bind
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testABI = `[
+  {"constant":true,"inputs":[{"name":"b","type":"bytes"},{"name":"s","type":"string"}],"name":"SayHello","outputs":[{"name":"","type":"bytes"},{"name":"","type":"string"}],"payable":false,"stateMutability":"view","type":"function"},
+  {"constant":true,"inputs":[],"name":"beneficiary","outputs":[{"name":"","type":"identity"}],"payable":false,"stateMutability":"view","type":"function"},
+  {"inputs":[{"name":"p","type":"tuple","components":[{"name":"x","type":"uint8"},{"name":"who","type":"identity[]"}]}],"name":"setPoint","outputs":[],"type":"function"},
+  {"anonymous":false,"inputs":[{"indexed":true,"name":"from","type":"identity"},{"indexed":true,"name":"memo","type":"string"},{"indexed":false,"name":"value","type":"uint256"}],"name":"Transfer","type":"event"},
+  {"anonymous":false,"inputs":[{"indexed":true,"name":"from","type":"identity"},{"indexed":false,"name":"value","type":"uint256"}],"name":"Transfer","type":"event"},
+  {"anonymous":false,"inputs":[{"indexed":true,"name":"from","type":"identity"},{"indexed":false,"name":"p","type":"tuple","components":[{"name":"x","type":"uint8"},{"name":"who","type":"identity[]"}]}],"name":"Moved","type":"event"}
+]`
+
+func TestBind(t *testing.T) {
+	code, err := Bind("sayhello", "sayHello", testABI, "0x6080")
+	require.NoError(t, err)
+
+	for _, expect := range []string{
+		"package sayhello",
+		`const SayHelloBin = "6080"`,
+		"func DeploySayHello(restClient *client.RestClient, opts *bind.TransactOpts, contractName string) (*SayHello, response.BaseResp, error)",
+		"func (contract *SayHello) SayHello(opts *bind.TransactOpts, b []byte, s string) (*SayHelloSayHelloOutput, error)",
+		"func (contract *SayHello) Beneficiary(opts *bind.TransactOpts) (domain.Identity, error)",
+		"func (contract *SayHello) SetPoint(opts *bind.TransactOpts, p Struct0) (response.BaseResp, error)",
+		`"setPoint((uint8,identity[]))"`,
+		"Who []domain.Identity",
+		"type SayHelloTransferFilter struct",
+		"Memo  domain.Hash",
+		"func (contract *SayHello) ParseTransfer(topics [][]byte, data []byte) (*SayHelloTransfer, error)",
+		"func (contract *SayHello) ParseTransfer0(topics [][]byte, data []byte) (*SayHelloTransfer0, error)",
+		`contract.abi.Events["Transfer(identity,uint256)"]`,
+		// reads are executed locally
+		`contract.call(opts, true, "SayHello(bytes,string)", params, respStruct)`,
+		`contract.client.CallContract(opts.BizId, opts.OrderId, opts.Account, opts.TenantId, contract.ContractName, "setPoint((uint8,identity[]))", params.InputParamListStr, params.OutTypes, opts.KmsId, opts.IsLocal, opts.Gas)`,
+	} {
+		require.Truef(t, strings.Contains(code, expect), "generated code misses %q:\n%s", expect, code)
+	}
+}
+
+func TestBindWithoutBytecode(t *testing.T) {
+	code, err := Bind("sayhello", "SayHello", testABI, "")
+	require.NoError(t, err)
+	require.False(t, strings.Contains(code, "DeploySayHello"), "deploy function generated without bytecode")
+
+	_, err = Bind("sayhello", "SayHello", "[{", "")
+	require.Error(t, err)
+}
+
+func TestBindCompiles(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not found")
+	}
+	code, err := Bind("sayhello", "SayHello", testABI, "0x6080")
+	require.NoError(t, err)
+
+	// build the binding inside the module so that its imports resolve
+	dir, err := ioutil.TempDir(".", "bindtest")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "sayhello.go"), []byte(code), 0644))
+	out, err := exec.Command("go", "vet", "./"+filepath.Base(dir)).CombinedOutput()
+	require.NoErrorf(t, err, "generated code does not compile:\n%s\n%s", out, code)
+}
+
+// parseEventsTest parses the Transfer events of testABI with the generated binding.
+const parseEventsTest = `package sayhello
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/oldercn/restclient-go-sdk/mychain/mychain-sdk-go/domain"
+)
+
+func TestParseTransfer(t *testing.T) {
+	contract, err := NewSayHello("contract", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, from, memo, value := make([]byte, 32), make([]byte, 32), make([]byte, 32), make([]byte, 32)
+	from[31], memo[0], value[31] = 1, 9, 42
+
+	transfer, err := contract.ParseTransfer0([][]byte{id, from}, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transfer.From != domain.BytesToIdentity(from) || transfer.Value.Cmp(big.NewInt(42)) != 0 {
+		t.Fatalf("unexpected Transfer0 %+v", transfer)
+	}
+	withMemo, err := contract.ParseTransfer([][]byte{id, from, memo}, value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if withMemo.From != domain.BytesToIdentity(from) || withMemo.Memo != domain.BytesToHash(memo) || withMemo.Value.Cmp(big.NewInt(42)) != 0 {
+		t.Fatalf("unexpected Transfer %+v", withMemo)
+	}
+	if _, err := contract.ParseTransfer([][]byte{id, from}, value); err == nil {
+		t.Fatal("a missing topic is not reported")
+	}
+
+	// a single non-indexed tuple, (7, [from])
+	point := make([]byte, 5*32)
+	point[31], point[63], point[95], point[127] = 0x20, 7, 0x40, 1
+	copy(point[128:], from)
+	moved, err := contract.ParseMoved([][]byte{id, from}, point)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.From != domain.BytesToIdentity(from) || moved.P.X != 7 || len(moved.P.Who) != 1 || moved.P.Who[0] != domain.BytesToIdentity(from) {
+		t.Fatalf("unexpected Moved %+v", moved)
+	}
+}
+`
+
+func TestBindParseEvents(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go command not found")
+	}
+	code, err := Bind("sayhello", "SayHello", testABI, "")
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir(".", "bindtest")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "sayhello.go"), []byte(code), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "sayhello_test.go"), []byte(parseEventsTest), 0644))
+	out, err := exec.Command("go", "test", "./"+filepath.Base(dir)).CombinedOutput()
+	require.NoErrorf(t, err, "generated code does not parse events:\n%s", out)
+}
+
+func TestBindConstructorInputs(t *testing.T) {
+	withConstructor := strings.Replace(testABI, "[", `[{"inputs":[{"name":"owner","type":"identity"}],"type":"constructor"},`, 1)
+	_, err := Bind("sayhello", "SayHello", withConstructor, "0x6080")
+	require.Error(t, err)
+
+	code, err := Bind("sayhello", "SayHello", withConstructor, "")
+	require.NoError(t, err)
+	require.False(t, strings.Contains(code, "DeploySayHello"))
+}
@@ -0,0 +1,279 @@
+package bind
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/oldercn/restclient-go-sdk/model"
+	"github.com/oldercn/restclient-go-sdk/mychain/mychain-sdk-go/common/codec/contract/abi"
+	"github.com/oldercn/restclient-go-sdk/mychain/mychain-sdk-go/domain"
+)
+
+// CallParams holds the serialized inputs and output types of a contract call,
+// ready for the inputParamListStr and outTypes arguments of RestClient.
+type CallParams struct {
+	InputParamListStr string
+	OutTypes          string
+}
+
+var (
+	bigIntT   = reflect.TypeOf(&big.Int{})
+	identityT = reflect.TypeOf(domain.Identity{})
+	hashT     = reflect.TypeOf(domain.Hash{})
+)
+
+// EncodeCall type-checks args against the inputs of method and serializes them
+// the way the rest server reads them:
+//
+//	int/uint  json number, from any go integer, *big.Int or big.Int
+//	bool      json bool
+//	string    json string
+//	bytes     base64 string, from []byte or a byte array, also for bytesN
+//	identity  hex string, from domain.Identity, [32]byte or a hex string
+//	T[] T[k]  json array, from a slice or array
+//	tuple     json array of the components, from a struct or map[string]interface{}
+//
+// Struct fields match tuple components by abi tag or by the camel cased name.
+func EncodeCall(method abi.Method, args ...interface{}) (CallParams, error) {
+	if len(args) != len(method.Inputs) {
+		return CallParams{}, fmt.Errorf("%s expect %d arguments, got %d", method.Sig(), len(method.Inputs), len(args))
+	}
+	inputs := make([]interface{}, len(args))
+	for i, input := range method.Inputs {
+		name := input.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+		value, err := encodeValue(input.Type, reflect.ValueOf(args[i]), name)
+		if err != nil {
+			return CallParams{}, fmt.Errorf("%s: %v", method.Sig(), err)
+		}
+		inputs[i] = value
+	}
+	inputParamListBytes, err := json.Marshal(inputs)
+	if err != nil {
+		return CallParams{}, err
+	}
+	outTypes := make([]string, len(method.Outputs))
+	for i, output := range method.Outputs {
+		outTypes[i] = output.Type.String()
+	}
+	outTypesBytes, err := json.Marshal(outTypes)
+	if err != nil {
+		return CallParams{}, err
+	}
+	return CallParams{InputParamListStr: string(inputParamListBytes), OutTypes: string(outTypesBytes)}, nil
+}
+
+// EncodeCallSignature is EncodeCall for a method given by its signature, e.g.
+// SayHello(bytes,string) returns (bytes,string). Without returns the call has no outputs.
+func EncodeCallSignature(signature string, args ...interface{}) (CallParams, error) {
+	parsed, err := abi.ParseHumanReadable(signature)
+	if err != nil {
+		return CallParams{}, err
+	}
+	if len(parsed.Methods) != 1 {
+		return CallParams{}, fmt.Errorf("signature '%s' is not a function", signature)
+	}
+	for _, method := range parsed.Methods {
+		return EncodeCall(method, args...)
+	}
+	return CallParams{}, nil
+}
+
+// SolidityVarType returns the server side type category of t, model.Unsupported
+// for types the rest server cannot take as inputs.
+func SolidityVarType(t abi.Type) model.SolidityVarType {
+	switch t.T {
+	case abi.IntTy:
+		if t.Size == 64 {
+			return model.Int64
+		}
+		return model.Int
+	case abi.UintTy:
+		return model.Uint
+	case abi.BoolTy:
+		return model.Bool
+	case abi.StringTy:
+		return model.String
+	case abi.BytesTy, abi.FixedBytesTy:
+		return model.Bytes
+	case abi.IdentityTy:
+		return model.Identity
+	case abi.TupleTy:
+		return model.Tuple
+	case abi.SliceTy, abi.ArrayTy:
+		if elem := SolidityVarType(*t.Elem); elem != model.Unsupported {
+			return elem + "[]"
+		}
+	}
+	return model.Unsupported
+}
+
+func encodeValue(t abi.Type, v reflect.Value, path string) (interface{}, error) {
+	for v.IsValid() && (v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr && v.Type() != bigIntT) {
+		v = v.Elem()
+	}
+	if !v.IsValid() || v.Type() == bigIntT && v.IsNil() {
+		return nil, fmt.Errorf("argument %s: nil value for %v", path, t)
+	}
+	if SolidityVarType(t) == model.Unsupported {
+		return nil, fmt.Errorf("argument %s: type %v is not supported", path, t)
+	}
+	mismatch := fmt.Errorf("argument %s: cannot use %v as %v", path, v.Type(), t)
+
+	switch t.T {
+	case abi.IntTy, abi.UintTy:
+		n, ok := toBigInt(v)
+		if !ok {
+			return nil, mismatch
+		}
+		if err := checkIntRange(t, n); err != nil {
+			return nil, fmt.Errorf("argument %s: %v", path, err)
+		}
+		return json.Number(n.String()), nil
+	case abi.BoolTy:
+		if v.Kind() != reflect.Bool {
+			return nil, mismatch
+		}
+		return v.Bool(), nil
+	case abi.StringTy:
+		if v.Kind() != reflect.String {
+			return nil, mismatch
+		}
+		return v.String(), nil
+	case abi.BytesTy, abi.FixedBytesTy:
+		b, ok := toBytes(v)
+		if !ok {
+			return nil, mismatch
+		}
+		if t.T == abi.FixedBytesTy && len(b) != t.Size {
+			return nil, fmt.Errorf("argument %s: %v takes %d bytes, got %d", path, t, t.Size, len(b))
+		}
+		return b, nil
+	case abi.IdentityTy:
+		switch {
+		case v.Type() == identityT || v.Type() == hashT:
+			return hex.EncodeToString(toByteSlice(v)), nil
+		case v.Kind() == reflect.String:
+			s := strings.TrimPrefix(v.String(), "0x")
+			if b, err := hex.DecodeString(s); err != nil || len(b) != len(domain.Identity{}) {
+				return nil, fmt.Errorf("argument %s: '%s' is not a hex identity", path, v.String())
+			}
+			return strings.ToLower(s), nil
+		}
+		if b, ok := toBytes(v); ok && v.Kind() == reflect.Array && len(b) == len(domain.Identity{}) {
+			return hex.EncodeToString(b), nil
+		}
+		return nil, mismatch
+	case abi.SliceTy, abi.ArrayTy:
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			return nil, mismatch
+		}
+		if t.T == abi.ArrayTy && v.Len() != t.Size {
+			return nil, fmt.Errorf("argument %s: %v takes %d elements, got %d", path, t, t.Size, v.Len())
+		}
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			elem, err := encodeValue(*t.Elem, v.Index(i), fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			list[i] = elem
+		}
+		return list, nil
+	case abi.TupleTy:
+		return encodeTuple(t, v, path)
+	}
+	return nil, mismatch
+}
+
+func encodeTuple(t abi.Type, v reflect.Value, path string) (interface{}, error) {
+	components := make([]interface{}, len(t.TupleElems))
+	for i, elem := range t.TupleElems {
+		name := t.TupleRawNames[i]
+		var field reflect.Value
+		switch v.Kind() {
+		case reflect.Struct:
+			field = structField(v, name)
+		case reflect.Map:
+			if v.Type().Key().Kind() != reflect.String {
+				return nil, fmt.Errorf("argument %s: cannot use %v as %v, want string keys", path, v.Type(), t)
+			}
+			field = v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+		default:
+			return nil, fmt.Errorf("argument %s: cannot use %v as %v, want struct or map", path, v.Type(), t)
+		}
+		if !field.IsValid() {
+			return nil, fmt.Errorf("argument %s: %v has no field for %s", path, v.Type(), name)
+		}
+		component, err := encodeValue(*elem, field, path+"."+name)
+		if err != nil {
+			return nil, err
+		}
+		components[i] = component
+	}
+	return components, nil
+}
+
+// structField finds the field for the tuple component name, by abi tag first.
+func structField(v reflect.Value, name string) reflect.Value {
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		if tag, ok := typ.Field(i).Tag.Lookup("abi"); ok && tag == name {
+			return v.Field(i)
+		}
+	}
+	return v.FieldByName(abi.ToCamelCase(name))
+}
+
+func toBigInt(v reflect.Value) (*big.Int, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Int).SetUint64(v.Uint()), true
+	}
+	switch n := v.Interface().(type) {
+	case *big.Int:
+		return n, true
+	case big.Int:
+		return &n, true
+	}
+	return nil, false
+}
+
+func checkIntRange(t abi.Type, n *big.Int) error {
+	size := t.Size
+	if size == 0 {
+		size = 256
+	}
+	if t.T == abi.UintTy {
+		if n.Sign() < 0 || n.BitLen() > size {
+			return fmt.Errorf("%v out of range of %v", n, t)
+		}
+		return nil
+	}
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(size-1))
+	if n.Cmp(limit) >= 0 || n.Cmp(new(big.Int).Neg(limit)) < 0 {
+		return fmt.Errorf("%v out of range of %v", n, t)
+	}
+	return nil
+}
+
+func toBytes(v reflect.Value) ([]byte, bool) {
+	if (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() == reflect.Uint8 {
+		return toByteSlice(v), true
+	}
+	return nil, false
+}
+
+func toByteSlice(v reflect.Value) []byte {
+	b := make([]byte, v.Len())
+	reflect.Copy(reflect.ValueOf(b), v)
+	return b
+}
@@ -0,0 +1,75 @@
+package bind
+
+import (
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/oldercn/restclient-go-sdk/model"
+	"github.com/oldercn/restclient-go-sdk/mychain/mychain-sdk-go/common/codec/contract/abi"
+	"github.com/oldercn/restclient-go-sdk/mychain/mychain-sdk-go/domain"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeCall(t *testing.T) {
+	params, err := EncodeCallSignature("SayHello(bytes,string) returns (bytes,string)", []byte{0, 1, 2}, "hello")
+	require.NoError(t, err)
+	require.Equal(t, `["AAEC","hello"]`, params.InputParamListStr)
+	require.Equal(t, `["bytes","string"]`, params.OutTypes)
+
+	type point struct {
+		X   uint8
+		Who []domain.Identity `abi:"who"`
+	}
+	var identity domain.Identity
+	identity[31] = 0xab
+	huge, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	parsed, err := abi.ParseHumanReadable("function set(uint256 amount, int8 delta, bool ok, bytes4 tag, identity to, (uint8 x, identity[] who) p, uint16[2] pair)")
+	require.NoError(t, err)
+	params, err = EncodeCall(parsed.Methods["set(uint256,int8,bool,bytes4,identity,(uint8,identity[]),uint16[2])"],
+		huge, int8(-3), true, [4]byte{1, 2, 3, 4}, "0x"+strings.Repeat("CD", 32), &point{X: 7, Who: []domain.Identity{identity}}, []int{1, 2})
+	require.NoError(t, err)
+	require.Equal(t, `[123456789012345678901234567890,-3,true,"AQIDBA==","`+strings.Repeat("cd", 32)+`",[7,["`+identity.ToHex()+`"]],[1,2]]`, params.InputParamListStr)
+	require.Equal(t, `[]`, params.OutTypes)
+}
+
+func TestEncodeCallTypeCheck(t *testing.T) {
+	tests := []struct {
+		signature string
+		args      []interface{}
+		err       string
+	}{
+		{"f(uint8)", []interface{}{256}, "argument #0: 256 out of range of uint8"},
+		{"f(uint256)", []interface{}{-1}, "out of range of uint256"},
+		{"f(int8)", []interface{}{-129}, "out of range of int8"},
+		{"f(uint256 a)", []interface{}{"1"}, "argument a: cannot use string as uint256"},
+		{"f(bytes4)", []interface{}{[]byte{1}}, "bytes4 takes 4 bytes, got 1"},
+		{"f(identity)", []interface{}{"abc"}, "is not a hex identity"},
+		{"f(uint8[2])", []interface{}{[]uint8{1}}, "uint8[2] takes 2 elements, got 1"},
+		{"f((uint8 x, bool ok) p)", []interface{}{struct{ X uint8 }{1}}, "has no field for ok"},
+		{"f((uint8 x, bool ok) p)", []interface{}{map[string]interface{}{"x": 1, "ok": "yes"}}, "argument p.ok: cannot use string as bool"},
+		{"f(uint8,uint8)", []interface{}{1}, "expect 2 arguments, got 1"},
+		{"f(uint8)", []interface{}{nil}, "nil value for uint8"},
+	}
+	for _, test := range tests {
+		_, err := EncodeCallSignature(test.signature, test.args...)
+		require.Error(t, err, test.signature)
+		require.Contains(t, err.Error(), test.err)
+	}
+}
+
+func TestSolidityVarType(t *testing.T) {
+	for typ, expect := range map[string]model.SolidityVarType{
+		"int64":      model.Int64,
+		"int32":      model.Int,
+		"uint256":    model.Uint,
+		"bytes32":    model.Bytes,
+		"identity[]": model.IdentityArray,
+		"string[2]":  "string[]",
+		"bool":       model.Bool,
+	} {
+		parsed, err := abi.NewType(typ, nil)
+		require.NoError(t, err)
+		require.Equal(t, expect, SolidityVarType(parsed), typ)
+	}
+}
@@ -0,0 +1,181 @@
+package bind
+
+// tmplSource is the go source template the contract bindings are generated from.
+const tmplSource = `// Code generated by abigen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/oldercn/restclient-go-sdk/bind"
+	"github.com/oldercn/restclient-go-sdk/client"
+	"github.com/oldercn/restclient-go-sdk/mychain/mychain-sdk-go/common/codec/contract/abi"
+	"github.com/oldercn/restclient-go-sdk/mychain/mychain-sdk-go/domain"
+	"github.com/oldercn/restclient-go-sdk/response"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var (
+	_ = json.Marshal
+	_ = fmt.Errorf
+	_ = big.NewInt
+	_ = reflect.DeepEqual
+	_ = strings.NewReader
+	_ = domain.BytesToIdentity
+	_ = response.BaseResp{}
+)
+
+// {{.Type}}ABI is the input ABI used to generate the binding from.
+const {{.Type}}ABI = ` + "`{{.InputABI}}`" + `
+{{if .InputBin}}
+// {{.Type}}Bin is the compiled bytecode used for deploying new contracts.
+const {{.Type}}Bin = "{{.InputBin}}"
+{{end}}
+{{range .Structs}}
+// {{.Name}} is an auto generated low-level Go binding around a user-defined struct.
+type {{.Name}} struct {
+{{range .Fields}}	{{.Name}} {{.Type}} ` + "`abi:\"{{.Raw}}\" json:\"{{.Raw}}\"`" + `
+{{end}}}
+{{end}}
+// {{.Type}} is a typed binding around a deployed contract.
+type {{.Type}} struct {
+	ContractName string
+	abi          abi.ABI
+	client       *client.RestClient
+}
+
+// New{{.Type}} creates a binding of the contract deployed under contractName.
+func New{{.Type}}(contractName string, restClient *client.RestClient) (*{{.Type}}, error) {
+	parsed, err := abi.JSON(strings.NewReader({{.Type}}ABI))
+	if err != nil {
+		return nil, err
+	}
+	return &{{.Type}}{ContractName: contractName, abi: parsed, client: restClient}, nil
+}
+{{if .InputBin}}
+// Deploy{{.Type}} deploys {{.Type}}Bin under contractName and returns a binding of it.
+func Deploy{{.Type}}(restClient *client.RestClient, opts *bind.TransactOpts, contractName string) (*{{.Type}}, response.BaseResp, error) {
+	contract, err := New{{.Type}}(contractName, restClient)
+	if err != nil {
+		return nil, response.BaseResp{}, err
+	}
+	baseResp, err := restClient.DeployContract(opts.BizId, opts.OrderId, opts.Account, opts.TenantId, opts.KmsId, contractName, {{.Type}}Bin, opts.Gas)
+	if err != nil {
+		return nil, baseResp, err
+	}
+	if !baseResp.Success || baseResp.Code != "200" {
+		return nil, baseResp, fmt.Errorf("deploy {{.Type}} failed,code:%+v err msg:%+v", baseResp.Code, baseResp.Data)
+	}
+	return contract, baseResp, nil
+}
+{{end}}
+
+// call calls the contract method signature and unpacks its outputs into out. Reads
+// and calls with opts.IsLocal are executed locally, without sending a transaction.
+func (contract *{{.Type}}) call(opts *bind.TransactOpts, read bool, signature string, params bind.CallParams, out interface{}) error {
+	var err error
+	if read || opts.IsLocal {
+		_, err = contract.client.CallSolcContractLocal(contract.abi, opts.BizId, opts.OrderId, opts.Account, opts.TenantId, opts.KmsId, contract.ContractName, signature, params.InputParamListStr, params.OutTypes, opts.Gas, out)
+	} else {
+		_, err = contract.client.CallSolcContractSyncWithReceipt(contract.abi, opts.BizId, opts.OrderId, opts.Account, opts.TenantId, opts.KmsId, contract.ContractName, signature, params.InputParamListStr, params.OutTypes, opts.Gas, out)
+	}
+	return err
+}
+{{range .Methods}}{{if gt (len .Outputs) 1}}
+// {{$.Type}}{{.Name}}Output holds the outputs of {{.Signature}}.
+type {{$.Type}}{{.Name}}Output struct {
+{{range .Outputs}}	{{.Name}} {{.Type}}
+{{end}}}
+{{end}}
+// {{.Name}} calls the contract method {{.Signature}}.
+func (contract *{{$.Type}}) {{.Name}}(opts *bind.TransactOpts{{range .Inputs}}, {{.Param}} {{.Type}}{{end}}) ({{if gt (len .Outputs) 1}}*{{$.Type}}{{.Name}}Output{{else if .Outputs}}{{(index .Outputs 0).Type}}{{else}}response.BaseResp{{end}}, error) {
+	params, err := bind.EncodeCall(contract.abi.Methods["{{.Signature}}"]{{range .Inputs}}, {{.Param}}{{end}})
+	if err != nil {
+		return {{if gt (len .Outputs) 1}}nil{{else if .Outputs}}*new({{(index .Outputs 0).Type}}){{else}}response.BaseResp{}{{end}}, err
+	}
+{{if gt (len .Outputs) 1}}	out := new({{$.Type}}{{.Name}}Output)
+	respStruct := &[]interface{}{ {{range $i, $out := .Outputs}}{{if $i}}, {{end}}&out.{{.Name}}{{end}} }
+	if err := contract.call(opts, {{.Const}}, "{{.Signature}}", params, respStruct); err != nil {
+		return nil, err
+	}
+	return out, nil
+{{else if .Outputs}}	var out {{(index .Outputs 0).Type}}
+	if err := contract.call(opts, {{.Const}}, "{{.Signature}}", params, &out); err != nil {
+		return *new({{(index .Outputs 0).Type}}), err
+	}
+	return out, nil
+{{else}}	return contract.client.CallContract(opts.BizId, opts.OrderId, opts.Account, opts.TenantId, contract.ContractName, "{{.Signature}}", params.InputParamListStr, params.OutTypes, opts.KmsId, {{if .Const}}true{{else}}opts.IsLocal{{end}}, opts.Gas)
+{{end}}}
+{{end}}
+{{range .Events}}
+// {{$.Type}}{{.Name}} represents a {{.Original}} event raised by the contract.
+type {{$.Type}}{{.Name}} struct {
+{{range .Fields}}	{{.Name}} {{.Type}}
+{{end}}}
+
+// {{$.Type}}{{.Name}}Filter selects {{.Original}} events by their indexed fields, an empty list matches any value.
+type {{$.Type}}{{.Name}}Filter struct {
+{{range .Indexed}}	{{.Name}} []{{.Type}}
+{{end}}}
+
+// Match reports whether event satisfies every indexed field rule of the filter.
+func (filter *{{$.Type}}{{.Name}}Filter) Match(event *{{$.Type}}{{.Name}}) bool {
+{{range .Indexed}}	if len(filter.{{.Name}}) > 0 {
+		matched := false
+		for _, rule := range filter.{{.Name}} {
+			if reflect.DeepEqual(rule, event.{{.Name}}) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+{{end}}	return true
+}
+
+// Parse{{.Name}} decodes a {{.Original}} event from the topics and data of a log,
+// topics[0] is the event id unless the event is anonymous.
+func (contract *{{$.Type}}) Parse{{.Name}}(topics [][]byte, data []byte) (*{{$.Type}}{{.Name}}, error) {
+	event := contract.abi.Events["{{.Signature}}"]
+	if !event.Anonymous {
+		if len(topics) == 0 {
+			return nil, fmt.Errorf("{{.Original}} event has no topics")
+		}
+		topics = topics[1:]
+	}
+	indexed := make(abi.Arguments, 0, len(event.Inputs))
+	for _, input := range event.Inputs {
+		if input.Indexed {
+			input.Indexed = false
+			indexed = append(indexed, input)
+		}
+	}
+	if len(topics) != len(indexed) {
+		return nil, fmt.Errorf("{{.Original}} event expect %d indexed topics, got %d", len(indexed), len(topics))
+	}
+	out := new({{$.Type}}{{.Name}})
+{{range .Indexed}}{{if eq .Type "domain.Hash"}}	out.{{.Name}} = domain.BytesToHash(topics[{{.Index}}])
+{{else}}	if err := (abi.Arguments{indexed[{{.Index}}]}).Unpack(&out.{{.Name}}, topics[{{.Index}}]); err != nil {
+		return nil, err
+	}
+{{end}}{{end}}	nonIndexed := []interface{}{ {{$first := true}}{{range .Fields}}{{if not .Indexed}}{{if not $first}}, {{end}}{{$first = false}}&out.{{.Name}}{{end}}{{end}} }
+	if len(nonIndexed) == 0 {
+		return out, nil
+	}
+	if len(nonIndexed) > 1 {
+		if err := event.Inputs.NonIndexed().Unpack(&nonIndexed, data); err != nil {
+			return nil, err
+		}
+	} else if err := event.Inputs.NonIndexed().Unpack(nonIndexed[0], data); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+{{end}}`
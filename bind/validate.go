@@ -0,0 +1,163 @@
+package bind
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/oldercn/restclient-go-sdk/model"
+	"github.com/oldercn/restclient-go-sdk/mychain/mychain-sdk-go/common/codec/contract/abi"
+)
+
+// CallValidationError lists every problem ValidateCall found in a contract call.
+type CallValidationError struct {
+	Signature string
+	Problems  []string
+}
+
+func (e *CallValidationError) Error() string {
+	return fmt.Sprintf("invalid call of %s: %s", e.Signature, strings.Join(e.Problems, "; "))
+}
+
+// ValidateCall checks a contract call against contractABI before it is sent: the
+// method exists and methodSignature names it exactly, inputParamListStr holds an
+// argument of the right type and range for every input, in the form EncodeCall
+// produces, and outTypes lists the outputs of the method. All problems are
+// reported together in a *CallValidationError.
+func ValidateCall(contractABI abi.ABI, methodSignature, inputParamListStr, outTypes string) error {
+	method, err := contractABI.LookupMethod(methodSignature)
+	if err != nil {
+		return &CallValidationError{Signature: methodSignature, Problems: []string{err.Error()}}
+	}
+	var problems []string
+	if sig := strings.Replace(methodSignature, " ", "", -1); sig != method.Sig() {
+		problems = append(problems, fmt.Sprintf("signature '%s' does not match the abi method %s", methodSignature, method.Sig()))
+	}
+
+	var inputs []interface{}
+	decoder := json.NewDecoder(strings.NewReader(inputParamListStr))
+	decoder.UseNumber()
+	if err := decoder.Decode(&inputs); err != nil {
+		problems = append(problems, fmt.Sprintf("inputParamListStr is not a json array: %v", err))
+	} else if len(inputs) != len(method.Inputs) {
+		problems = append(problems, fmt.Sprintf("expect %d arguments, got %d", len(method.Inputs), len(inputs)))
+	} else {
+		for i, input := range method.Inputs {
+			name := input.Name
+			if name == "" {
+				name = fmt.Sprintf("#%d", i)
+			}
+			problems = append(problems, validateJSONValue(input.Type, inputs[i], "argument "+name)...)
+		}
+	}
+
+	var outputs []string
+	if err := json.Unmarshal([]byte(outTypes), &outputs); err != nil {
+		problems = append(problems, fmt.Sprintf("outTypes is not a json array of types: %v", err))
+	} else if !(len(method.Outputs) == 0 && len(outputs) == 1 && outputs[0] == model.VOID) {
+		if len(outputs) != len(method.Outputs) {
+			problems = append(problems, fmt.Sprintf("outTypes lists %d types, the method has %d outputs", len(outputs), len(method.Outputs)))
+		} else {
+			for i, output := range method.Outputs {
+				if typ := strings.Replace(outputs[i], " ", "", -1); typ != output.Type.String() {
+					problems = append(problems, fmt.Sprintf("outTypes[%d] is %s, the method outputs %v", i, outputs[i], output.Type))
+				}
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return &CallValidationError{Signature: method.Sig(), Problems: problems}
+	}
+	return nil
+}
+
+// validateJSONValue checks a decoded json value against t, see EncodeCall for the expected forms.
+func validateJSONValue(t abi.Type, value interface{}, path string) []string {
+	mismatch := []string{fmt.Sprintf("%s: %s is not a valid %v", path, jsonString(value), t)}
+	switch t.T {
+	case abi.IntTy, abi.UintTy:
+		number, ok := value.(json.Number)
+		if !ok {
+			return mismatch
+		}
+		n, ok := new(big.Int).SetString(number.String(), 10)
+		if !ok {
+			return mismatch
+		}
+		if err := checkIntRange(t, n); err != nil {
+			return []string{fmt.Sprintf("%s: %v", path, err)}
+		}
+	case abi.BoolTy:
+		if _, ok := value.(bool); !ok {
+			return mismatch
+		}
+	case abi.StringTy:
+		if _, ok := value.(string); !ok {
+			return mismatch
+		}
+	case abi.BytesTy, abi.FixedBytesTy:
+		s, ok := value.(string)
+		if !ok {
+			return mismatch
+		}
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return []string{fmt.Sprintf("%s: %v is not base64: %v", path, t, err)}
+		}
+		if t.T == abi.FixedBytesTy && len(b) != t.Size {
+			return []string{fmt.Sprintf("%s: %v takes %d bytes, got %d", path, t, t.Size, len(b))}
+		}
+	case abi.IdentityTy:
+		s, ok := value.(string)
+		if !ok {
+			return mismatch
+		}
+		if b, err := hex.DecodeString(strings.TrimPrefix(s, "0x")); err != nil || len(b) != 32 {
+			return mismatch
+		}
+	case abi.SliceTy, abi.ArrayTy:
+		list, ok := value.([]interface{})
+		if !ok {
+			return mismatch
+		}
+		if t.T == abi.ArrayTy && len(list) != t.Size {
+			return []string{fmt.Sprintf("%s: %v takes %d elements, got %d", path, t, t.Size, len(list))}
+		}
+		var problems []string
+		for i, elem := range list {
+			problems = append(problems, validateJSONValue(*t.Elem, elem, fmt.Sprintf("%s[%d]", path, i))...)
+		}
+		return problems
+	case abi.TupleTy:
+		components, ok := value.([]interface{})
+		if !ok {
+			return mismatch
+		}
+		if len(components) != len(t.TupleElems) {
+			return []string{fmt.Sprintf("%s: %v takes %d components, got %d", path, t, len(t.TupleElems), len(components))}
+		}
+		var problems []string
+		for i, elem := range t.TupleElems {
+			problems = append(problems, validateJSONValue(*elem, components[i], path+"."+t.TupleRawNames[i])...)
+		}
+		return problems
+	default:
+		return []string{fmt.Sprintf("%s: type %v is not supported", path, t)}
+	}
+	return nil
+}
+
+func jsonString(value interface{}) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return strings.TrimSpace(buf.String())
+}
@@ -0,0 +1,38 @@
+package bind
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/oldercn/restclient-go-sdk/mychain/mychain-sdk-go/common/codec/contract/abi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCall(t *testing.T) {
+	contractABI, err := abi.JSON(strings.NewReader(testABI))
+	require.NoError(t, err)
+
+	require.NoError(t, ValidateCall(contractABI, "SayHello(bytes,string)", `["AAEC","hello"]`, `["bytes","string"]`))
+	require.NoError(t, ValidateCall(contractABI, "setPoint((uint8,identity[]))", `[[7,["`+strings.Repeat("ab", 32)+`"]]]`, `["void"]`))
+
+	params, err := EncodeCall(contractABI.Methods["setPoint((uint8,identity[]))"], map[string]interface{}{"x": 1, "who": []string{}})
+	require.NoError(t, err)
+	require.NoError(t, ValidateCall(contractABI, "setPoint((uint8,identity[]))", params.InputParamListStr, params.OutTypes))
+
+	err = ValidateCall(contractABI, "setPoint((uint8,identity[]))", `[[256,["ab", 1]]]`, `["bytes"]`)
+	require.IsType(t, &CallValidationError{}, err)
+	require.Equal(t, []string{
+		"argument p.x: 256 out of range of uint8",
+		`argument p.who[0]: "ab" is not a valid identity`,
+		"argument p.who[1]: 1 is not a valid identity",
+		"outTypes lists 1 types, the method has 0 outputs",
+	}, err.(*CallValidationError).Problems)
+
+	err = ValidateCall(contractABI, "SayHello(bytes)", `["AAEC"]`, `["bytes","string"]`)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "SayHello(bytes)")
+
+	err = ValidateCall(contractABI, "SayHello", `["AAEC"]`, `["bytes","uint8"]`)
+	require.EqualError(t, err, "invalid call of SayHello(bytes,string): signature 'SayHello' does not match the abi method SayHello(bytes,string); "+
+		"expect 2 arguments, got 1; outTypes[1] is uint8, the method outputs string")
+}
@@ -0,0 +1,301 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/oldercn/restclient-go-sdk/client/config"
+	"github.com/oldercn/restclient-go-sdk/model"
+)
+
+var (
+	DefaultCircuitBreakerFailures     = 5
+	DefaultCircuitBreakerErrorRate    = 50
+	DefaultCircuitBreakerWindow       = 20
+	DefaultCircuitBreakerOpenDuration = 30 * time.Second
+)
+
+// ErrCircuitOpen is what a *CircuitOpenError is, for errors.Is.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// CircuitOpenError is returned without sending a request when the circuit breakers of
+// all endpoints are open for the class of its method.
+type CircuitOpenError struct {
+	Method model.Method
+	Class  MethodClass
+	// RetryAfter is when the first breaker lets a trial request through.
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker open for %v requests of %v,retry after %v", e.Class, e.Method, e.RetryAfter)
+}
+
+func (e *CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// CircuitState is the state of a circuit breaker.
+type CircuitState string
+
+const (
+	// CircuitClosed lets every request through.
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen fails every request fast until the open duration is over.
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen lets a single trial request through, which closes the breaker
+	// when it succeeds and opens it again when it fails.
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+// outcome is what an attempt tells a circuit breaker.
+type outcome int
+
+const (
+	outcomeSuccess outcome = iota
+	outcomeFailure
+	// outcomeIgnored attempts were canceled by the caller, they only free the trial.
+	outcomeIgnored
+)
+
+// breakerSettings are the thresholds of the properties with their defaults.
+type breakerSettings struct {
+	disabled     bool
+	failures     int
+	errorRate    int
+	window       int
+	openDuration time.Duration
+}
+
+func newBreakerSettings(restClientProperties config.RestClientProperties) breakerSettings {
+	settings := breakerSettings{
+		disabled:     restClientProperties.CircuitBreakerDisabled,
+		failures:     DefaultCircuitBreakerFailures,
+		errorRate:    DefaultCircuitBreakerErrorRate,
+		window:       DefaultCircuitBreakerWindow,
+		openDuration: DefaultCircuitBreakerOpenDuration,
+	}
+	if restClientProperties.CircuitBreakerFailures != 0 {
+		settings.failures = restClientProperties.CircuitBreakerFailures
+	}
+	if restClientProperties.CircuitBreakerErrorRate != 0 {
+		settings.errorRate = restClientProperties.CircuitBreakerErrorRate
+	}
+	if restClientProperties.CircuitBreakerWindow != 0 {
+		settings.window = restClientProperties.CircuitBreakerWindow
+	}
+	if restClientProperties.CircuitBreakerOpenDuration != 0 {
+		settings.openDuration = time.Duration(restClientProperties.CircuitBreakerOpenDuration) * time.Millisecond
+	}
+	return settings
+}
+
+// breaker is the circuit breaker of a method class on an endpoint.
+type breaker struct {
+	lock      sync.Mutex
+	state     CircuitState
+	failures  int    // in a row
+	outcomes  []bool // the last ones, true for a failure
+	next      int
+	count     int // outcomes recorded, up to the window
+	openUntil time.Time
+	trial     bool // a trial request is in flight
+}
+
+func newBreaker() *breaker {
+	return &breaker{state: CircuitClosed}
+}
+
+// allow tells whether a request may go through, and the state it moved to when that
+// changed. A rejected request gets the time left until the next trial.
+func (b *breaker) allow(settings breakerSettings, now time.Time) (ok bool, changed CircuitState, retryAfter time.Duration) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	switch b.state {
+	case CircuitOpen:
+		if now.Before(b.openUntil) {
+			return false, "", b.openUntil.Sub(now)
+		}
+		b.state, b.trial = CircuitHalfOpen, true
+		return true, CircuitHalfOpen, 0
+	case CircuitHalfOpen:
+		if b.trial {
+			return false, "", 0
+		}
+		b.trial = true
+		return true, "", 0
+	default:
+		return true, "", 0
+	}
+}
+
+// record counts the outcome of an allowed request and returns the state the breaker
+// moved to when that changed.
+func (b *breaker) record(settings breakerSettings, result outcome, now time.Time) (changed CircuitState) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.state == CircuitHalfOpen {
+		b.trial = false
+		switch result {
+		case outcomeSuccess:
+			b.reset(settings)
+			return CircuitClosed
+		case outcomeFailure:
+			b.open(settings, now)
+			return CircuitOpen
+		}
+		return ""
+	}
+	if result == outcomeIgnored || b.state != CircuitClosed {
+		// the outcome of a request sent before the breaker opened
+		return ""
+	}
+	if len(b.outcomes) != settings.window {
+		b.reset(settings)
+	}
+	b.outcomes[b.next] = result == outcomeFailure
+	b.next = (b.next + 1) % len(b.outcomes)
+	if b.count < len(b.outcomes) {
+		b.count++
+	}
+	if result == outcomeSuccess {
+		b.failures = 0
+		return ""
+	}
+	b.failures++
+	if b.failures >= settings.failures || b.errorRate() >= settings.errorRate {
+		b.open(settings, now)
+		return CircuitOpen
+	}
+	return ""
+}
+
+// errorRate is the percentage of failures in a full window, 0 before it is full.
+func (b *breaker) errorRate() int {
+	if b.count < len(b.outcomes) {
+		return 0
+	}
+	failed := 0
+	for _, failure := range b.outcomes {
+		if failure {
+			failed++
+		}
+	}
+	return failed * 100 / len(b.outcomes)
+}
+
+func (b *breaker) open(settings breakerSettings, now time.Time) {
+	b.state, b.openUntil = CircuitOpen, now.Add(settings.openDuration)
+}
+
+func (b *breaker) reset(settings breakerSettings) {
+	b.state, b.failures, b.outcomes, b.next, b.count = CircuitClosed, 0, make([]bool, settings.window), 0, 0
+}
+
+func (b *breaker) getState() CircuitState {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.state
+}
+
+// breaker returns the circuit breaker of class on e.
+func (e *endpoint) breaker(class MethodClass) *breaker {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	b, ok := e.breakers[class]
+	if !ok {
+		if e.breakers == nil {
+			e.breakers = make(map[MethodClass]*breaker)
+		}
+		b = newBreaker()
+		e.breakers[class] = b
+	}
+	return b
+}
+
+// pickEndpoint picks an endpoint not tried yet if possible, whose circuit breaker
+// for the class of info lets the request through. The breaker is nil when they are
+// disabled.
+func (client *RestClient) pickEndpoint(state *clientState, info MethodInfo, tried []*endpoint) (*endpoint, *breaker, error) {
+	settings := newBreakerSettings(state.properties)
+	if settings.disabled {
+		return state.endpoints.pick(tried), nil, nil
+	}
+	class := info.class()
+	openErr := &CircuitOpenError{Method: info.Method, Class: class}
+	var rejected []*endpoint
+	for len(rejected) < len(state.endpoints.endpoints) {
+		e := state.endpoints.pick(append(append([]*endpoint(nil), tried...), rejected...))
+		if containsEndpoint(rejected, e) {
+			e = state.endpoints.pick(rejected)
+		}
+		b := e.breaker(class)
+		ok, changed, retryAfter := b.allow(settings, time.Now())
+		if changed != "" {
+			client.circuitChanged(e, class, changed)
+		}
+		if ok {
+			return e, b, nil
+		}
+		if openErr.RetryAfter == 0 || retryAfter < openErr.RetryAfter {
+			openErr.RetryAfter = retryAfter
+		}
+		rejected = append(rejected, e)
+	}
+	return nil, nil, openErr
+}
+
+// pickOpenEndpoint is pickEndpoint failing fast when every circuit breaker is open,
+// instead of piling up requests on a degraded gateway.
+func (client *RestClient) pickOpenEndpoint(state *clientState, info MethodInfo, path, chainCallType string, tried []*endpoint) (*endpoint, *breaker, error) {
+	e, b, err := client.pickEndpoint(state, info, tried)
+	if err != nil {
+		client.metrics().RequestDone(info.Method, path, CodeCircuitOpen, 0)
+		client.log().Warn(fmt.Sprintf("fail to send %v request", chainCallType), "err", err.Error())
+		return nil, nil, err
+	}
+	return e, b, nil
+}
+
+// attemptFailed counts an attempt on e failing with a transport error, attempts
+// canceled by the caller do not count against the circuit breaker.
+func (client *RestClient) attemptFailed(ctx context.Context, state *clientState, e *endpoint, b *breaker, class MethodClass) {
+	client.endpointFailed(state, e)
+	if ctx.Err() != nil {
+		client.recordOutcome(state, e, b, class, outcomeIgnored)
+	} else {
+		client.recordOutcome(state, e, b, class, outcomeFailure)
+	}
+}
+
+// recordResult tells the circuit breaker b whether the answer of an attempt was a
+// failure of the server.
+func (client *RestClient) recordResult(state *clientState, e *endpoint, b *breaker, class MethodClass, failed bool) {
+	if failed {
+		client.recordOutcome(state, e, b, class, outcomeFailure)
+	} else {
+		client.recordOutcome(state, e, b, class, outcomeSuccess)
+	}
+}
+
+// recordOutcome tells the circuit breaker b of class on e how an attempt went.
+func (client *RestClient) recordOutcome(state *clientState, e *endpoint, b *breaker, class MethodClass, result outcome) {
+	if b == nil {
+		return
+	}
+	if changed := b.record(newBreakerSettings(state.properties), result, time.Now()); changed != "" {
+		client.circuitChanged(e, class, changed)
+	}
+}
+
+func (client *RestClient) circuitChanged(e *endpoint, class MethodClass, state CircuitState) {
+	if state == CircuitOpen {
+		client.log().Warn("circuit breaker open", "url", e.url, "class", string(class))
+	} else {
+		client.log().Info("circuit breaker "+string(state), "url", e.url, "class", string(class))
+	}
+	client.metrics().CircuitChanged(e.url, class, state)
+}
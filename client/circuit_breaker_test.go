@@ -0,0 +1,134 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oldercn/restclient-go-sdk/client/config"
+	"github.com/oldercn/restclient-go-sdk/response"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var calls, healthy int32
+	client, closeServer := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if atomic.LoadInt32(&healthy) == 1 {
+			writeResp(w, response.BaseResp{Success: true, Code: "200"})
+			return
+		}
+		writeResp(w, response.BaseResp{Success: false, Code: "500"})
+	})
+	defer closeServer()
+	client.RestClientProperties.CircuitBreakerFailures = 2
+	client.RestClientProperties.CircuitBreakerOpenDuration = 50
+	collector := NewCollector()
+	logger := &recordingLogger{}
+	client.metricsSink, client.logger = collector, logger
+
+	// the second failure opens the breaker, the third attempt fails fast
+	_, err := client.QueryReceipt("biz", "abcd")
+	require.True(t, errors.Is(err, ErrCircuitOpen))
+	require.IsType(t, &CircuitOpenError{}, err)
+	require.Equal(t, ClassRead, err.(*CircuitOpenError).Class)
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	_, err = client.QueryReceipt("biz", "abcd")
+	require.True(t, errors.Is(err, ErrCircuitOpen))
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	require.Equal(t, CircuitOpen, client.Endpoints()[0].Circuits[ClassRead])
+	require.Contains(t, logger.String(), "warn circuit breaker open [url "+client.RestClientProperties.RestUrl+" class read]")
+
+	url := client.RestClientProperties.RestUrl
+	snapshot := collector.Snapshot()
+	require.Equal(t, float64(1), snapshot[`restclient_circuit_state{url="`+url+`",class="read",state="open"}`])
+	require.Equal(t, float64(2), snapshot[`restclient_requests{method="QUERYRECEIPT",endpoint="/api/contract/chainCallForBiz",code="circuit_open"}`])
+
+	// a successful trial closes it again
+	atomic.StoreInt32(&healthy, 1)
+	time.Sleep(60 * time.Millisecond)
+	_, err = client.QueryReceipt("biz", "abcd")
+	require.NoError(t, err)
+	require.Equal(t, CircuitClosed, client.Endpoints()[0].Circuits[ClassRead])
+	snapshot = collector.Snapshot()
+	require.Equal(t, float64(1), snapshot[`restclient_circuit_changes{url="`+url+`",class="read",state="half_open"}`])
+	require.Equal(t, float64(1), snapshot[`restclient_circuit_state{url="`+url+`",class="read",state="closed"}`])
+	require.Equal(t, float64(0), snapshot[`restclient_circuit_state{url="`+url+`",class="read",state="open"}`])
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	var calls int32
+	client, closeServer := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		writeResp(w, response.BaseResp{Success: false, Code: "500"})
+	})
+	defer closeServer()
+	client.RestClientProperties.CircuitBreakerFailures = 1
+	client.RestClientProperties.CircuitBreakerDisabled = true
+
+	_, err := client.QueryReceipt("biz", "abcd")
+	require.Error(t, err)
+	require.False(t, errors.Is(err, ErrCircuitOpen))
+	require.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestAttemptFailed(t *testing.T) {
+	client := &RestClient{RestClientProperties: config.RestClientProperties{RestUrl: "http://127.0.0.1:1", CircuitBreakerFailures: 1}}
+	state := client.current()
+	e := state.endpoints.endpoints[0]
+	b := e.breaker(ClassRead)
+
+	// canceled attempts do not count
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client.attemptFailed(ctx, state, e, b, ClassRead)
+	require.Equal(t, CircuitClosed, client.Endpoints()[0].Circuits[ClassRead])
+	client.recordResult(state, e, b, ClassRead, false)
+	require.Equal(t, CircuitClosed, client.Endpoints()[0].Circuits[ClassRead])
+
+	client.attemptFailed(context.Background(), state, e, b, ClassRead)
+	require.Equal(t, CircuitOpen, client.Endpoints()[0].Circuits[ClassRead])
+	_, _, err := client.pickOpenEndpoint(state, readMethod("QUERYRECEIPT"), ChainCallForBizPath, "chainCallForBiz", nil)
+	require.True(t, errors.Is(err, ErrCircuitOpen))
+}
+
+func TestBreakerStates(t *testing.T) {
+	settings := breakerSettings{failures: 10, errorRate: 50, window: 4, openDuration: time.Second}
+	now := time.Now()
+	b := newBreaker()
+
+	// half of a full window failed
+	for i, result := range []outcome{outcomeSuccess, outcomeFailure, outcomeSuccess} {
+		ok, _, _ := b.allow(settings, now)
+		require.True(t, ok)
+		require.Equal(t, CircuitState(""), b.record(settings, result, now), i)
+	}
+	require.Equal(t, CircuitOpen, b.record(settings, outcomeFailure, now))
+	ok, _, retryAfter := b.allow(settings, now.Add(100*time.Millisecond))
+	require.False(t, ok)
+	require.Equal(t, 900*time.Millisecond, retryAfter)
+
+	// one trial at a time, a failed one opens the breaker again
+	later := now.Add(time.Second)
+	ok, changed, _ := b.allow(settings, later)
+	require.True(t, ok)
+	require.Equal(t, CircuitHalfOpen, changed)
+	ok, _, _ = b.allow(settings, later)
+	require.False(t, ok)
+	require.Equal(t, CircuitOpen, b.record(settings, outcomeFailure, later))
+	ok, _, _ = b.allow(settings, later)
+	require.False(t, ok)
+
+	// a canceled trial lets the next one through
+	later = later.Add(time.Second)
+	ok, _, _ = b.allow(settings, later)
+	require.True(t, ok)
+	require.Equal(t, CircuitState(""), b.record(settings, outcomeIgnored, later))
+	ok, _, _ = b.allow(settings, later)
+	require.True(t, ok)
+	require.Equal(t, CircuitClosed, b.record(settings, outcomeSuccess, later))
+	require.Equal(t, CircuitClosed, b.getState())
+}
@@ -0,0 +1,378 @@
+package config
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/oldercn/restclient-go-sdk/utils"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// EnvPrefix prefixes the environment variables overriding a field, e.g.
+	// RESTCLIENT_ACCESSID overrides AccessId.
+	EnvPrefix = "RESTCLIENT_"
+	// EnvProfile selects the profile when Load is given none.
+	EnvProfile = EnvPrefix + "PROFILE"
+)
+
+// Load reads the properties from a json or yaml file, chosen by the extension
+// .json, .yaml or .yml. Besides the plain properties the file may hold named profiles
+// overriding them, and name the profile to use by default:
+//
+//	{
+//	  "AccessId": "baas_admin",
+//	  "Profile": "dev",
+//	  "Profiles": {
+//	    "dev":  {"RestUrl": "http://127.0.0.1:7939"},
+//	    "prod": {"RestUrl": "https://rest.example.com", "RetryMaxAttempts": 10}
+//	  }
+//	}
+//
+// The profile is the given one, else the one in RESTCLIENT_PROFILE, else the default
+// of the file. Environment variables named RESTCLIENT_ and the upper cased field name
+// override the result, which is validated before it is returned. Keys naming no
+// field are ignored, see LoadStrict.
+func Load(path, profile string) (RestClientProperties, error) {
+	return load(path, profile, false)
+}
+
+// LoadStrict is Load failing on keys that name no field, such as misspelled ones.
+func LoadStrict(path, profile string) (RestClientProperties, error) {
+	return load(path, profile, true)
+}
+
+func load(path, profile string, strict bool) (RestClientProperties, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return RestClientProperties{}, err
+	}
+	var doc map[string]interface{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		var yamlDoc map[interface{}]interface{}
+		if err := yaml.Unmarshal(data, &yamlDoc); err != nil {
+			return RestClientProperties{}, fmt.Errorf("fail to parse %s,err:%v", path, err)
+		}
+		converted, err := jsonCompatible(yamlDoc)
+		if err != nil {
+			return RestClientProperties{}, fmt.Errorf("fail to parse %s,err:%v", path, err)
+		}
+		doc, _ = converted.(map[string]interface{})
+	case ".json", "":
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return RestClientProperties{}, fmt.Errorf("fail to parse %s,err:%v", path, err)
+		}
+	default:
+		return RestClientProperties{}, fmt.Errorf("unsupported config format %s", ext)
+	}
+
+	properties, err := fromDocument(doc, profile, strict)
+	if err != nil {
+		return RestClientProperties{}, fmt.Errorf("fail to load %s,err:%v", path, err)
+	}
+	if err := properties.ApplyEnv(); err != nil {
+		return RestClientProperties{}, err
+	}
+	if err := properties.Validate(); err != nil {
+		return RestClientProperties{}, err
+	}
+	return properties, nil
+}
+
+func fromDocument(doc map[string]interface{}, profile string, strict bool) (RestClientProperties, error) {
+	profiles, _ := doc["Profiles"].(map[string]interface{})
+	if profile == "" {
+		profile = os.Getenv(EnvProfile)
+	}
+	if profile == "" {
+		profile, _ = doc["Profile"].(string)
+	}
+	delete(doc, "Profiles")
+	delete(doc, "Profile")
+
+	var properties RestClientProperties
+	if err := decode(doc, &properties, strict); err != nil {
+		return RestClientProperties{}, err
+	}
+	if profile == "" {
+		return properties, nil
+	}
+	overrides, ok := profiles[profile].(map[string]interface{})
+	if !ok {
+		return RestClientProperties{}, fmt.Errorf("no profile %s", profile)
+	}
+	// fields missing in the profile keep their shared values
+	if err := decode(overrides, &properties, strict); err != nil {
+		return RestClientProperties{}, fmt.Errorf("profile %s: %v", profile, err)
+	}
+	return properties, nil
+}
+
+func decode(doc map[string]interface{}, properties *RestClientProperties, strict bool) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	return decoder.Decode(properties)
+}
+
+// jsonCompatible turns the maps yaml decodes into json style maps keyed by string.
+func jsonCompatible(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		converted := make(map[string]interface{}, len(v))
+		for key, elem := range v {
+			name, ok := key.(string)
+			if !ok {
+				return nil, fmt.Errorf("key %v is not a string", key)
+			}
+			convertedElem, err := jsonCompatible(elem)
+			if err != nil {
+				return nil, err
+			}
+			converted[name] = convertedElem
+		}
+		return converted, nil
+	case []interface{}:
+		for i, elem := range v {
+			convertedElem, err := jsonCompatible(elem)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = convertedElem
+		}
+	}
+	return value, nil
+}
+
+// ApplyEnv overrides every field whose RESTCLIENT_ variable is set, lists are given
+// as json arrays.
+func (properties *RestClientProperties) ApplyEnv() error {
+	value := reflect.ValueOf(properties).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		env := EnvPrefix + strings.ToUpper(field.Name)
+		raw, ok := os.LookupEnv(env)
+		if !ok {
+			continue
+		}
+		switch field.Type.Kind() {
+		case reflect.String:
+			value.Field(i).SetString(raw)
+		case reflect.Int:
+			n, err := strconv.Atoi(raw)
+			if err != nil {
+				return fmt.Errorf("%s is not an integer: %s", env, raw)
+			}
+			value.Field(i).SetInt(int64(n))
+		case reflect.Bool:
+			b, err := strconv.ParseBool(raw)
+			if err != nil {
+				return fmt.Errorf("%s is not a boolean: %s", env, raw)
+			}
+			value.Field(i).SetBool(b)
+		case reflect.Slice:
+			// e.g. RESTCLIENT_ENDPOINTS=[{"Url":"https://rest-1","Weight":3}]
+			list := reflect.New(field.Type)
+			if err := json.Unmarshal([]byte(raw), list.Interface()); err != nil {
+				return fmt.Errorf("%s is not a json array of %v: %v", env, field.Type.Elem().Name(), err)
+			}
+			value.Field(i).Set(list.Elem())
+		default:
+			return fmt.Errorf("%s can not be set from the environment", env)
+		}
+	}
+	return nil
+}
+
+// ValidationError lists every invalid field of a RestClientProperties.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid rest client properties: " + strings.Join(e.Problems, "; ")
+}
+
+// Validate checks every field and reports all problems in a *ValidationError.
+// Zero numbers select the client defaults.
+func (properties RestClientProperties) Validate() error {
+	return properties.validate(true)
+}
+
+// ValidateWithoutSecret is Validate for clients signing with a signer of their own,
+// which ignore AccessSecret.
+func (properties RestClientProperties) ValidateWithoutSecret() error {
+	return properties.validate(false)
+}
+
+func (properties RestClientProperties) validate(needSecret bool) error {
+	var problems []string
+	checkURL := func(name, value string) {
+		if u, err := url.Parse(value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s is invalid: %v", name, err))
+		} else if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("%s %s must be an http or https url", name, value))
+		}
+	}
+	if len(properties.Endpoints) > 0 {
+		for i, endpoint := range properties.Endpoints {
+			checkURL(fmt.Sprintf("Endpoints[%d].Url", i), endpoint.Url)
+			if endpoint.Weight < 0 {
+				problems = append(problems, fmt.Sprintf("Endpoints[%d].Weight %d must not be negative", i, endpoint.Weight))
+			}
+		}
+	} else if properties.RestUrl == "" {
+		problems = append(problems, "RestUrl or Endpoints is required")
+	} else {
+		checkURL("RestUrl", properties.RestUrl)
+	}
+	if properties.AccessId == "" {
+		problems = append(problems, "AccessId is required")
+	}
+	if needSecret {
+		if properties.AccessSecret == "" {
+			problems = append(problems, "AccessSecret is required")
+		} else if file, err := os.Open(properties.AccessSecret); err != nil {
+			problems = append(problems, fmt.Sprintf("AccessSecret is not readable: %v", err))
+		} else {
+			file.Close()
+		}
+	}
+	switch properties.SignAlgorithm {
+	case "", utils.SignAlgorithmSHA256, utils.SignAlgorithmSM2:
+	default:
+		problems = append(problems, fmt.Sprintf("SignAlgorithm %s must be %s or %s", properties.SignAlgorithm, utils.SignAlgorithmSHA256, utils.SignAlgorithmSM2))
+	}
+	if _, err := ParseTLSVersion(properties.TLSMinVersion); err != nil {
+		problems = append(problems, err.Error())
+	}
+	if (properties.TLSCertFile == "") != (properties.TLSKeyFile == "") {
+		problems = append(problems, "TLSCertFile and TLSKeyFile must be set together")
+	}
+	for _, file := range []struct{ name, path string }{
+		{"TLSCAFile", properties.TLSCAFile},
+		{"TLSCertFile", properties.TLSCertFile},
+		{"TLSKeyFile", properties.TLSKeyFile},
+	} {
+		if file.path == "" {
+			continue
+		}
+		if f, err := os.Open(file.path); err != nil {
+			problems = append(problems, fmt.Sprintf("%s is not readable: %v", file.name, err))
+		} else {
+			f.Close()
+		}
+	}
+	if properties.ProxyUrl != "" {
+		if u, err := url.Parse(properties.ProxyUrl); err != nil {
+			problems = append(problems, fmt.Sprintf("ProxyUrl is invalid: %v", err))
+		} else if (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("ProxyUrl %s must be an http, https or socks5 url", redactURL(u)))
+		}
+	}
+	for i, limit := range properties.RateLimits {
+		if limit.Rate < 0 || limit.Burst < 0 || limit.MaxInFlight < 0 {
+			problems = append(problems, fmt.Sprintf("RateLimits[%d] must not be negative", i))
+		} else if limit.Rate == 0 && limit.MaxInFlight == 0 {
+			problems = append(problems, fmt.Sprintf("RateLimits[%d] needs a Rate or MaxInFlight", i))
+		}
+		for j, other := range properties.RateLimits[:i] {
+			if limit.BizId == other.BizId && limit.TenantId == other.TenantId && limit.Class == other.Class {
+				problems = append(problems, fmt.Sprintf("RateLimits[%d] has the same BizId, TenantId and Class as RateLimits[%d]", i, j))
+				break
+			}
+		}
+	}
+	for _, limit := range []struct {
+		name       string
+		value, max int
+	}{
+		{"MaxIdleConns", properties.MaxIdleConns, 10000},
+		{"IdleConnTimeout", properties.IdleConnTimeout, 24 * 3600},
+		{"RetryMaxAttempts", properties.RetryMaxAttempts, 100},
+		{"BackOffPeriod", properties.BackOffPeriod, 10 * 60 * 1000},
+		{"HealthCheckInterval", properties.HealthCheckInterval, 24 * 3600},
+		{"CircuitBreakerFailures", properties.CircuitBreakerFailures, 1000},
+		{"CircuitBreakerErrorRate", properties.CircuitBreakerErrorRate, 100},
+		{"CircuitBreakerWindow", properties.CircuitBreakerWindow, 10000},
+		{"CircuitBreakerOpenDuration", properties.CircuitBreakerOpenDuration, 3600 * 1000},
+	} {
+		if limit.value < 0 || limit.value > limit.max {
+			problems = append(problems, fmt.Sprintf("%s %d is out of range [0, %d]", limit.name, limit.value, limit.max))
+		}
+	}
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// tlsVersions are the values of TLSMinVersion.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ParseTLSVersion returns the tls.VersionTLS constant of a TLSMinVersion, 0 for an
+// empty one.
+func ParseTLSVersion(version string) (uint16, error) {
+	if version == "" {
+		return 0, nil
+	}
+	if v, ok := tlsVersions[version]; ok {
+		return v, nil
+	}
+	return 0, fmt.Errorf("TLSMinVersion %s must be one of 1.0, 1.1, 1.2 or 1.3", version)
+}
+
+// redactURL prints u with its password replaced like URL.Redacted of Go 1.15, proxy
+// urls may carry credentials.
+func redactURL(u *url.URL) string {
+	if _, ok := u.User.Password(); ok {
+		copied := *u
+		copied.User = url.UserPassword(u.User.Username(), "xxxxx")
+		return copied.String()
+	}
+	return u.String()
+}
+
+// Dump prints the properties one field per line, sorted by name, with the values
+// of fields tagged redact:"true" replaced and the passwords of those tagged redact:"url".
+func (properties RestClientProperties) Dump() string {
+	value := reflect.ValueOf(properties)
+	lines := make([]string, 0, value.NumField())
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		fieldValue := fmt.Sprintf("%v", value.Field(i).Interface())
+		switch field.Tag.Get("redact") {
+		case "true":
+			if fieldValue != "" {
+				fieldValue = "******"
+			}
+		case "url":
+			if u, err := url.Parse(fieldValue); err == nil {
+				fieldValue = redactURL(u)
+			}
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", field.Name, fieldValue))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
@@ -1,13 +1,76 @@
 package config
 
 type RestClientProperties struct {
-	RestUrl           string `json:"RestUrl"`
-	AccessId          string `json:"AccessId""`
-	AccessSecret      string `json:"AccessSecret"`
+	RestUrl string `json:"RestUrl" yaml:"RestUrl"`
+	// 多个网关节点,设置后取代 RestUrl,请求按权重分配并在节点故障时切换
+	Endpoints []Endpoint `json:"Endpoints" yaml:"Endpoints"`
+	// 各节点的令牌互不通用时为 true,切换到新节点前先与其握手
+	TokenPerEndpoint bool `json:"TokenPerEndpoint" yaml:"TokenPerEndpoint"`
+	// 探测故障节点的间隔,单位为秒
+	HealthCheckInterval int    `json:"HealthCheckInterval" yaml:"HealthCheckInterval"`
+	AccessId            string `json:"AccessId" yaml:"AccessId"`
+	AccessSecret        string `json:"AccessSecret" yaml:"AccessSecret" redact:"true"` // 私钥文件路径
+	// 握手签名算法,SHA256 或 SM2,为空时按私钥类型选择
+	SignAlgorithm string `json:"SignAlgorithm" yaml:"SignAlgorithm"`
 
-	MaxIdleConns    int `json:"MaxIdleConns"`
-	IdleConnTimeout int `json:"IdleConnTimeout"` // 单位为秒
+	MaxIdleConns    int `json:"MaxIdleConns" yaml:"MaxIdleConns"`
+	IdleConnTimeout int `json:"IdleConnTimeout" yaml:"IdleConnTimeout"` // 单位为秒
 
-	RetryMaxAttempts int `json:"RetryMaxAttempts"` // http.client 重试次数
-	BackOffPeriod    int `json:"BackOffPeriod"`    // http.client重试间隔,单位为毫秒
+	RetryMaxAttempts int `json:"RetryMaxAttempts" yaml:"RetryMaxAttempts"` // http.client 重试次数
+	BackOffPeriod    int `json:"BackOffPeriod" yaml:"BackOffPeriod"`       // http.client重试间隔,单位为毫秒
+
+	// 熔断配置,同一节点上同类方法连续失败或错误率达到阈值后熔断,熔断期间请求直接返回 ErrCircuitOpen
+	CircuitBreakerDisabled     bool `json:"CircuitBreakerDisabled" yaml:"CircuitBreakerDisabled"`
+	CircuitBreakerFailures     int  `json:"CircuitBreakerFailures" yaml:"CircuitBreakerFailures"`         // 连续失败次数,为 0 时为 5
+	CircuitBreakerErrorRate    int  `json:"CircuitBreakerErrorRate" yaml:"CircuitBreakerErrorRate"`       // 最近 CircuitBreakerWindow 次请求中失败的百分比,为 0 时为 50
+	CircuitBreakerWindow       int  `json:"CircuitBreakerWindow" yaml:"CircuitBreakerWindow"`             // 计算错误率的请求数,为 0 时为 20
+	CircuitBreakerOpenDuration int  `json:"CircuitBreakerOpenDuration" yaml:"CircuitBreakerOpenDuration"` // 熔断时长,之后放行一次试探请求,单位为毫秒,为 0 时为 30000
+
+	// 限流配置,请求发出前按链、租户和方法类别限制速率及并发数
+	RateLimits []RateLimit `json:"RateLimits" yaml:"RateLimits"`
+	// 为 true 时超限的请求等待放行,否则立即返回 ErrRateLimited
+	RateLimitWait bool `json:"RateLimitWait" yaml:"RateLimitWait"`
+
+	// TLS 配置,均为空时使用系统根证书
+	TLSCAFile     string `json:"TLSCAFile" yaml:"TLSCAFile"`                 // 信任的 CA 证书文件,PEM 格式
+	TLSCertFile   string `json:"TLSCertFile" yaml:"TLSCertFile"`             // 双向认证的客户端证书链文件,PEM 格式
+	TLSKeyFile    string `json:"TLSKeyFile" yaml:"TLSKeyFile" redact:"true"` // 双向认证的客户端私钥文件路径
+	TLSMinVersion string `json:"TLSMinVersion" yaml:"TLSMinVersion"`         // 最低 TLS 版本,1.0 至 1.3,为空时为 1.2
+	TLSServerName string `json:"TLSServerName" yaml:"TLSServerName"`         // 覆盖 SNI 及证书校验的服务器名
+	ProxyUrl      string `json:"ProxyUrl" yaml:"ProxyUrl" redact:"url"`      // http、https 或 socks5 代理,为空时直连
+}
+
+// Endpoint is a gateway node of the rest server.
+type Endpoint struct {
+	Url    string `json:"Url" yaml:"Url"`
+	Weight int    `json:"Weight" yaml:"Weight"` // 权重,为 0 时为 1
+}
+
+// RateLimit limits the requests matching BizId, TenantId and Class, empty ones match
+// every request. All requests matching a RateLimit share its limits, a request has
+// to pass every RateLimit it matches.
+type RateLimit struct {
+	BizId    string `json:"BizId" yaml:"BizId"`
+	TenantId string `json:"TenantId" yaml:"TenantId"`
+	Class    string `json:"Class" yaml:"Class"` // 方法类别,read、write 或 deploy
+
+	Rate        float64 `json:"Rate" yaml:"Rate"`               // 每秒请求数,为 0 时不限
+	Burst       int     `json:"Burst" yaml:"Burst"`             // 令牌桶容量,为 0 时为 Rate 向上取整
+	MaxInFlight int     `json:"MaxInFlight" yaml:"MaxInFlight"` // 最大并发请求数,为 0 时不限
+}
+
+// ResolvedEndpoints returns the Endpoints with their default weights, or RestUrl as
+// the only endpoint when there are none.
+func (properties RestClientProperties) ResolvedEndpoints() []Endpoint {
+	if len(properties.Endpoints) == 0 {
+		return []Endpoint{{Url: properties.RestUrl, Weight: 1}}
+	}
+	endpoints := make([]Endpoint, len(properties.Endpoints))
+	for i, endpoint := range properties.Endpoints {
+		if endpoint.Weight == 0 {
+			endpoint.Weight = 1
+		}
+		endpoints[i] = endpoint
+	}
+	return endpoints
 }
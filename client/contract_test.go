@@ -0,0 +1,93 @@
+package client
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/oldercn/restclient-go-sdk/bind"
+	"github.com/oldercn/restclient-go-sdk/model"
+	"github.com/oldercn/restclient-go-sdk/mychain/mychain-sdk-go/common/codec/contract/abi"
+	"github.com/oldercn/restclient-go-sdk/response"
+	"github.com/stretchr/testify/require"
+)
+
+const countABI = `[{"type":"function","name":"count","constant":true,"inputs":[],"outputs":[{"name":"","type":"uint256"}]}]`
+
+// contractServer answers contract calls with a receipt whose output is the uint256 7,
+// at once for local calls, else through QUERYRECEIPT.
+func contractServer(t *testing.T) (*RestClient, func() []model.CallRestBizParam, func()) {
+	output := make([]byte, 32)
+	output[31] = 7
+	receipt := `{"result":0,"output":"` + base64.StdEncoding.EncodeToString(output) + `"}`
+	var lock sync.Mutex
+	var params []model.CallRestBizParam
+	client, closeServer := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		var param model.CallRestBizParam
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&param))
+		lock.Lock()
+		params = append(params, param)
+		lock.Unlock()
+		switch {
+		case param.Method == model.QUERYRECEIPT:
+			writeResp(w, response.BaseResp{Success: true, Code: "200", Data: receipt})
+		case param.IsLocalTransaction:
+			writeResp(w, response.BaseResp{Success: true, Code: "200", Data: receipt})
+		default:
+			writeResp(w, response.BaseResp{Success: true, Code: "200", Data: "abcd"})
+		}
+	})
+	return client, func() []model.CallRestBizParam {
+		lock.Lock()
+		defer lock.Unlock()
+		return params
+	}, closeServer
+}
+
+func TestCallSolcContractLocal(t *testing.T) {
+	client, params, closeServer := contractServer(t)
+	defer closeServer()
+	contractABI, err := abi.JSON(strings.NewReader(countABI))
+	require.NoError(t, err)
+
+	var count *big.Int
+	_, err = client.CallSolcContractLocal(contractABI, "biz", "", "account", "tenant", "kms", "contract", "count()", "[]", `["uint256"]`, 0, &count)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), count.Int64())
+	// one local call, no receipt to wait for
+	require.Len(t, params(), 1)
+	require.True(t, params()[0].IsLocalTransaction)
+
+	_, err = client.CallSolcContractSyncWithReceipt(contractABI, "biz", "", "account", "tenant", "kms", "contract", "count()", "[]", `["uint256"]`, 0, &count)
+	require.NoError(t, err)
+	require.Len(t, params(), 3)
+	require.False(t, params()[1].IsLocalTransaction)
+	require.Equal(t, model.Method(model.QUERYRECEIPT), params()[2].Method)
+}
+
+func TestCallValidationOptIn(t *testing.T) {
+	client, params, closeServer := contractServer(t)
+	defer closeServer()
+	contractABI, err := abi.JSON(strings.NewReader(`[{"type":"function","name":"add","inputs":[{"name":"v","type":"uint256"}],"outputs":[{"name":"","type":"uint256"}]}]`))
+	require.NoError(t, err)
+
+	// the server takes numbers as strings, the local check does not
+	var sum *big.Int
+	_, err = client.CallSolcContractSyncWithReceipt(contractABI, "biz", "", "account", "tenant", "kms", "contract", "add(uint256)", `["7"]`, `["uint256"]`, 0, &sum)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), sum.Int64())
+	require.Equal(t, `["7"]`, params()[0].InputParamListStr)
+	_, _, err = client.CallContractDynamic(contractABI, "biz", "", "account", "tenant", "kms", "contract", "add(uint256)", `["7"]`, `["uint256"]`, 0)
+	require.NoError(t, err)
+	sent := len(params())
+
+	client.callValidation = true
+	_, err = client.CallSolcContractSyncWithReceipt(contractABI, "biz", "", "account", "tenant", "kms", "contract", "add(uint256)", `["7"]`, `["uint256"]`, 0, &sum)
+	_, ok := err.(*bind.CallValidationError)
+	require.Truef(t, ok, "unexpected err:%+v", err)
+	require.Len(t, params(), sent)
+}
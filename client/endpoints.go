@@ -0,0 +1,315 @@
+package client
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oldercn/restclient-go-sdk/client/config"
+)
+
+var (
+	// DefaultHealthCheckInterval is how often endpoints marked down are probed when
+	// HealthCheckInterval is 0.
+	DefaultHealthCheckInterval = 10 * time.Second
+	// MaxEndpointFailures is how many transport errors in a row mark an endpoint down.
+	MaxEndpointFailures = 3
+)
+
+// latencyWeight is the weight of a new latency in the moving average.
+const latencyWeight = 0.3
+
+// EndpointStatus is the health of an endpoint, see RestClient.Endpoints.
+type EndpointStatus struct {
+	Url    string
+	Weight int
+	// Healthy is false from MaxEndpointFailures transport errors in a row until a
+	// probe reaches the endpoint again.
+	Healthy             bool
+	ConsecutiveFailures int
+	// Latency is the exponentially weighted moving average of the attempts answered.
+	Latency time.Duration
+	// Circuits are the states of the circuit breakers used so far, by method class.
+	Circuits map[MethodClass]CircuitState
+}
+
+// endpoint is a gateway node and its health.
+type endpoint struct {
+	url    string
+	weight int
+
+	lock     sync.Mutex
+	failures int
+	latency  time.Duration
+	down     bool
+	token    string // the token of the node, with TokenPerEndpoint
+	breakers map[MethodClass]*breaker
+}
+
+func (e *endpoint) succeeded(latency time.Duration) (recovered bool) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.latency == 0 {
+		e.latency = latency
+	} else {
+		e.latency = time.Duration(latencyWeight*float64(latency) + (1-latencyWeight)*float64(e.latency))
+	}
+	recovered = e.down
+	e.failures, e.down = 0, false
+	return recovered
+}
+
+func (e *endpoint) failed() (wentDown bool) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.failures++
+	if e.failures >= MaxEndpointFailures && !e.down {
+		// the node may come back restarted, with its tokens lost
+		e.down, e.token = true, ""
+		return true
+	}
+	return false
+}
+
+// recovered marks an endpoint a probe reached as healthy again.
+func (e *endpoint) recovered() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.failures, e.down = 0, false
+}
+
+func (e *endpoint) healthy() bool {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return !e.down
+}
+
+func (e *endpoint) getToken() string {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.token
+}
+
+func (e *endpoint) setToken(token string) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.token = token
+}
+
+func (e *endpoint) status() EndpointStatus {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	circuits := make(map[MethodClass]CircuitState, len(e.breakers))
+	for class, b := range e.breakers {
+		circuits[class] = b.getState()
+	}
+	return EndpointStatus{Url: e.url, Weight: e.weight, Healthy: !e.down, ConsecutiveFailures: e.failures, Latency: e.latency, Circuits: circuits}
+}
+
+// endpointPool balances the requests of a client over its endpoints.
+type endpointPool struct {
+	endpoints []*endpoint
+}
+
+func newEndpointPool(restClientProperties config.RestClientProperties) *endpointPool {
+	pool := &endpointPool{}
+	for _, e := range restClientProperties.ResolvedEndpoints() {
+		pool.endpoints = append(pool.endpoints, &endpoint{url: e.Url, weight: e.Weight})
+	}
+	return pool
+}
+
+// pick chooses an endpoint by weight, among the healthy ones not tried yet, else
+// among all not tried yet, else among all of them.
+func (pool *endpointPool) pick(tried []*endpoint) *endpoint {
+	var untried, healthy []*endpoint
+	for _, e := range pool.endpoints {
+		if containsEndpoint(tried, e) {
+			continue
+		}
+		untried = append(untried, e)
+		if e.healthy() {
+			healthy = append(healthy, e)
+		}
+	}
+	switch {
+	case len(healthy) > 0:
+		return pickWeighted(healthy)
+	case len(untried) > 0:
+		return pickWeighted(untried)
+	default:
+		return pickWeighted(pool.endpoints)
+	}
+}
+
+func pickWeighted(endpoints []*endpoint) *endpoint {
+	total := 0
+	for _, e := range endpoints {
+		total += e.weight
+	}
+	n := rand.Intn(total)
+	for _, e := range endpoints {
+		if n < e.weight {
+			return e
+		}
+		n -= e.weight
+	}
+	return endpoints[len(endpoints)-1]
+}
+
+func containsEndpoint(endpoints []*endpoint, e *endpoint) bool {
+	for _, other := range endpoints {
+		if other == e {
+			return true
+		}
+	}
+	return false
+}
+
+// endpointsChanged tells whether next needs new endpoints and tokens.
+func endpointsChanged(old, next config.RestClientProperties) bool {
+	oldEndpoints, nextEndpoints := old.ResolvedEndpoints(), next.ResolvedEndpoints()
+	if len(oldEndpoints) != len(nextEndpoints) || old.TokenPerEndpoint != next.TokenPerEndpoint {
+		return true
+	}
+	for i := range oldEndpoints {
+		if oldEndpoints[i] != nextEndpoints[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// Endpoints returns the health of the endpoints of the client.
+func (client *RestClient) Endpoints() []EndpointStatus {
+	pool := client.current().endpoints
+	statuses := make([]EndpointStatus, len(pool.endpoints))
+	for i, e := range pool.endpoints {
+		statuses[i] = e.status()
+	}
+	return statuses
+}
+
+// endpointFailed counts a transport error of e and starts probing once it is down.
+// A single endpoint is not probed, it is picked anyway and comes back with the first
+// answer.
+func (client *RestClient) endpointFailed(state *clientState, e *endpoint) {
+	if e.failed() {
+		client.log().Warn("endpoint down", "url", e.url)
+		if len(state.endpoints.endpoints) > 1 && atomic.CompareAndSwapInt32(&client.probing, 0, 1) {
+			client.probe()
+		}
+	}
+}
+
+func (client *RestClient) endpointSucceeded(e *endpoint, latency time.Duration) {
+	if e.succeeded(latency) {
+		client.log().Info("endpoint recovered", "url", e.url)
+	}
+}
+
+// failover tells whether an endpoint of state is left that did not fail since the
+// last backoff, the next attempt goes there at once.
+func failover(state *clientState, tried []*endpoint) bool {
+	return len(tried) < len(state.endpoints.endpoints)
+}
+
+// tokenFor returns the token to send to e, with TokenPerEndpoint the one of the node,
+// which is fetched first if there is none yet.
+func (client *RestClient) tokenFor(ctx context.Context, state *clientState, e *endpoint) (string, error) {
+	if !state.properties.TokenPerEndpoint {
+		return client.Token(), nil
+	}
+	if token := e.getToken(); token != "" {
+		return token, nil
+	}
+	return client.shakeEndpoint(ctx, state, e)
+}
+
+// shakeEndpoint refreshes the token of e.
+func (client *RestClient) shakeEndpoint(ctx context.Context, state *clientState, e *endpoint) (string, error) {
+	client.shakeLock.Lock()
+	defer client.shakeLock.Unlock()
+	token, err := client.handshake(ctx, state, e.url)
+	if err != nil {
+		return "", err
+	}
+	e.setToken(token)
+	return token, nil
+}
+
+// handshakeAny handshakes with the endpoints of state until one answers.
+func (client *RestClient) handshakeAny(ctx context.Context, state *clientState) (token string, err error) {
+	var tried []*endpoint
+	for len(tried) < len(state.endpoints.endpoints) {
+		e := state.endpoints.pick(tried)
+		tried = append(tried, e)
+		if token, err = client.handshake(ctx, state, e.url); err == nil {
+			e.setToken(token)
+			return token, nil
+		}
+		client.endpointFailed(state, e)
+	}
+	return "", err
+}
+
+// probe checks the endpoints marked down every HealthCheckInterval until all of them
+// are back or Close is called. Any http answer brings an endpoint back.
+func (client *RestClient) probe() {
+	stop := client.stopped()
+	defaultInterval := DefaultHealthCheckInterval
+	go func() {
+		for {
+			interval := defaultInterval
+			if seconds := client.Properties().HealthCheckInterval; seconds != 0 {
+				interval = time.Duration(seconds) * time.Second
+			}
+			select {
+			case <-stop:
+				return
+			case <-time.After(interval):
+			}
+			state := client.current()
+			for _, e := range state.endpoints.endpoints {
+				if !e.healthy() && client.reachable(state, e, interval) {
+					e.recovered()
+					client.log().Info("endpoint recovered", "url", e.url)
+				}
+			}
+			if allHealthy(client.current()) {
+				atomic.StoreInt32(&client.probing, 0)
+				// an endpoint going down meanwhile found the probe still running
+				if allHealthy(client.current()) || !atomic.CompareAndSwapInt32(&client.probing, 0, 1) {
+					return
+				}
+			}
+		}
+	}()
+}
+
+func allHealthy(state *clientState) bool {
+	for _, e := range state.endpoints.endpoints {
+		if !e.healthy() {
+			return false
+		}
+	}
+	return true
+}
+
+func (client *RestClient) reachable(state *clientState, e *endpoint, timeout time.Duration) bool {
+	req, err := http.NewRequest(http.MethodHead, e.url, nil)
+	if err != nil {
+		return false
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	resp, err := state.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return true
+}
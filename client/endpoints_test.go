@@ -0,0 +1,184 @@
+package client
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oldercn/restclient-go-sdk/client/config"
+	"github.com/oldercn/restclient-go-sdk/model"
+	"github.com/oldercn/restclient-go-sdk/response"
+	"github.com/stretchr/testify/require"
+)
+
+// nodeServer is a gateway node handing out token-<host>, it only takes its own tokens
+// and drops connections while down.
+type nodeServer struct {
+	*httptest.Server
+	down   int32
+	calls  int32
+	lock   sync.Mutex
+	tokens []string
+}
+
+func newNodeServer() *nodeServer {
+	node := &nodeServer{}
+	node.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&node.down) == 1 {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+			return
+		}
+		if r.URL.Path == ShakeHandPath || r.Method == http.MethodHead {
+			shakeHandler(w, r)
+			return
+		}
+		atomic.AddInt32(&node.calls, 1)
+		var param model.CallRestBizParam
+		_ = json.NewDecoder(r.Body).Decode(&param)
+		node.lock.Lock()
+		node.tokens = append(node.tokens, param.Token)
+		node.lock.Unlock()
+		writeResp(w, response.BaseResp{Success: true, Code: "200"})
+	}))
+	return node
+}
+
+func (node *nodeServer) setDown(down bool) {
+	if down {
+		atomic.StoreInt32(&node.down, 1)
+	} else {
+		atomic.StoreInt32(&node.down, 0)
+	}
+}
+
+func TestEndpointsFailover(t *testing.T) {
+	defaultInterval := DefaultHealthCheckInterval
+	DefaultHealthCheckInterval = 10 * time.Millisecond
+	defer func() { DefaultHealthCheckInterval = defaultInterval }()
+	dir, err := ioutil.TempDir("", "restclient-endpoints")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	flaky, stable := newNodeServer(), newNodeServer()
+	defer flaky.Close()
+	defer stable.Close()
+
+	properties := config.RestClientProperties{
+		Endpoints:        []config.Endpoint{{Url: flaky.URL, Weight: 100}, {Url: stable.URL, Weight: 1}},
+		AccessId:         "access",
+		AccessSecret:     writeTestKey(t, dir),
+		RetryMaxAttempts: 2,
+		BackOffPeriod:    1,
+	}
+	logger := &recordingLogger{}
+	client, err := NewRestClientFromProperties(properties, WithLogger(logger))
+	require.NoError(t, err)
+	defer client.Close()
+
+	// every call fails over to the stable node at once
+	flaky.setDown(true)
+	for i := 0; i < MaxEndpointFailures+2; i++ {
+		require.NoError(t, queryReceipt(client))
+	}
+	require.Equal(t, int32(MaxEndpointFailures+2), atomic.LoadInt32(&stable.calls))
+	statuses := client.Endpoints()
+	require.False(t, statuses[0].Healthy)
+	require.Equal(t, MaxEndpointFailures, statuses[0].ConsecutiveFailures)
+	require.True(t, statuses[1].Healthy)
+	require.NotZero(t, statuses[1].Latency)
+	require.Contains(t, logger.String(), "warn endpoint down [url "+flaky.URL)
+
+	// the probes bring the node back
+	flaky.setDown(false)
+	require.Eventually(t, func() bool { return client.Endpoints()[0].Healthy }, time.Second, 10*time.Millisecond)
+	require.NoError(t, queryReceipt(client))
+	require.NotZero(t, atomic.LoadInt32(&flaky.calls))
+	// and stop with all nodes healthy
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&client.probing) == 0 }, time.Second, 10*time.Millisecond)
+
+	// all nodes down
+	flaky.setDown(true)
+	stable.setDown(true)
+	require.Error(t, queryReceipt(client))
+}
+
+func TestEndpointsSingleNotProbed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restclient-endpoints")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	node := newNodeServer()
+	defer node.Close()
+
+	properties := config.RestClientProperties{
+		RestUrl:          node.URL,
+		AccessId:         "access",
+		AccessSecret:     writeTestKey(t, dir),
+		RetryMaxAttempts: 1,
+		BackOffPeriod:    1,
+	}
+	client, err := NewRestClientFromProperties(properties)
+	require.NoError(t, err)
+
+	node.setDown(true)
+	for i := 0; i < MaxEndpointFailures; i++ {
+		require.Error(t, queryReceipt(client))
+	}
+	require.False(t, client.Endpoints()[0].Healthy)
+	require.Zero(t, atomic.LoadInt32(&client.probing))
+
+	// the next answer brings it back without a probe
+	node.setDown(false)
+	require.NoError(t, queryReceipt(client))
+	require.True(t, client.Endpoints()[0].Healthy)
+}
+
+func TestEndpointsWeight(t *testing.T) {
+	light, heavy := newNodeServer(), newNodeServer()
+	defer light.Close()
+	defer heavy.Close()
+	client := &RestClient{RestClientProperties: config.RestClientProperties{
+		Endpoints: []config.Endpoint{{Url: light.URL, Weight: 1}, {Url: heavy.URL, Weight: 3}},
+		AccessId:  "access",
+	}, RestToken: "token", httpClient: http.DefaultClient}
+
+	for i := 0; i < 400; i++ {
+		require.NoError(t, queryReceipt(client))
+	}
+	require.Equal(t, int32(400), atomic.LoadInt32(&light.calls)+atomic.LoadInt32(&heavy.calls))
+	require.True(t, atomic.LoadInt32(&heavy.calls) > 2*atomic.LoadInt32(&light.calls))
+}
+
+func TestEndpointsTokenPerEndpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restclient-endpoints")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	first, second := newNodeServer(), newNodeServer()
+	defer first.Close()
+	defer second.Close()
+
+	properties := config.RestClientProperties{
+		Endpoints:        []config.Endpoint{{Url: first.URL}, {Url: second.URL}},
+		TokenPerEndpoint: true,
+		AccessId:         "access",
+		AccessSecret:     writeTestKey(t, dir),
+		BackOffPeriod:    1,
+	}
+	client, err := NewRestClientFromProperties(properties)
+	require.NoError(t, err)
+	defer client.Close()
+	for i := 0; i < 20 || atomic.LoadInt32(&first.calls) == 0 || atomic.LoadInt32(&second.calls) == 0; i++ {
+		require.NoError(t, queryReceipt(client))
+	}
+	// every node gets a token of its own
+	for _, node := range []*nodeServer{first, second} {
+		for _, token := range node.tokens {
+			require.Equal(t, "token-"+node.Listener.Addr().String(), token)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package client
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/oldercn/restclient-go-sdk/mychain"
+	"github.com/oldercn/restclient-go-sdk/mychain/mychain-sdk-go/common/codec/contract/abi"
+)
+
+// ContractRevertError is returned when the receipt of a contract call reports a non zero result.
+// Revert holds the decoded revert reason, panic code or custom error, it is nil when the
+// output is empty or could not be decoded.
+type ContractRevertError struct {
+	Hash    string
+	Result  int64
+	GasUsed int64
+	Output  []byte
+	Revert  *abi.Revert
+}
+
+func (e *ContractRevertError) Error() string {
+	reason := "no revert data"
+	if e.Revert != nil {
+		reason = e.Revert.String()
+	} else if len(e.Output) > 0 {
+		reason = fmt.Sprintf("undecoded output:0x%x", e.Output)
+	}
+	return fmt.Sprintf("contract call failed,hash:%v result:%v %v", e.Hash, e.Result, reason)
+}
+
+// ParseReceipt unmarshals the receipt data returned by QUERYRECEIPT and turns a failed
+// receipt into a *ContractRevertError. contractABI is optional, it is only needed to
+// decode the custom errors declared by the contract.
+func ParseReceipt(contractABI *abi.ABI, hash, receiptData string) (mychain.TransactionReceipt, error) {
+	transactionReceipt := mychain.GetDefaultTransactionReceipt()
+	err := json.Unmarshal([]byte(receiptData), &transactionReceipt)
+	if err != nil {
+		return transactionReceipt, err
+	}
+	if transactionReceipt.Result == 0 {
+		return transactionReceipt, nil
+	}
+	revertErr := &ContractRevertError{
+		Hash:    hash,
+		Result:  transactionReceipt.Result,
+		GasUsed: transactionReceipt.GasUsed,
+	}
+	if transactionReceipt.Output != "" {
+		output, err := base64.StdEncoding.DecodeString(transactionReceipt.Output)
+		if err != nil {
+			// the call failed all the same, only its output is lost
+			return transactionReceipt, revertErr
+		}
+		revertErr.Output = output
+		if contractABI == nil {
+			contractABI = &abi.ABI{}
+		}
+		if revert, err := contractABI.UnpackRevert(output); err == nil {
+			revertErr.Revert = revert
+		}
+	}
+	return transactionReceipt, revertErr
+}
@@ -0,0 +1,35 @@
+package client
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseReceipt(t *testing.T) {
+	receipt, err := ParseReceipt(nil, "hash", `{"result":0,"gasUsed":10,"output":""}`)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), receipt.GasUsed)
+
+	output, err := hex.DecodeString("08c379a0" + strings.Repeat("0", 62) + "20" + strings.Repeat("0", 63) + "2" + hex.EncodeToString([]byte("no")) + strings.Repeat("0", 60))
+	require.NoError(t, err)
+	_, err = ParseReceipt(nil, "hash", `{"result":10201,"output":"`+base64.StdEncoding.EncodeToString(output)+`"}`)
+	revertErr, ok := err.(*ContractRevertError)
+	require.Truef(t, ok, "unexpected err:%+v", err)
+	require.Equal(t, int64(10201), revertErr.Result)
+	require.Equal(t, "no", revertErr.Revert.Reason)
+	require.Equal(t, "contract call failed,hash:hash result:10201 execution reverted: no", revertErr.Error())
+}
+
+func TestParseReceiptUndecodableOutput(t *testing.T) {
+	_, err := ParseReceipt(nil, "hash", `{"result":10201,"gasUsed":7,"output":"not base64!"}`)
+	revertErr, ok := err.(*ContractRevertError)
+	require.Truef(t, ok, "unexpected err:%+v", err)
+	require.Equal(t, int64(10201), revertErr.Result)
+	require.Equal(t, int64(7), revertErr.GasUsed)
+	require.Nil(t, revertErr.Output)
+	require.Nil(t, revertErr.Revert)
+}
@@ -0,0 +1,321 @@
+package client
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/oldercn/restclient-go-sdk/model"
+	"github.com/oldercn/restclient-go-sdk/response"
+)
+
+// DefaultOrderStoreCapacity is how many orders the store of a client remembers when
+// WithOrderStore is not given.
+var DefaultOrderStoreCapacity = 10000
+
+// ErrOrderInFlight is what an *OrderInFlightError is, for errors.Is.
+var ErrOrderInFlight = errors.New("order in flight")
+
+// OrderInFlightError is returned for a write whose orderId is already being sent by
+// another call of the client.
+type OrderInFlightError struct {
+	BizId   string
+	OrderId string
+}
+
+func (e *OrderInFlightError) Error() string {
+	return fmt.Sprintf("order %v of bizid %v is in flight", e.OrderId, e.BizId)
+}
+
+func (e *OrderInFlightError) Is(target error) bool {
+	return target == ErrOrderInFlight
+}
+
+// ErrOrderUnconfirmed is what an *OrderUnconfirmedError is, for errors.Is.
+var ErrOrderUnconfirmed = errors.New("order unconfirmed")
+
+// OrderUnconfirmedError is returned for a write an attempt of which may have reached
+// the server without an answer, when the OrderLookup failed to tell whether it sent
+// its transaction. The write is not sent again, by this call or a later one with the
+// same orderId, until an OrderLookup says it did not. Query the receipt of the
+// orderId, or send the write with another orderId.
+type OrderUnconfirmedError struct {
+	BizId   string
+	OrderId string
+	Err     error // the error of the attempt, nil when an earlier call left the order unconfirmed
+}
+
+func (e *OrderUnconfirmedError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("order %v of bizid %v may have been sent", e.OrderId, e.BizId)
+	}
+	return fmt.Sprintf("order %v of bizid %v may have been sent,err:%+v", e.OrderId, e.BizId, e.Err)
+}
+
+func (e *OrderUnconfirmedError) Is(target error) bool {
+	return target == ErrOrderUnconfirmed
+}
+
+func (e *OrderUnconfirmedError) Unwrap() error {
+	return e.Err
+}
+
+// OrderStore remembers the hash of the transaction sent for an orderId, so that a
+// write sent again returns the hash instead of sending another transaction. The hash
+// is "" for an order that may have been sent, see OrderUnconfirmedError. It must be
+// safe for concurrent use.
+type OrderStore interface {
+	// Load returns the hash stored for orderId on bizId.
+	Load(bizId, orderId string) (hash string, ok bool)
+	Store(bizId, orderId, hash string)
+}
+
+// OrderLookup asks the server for the hash of the transaction of orderId on bizId, ""
+// when there is none. It is called before a write an attempt of which may have
+// reached the server is sent again. By default the client queries QUERYTRANSACTIONBIZ
+// for the orderId, see WithOrderLookup.
+type OrderLookup func(ctx context.Context, bizId, orderId string) (hash string, err error)
+
+// WithOrderStore makes the client remember the orders it sent in store, a
+// MemoryOrderStore of DefaultOrderStoreCapacity by default.
+func WithOrderStore(store OrderStore) Option {
+	return func(options *clientOptions) {
+		options.orderStore = store
+	}
+}
+
+// WithOrderLookup makes the client ask lookup for the transaction of an orderId
+// before it sends a write again that may have reached the server, instead of
+// querying QUERYTRANSACTIONBIZ. For gateways answering that query differently.
+func WithOrderLookup(lookup OrderLookup) Option {
+	return func(options *clientOptions) {
+		options.orderLookup = lookup
+	}
+}
+
+type orderKey struct {
+	bizId, orderId string
+}
+
+// MemoryOrderStore is an OrderStore in memory, which forgets the oldest orders
+// beyond its capacity.
+type MemoryOrderStore struct {
+	lock     sync.Mutex
+	capacity int
+	hashes   map[orderKey]string
+	order    []orderKey // oldest first
+}
+
+// NewMemoryOrderStore returns an empty MemoryOrderStore remembering capacity orders.
+func NewMemoryOrderStore(capacity int) *MemoryOrderStore {
+	return &MemoryOrderStore{capacity: capacity, hashes: make(map[orderKey]string)}
+}
+
+func (s *MemoryOrderStore) Load(bizId, orderId string) (string, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	hash, ok := s.hashes[orderKey{bizId, orderId}]
+	return hash, ok
+}
+
+func (s *MemoryOrderStore) Store(bizId, orderId, hash string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key := orderKey{bizId, orderId}
+	if _, ok := s.hashes[key]; !ok {
+		s.order = append(s.order, key)
+	}
+	s.hashes[key] = hash
+	for len(s.order) > s.capacity {
+		delete(s.hashes, s.order[0])
+		s.order = s.order[1:]
+	}
+}
+
+func (client *RestClient) orders() OrderStore {
+	client.orderStoreOnce.Do(func() {
+		if client.orderStore == nil {
+			client.orderStore = NewMemoryOrderStore(DefaultOrderStoreCapacity)
+		}
+	})
+	return client.orderStore
+}
+
+// beginOrder claims orderId for a call, the function returned gives it up.
+func (client *RestClient) beginOrder(bizId, orderId string) (func(), error) {
+	key := orderKey{bizId, orderId}
+	client.pendingLock.Lock()
+	defer client.pendingLock.Unlock()
+	if _, ok := client.pendingOrders[key]; ok {
+		return nil, &OrderInFlightError{BizId: bizId, OrderId: orderId}
+	}
+	if client.pendingOrders == nil {
+		client.pendingOrders = make(map[orderKey]struct{})
+	}
+	client.pendingOrders[key] = struct{}{}
+	return func() {
+		client.pendingLock.Lock()
+		defer client.pendingLock.Unlock()
+		delete(client.pendingOrders, key)
+	}, nil
+}
+
+// idempotent tells whether a write of info with param is deduplicated by orderId, a
+// local call sends no transaction.
+func idempotent(info MethodInfo, param *model.CallRestBizParam) bool {
+	return info.ProducesTxHash && param.OrderId != "" && !param.IsLocalTransaction
+}
+
+// sendOnce sends call with send unless it is a write whose orderId was sent before,
+// and remembers the hash of the transaction it sends. It runs inside the interceptors,
+// on the orderId they leave in the call.
+func (client *RestClient) sendOnce(ctx context.Context, call *Call, send Invoker) (response.BaseResp, error) {
+	param := call.BizParam()
+	if param == nil || !idempotent(call.Info, param) {
+		return send(ctx, call)
+	}
+	done, err := client.beginOrder(param.BizId, param.OrderId)
+	if err != nil {
+		return response.BaseResp{}, err
+	}
+	defer done()
+	if hash, ok := client.orders().Load(param.BizId, param.OrderId); ok {
+		if hash == "" {
+			// an earlier call may have sent it
+			if hash, err = client.confirmOrder(ctx, param, nil); err != nil {
+				return response.BaseResp{}, err
+			}
+		}
+		if hash != "" {
+			return client.sentOrderResp(param, hash), nil
+		}
+	}
+	baseResp, err := send(ctx, call)
+	if err == nil && baseResp.Success && baseResp.Data != "" {
+		client.orders().Store(param.BizId, param.OrderId, baseResp.Data)
+	}
+	return baseResp, err
+}
+
+// orderOf returns param if it is a write deduplicated by orderId, else nil.
+func orderOf(info MethodInfo, param interface{}) *model.CallRestBizParam {
+	if order, ok := param.(*model.CallRestBizParam); ok && idempotent(info, order) {
+		return order
+	}
+	return nil
+}
+
+// confirmSent runs before order is sent again after an attempt failing with maybeSent
+// may have reached the server. It is done with the response of the transaction sent
+// before, or with the error of an order that can not be confirmed. Nothing is done
+// for a nil order or maybeSent.
+func (client *RestClient) confirmSent(ctx context.Context, order *model.CallRestBizParam, maybeSent error) (response.BaseResp, bool, error) {
+	if order == nil || maybeSent == nil {
+		return response.BaseResp{}, false, nil
+	}
+	hash, err := client.confirmOrder(ctx, order, maybeSent)
+	if err != nil {
+		return response.BaseResp{}, true, err
+	}
+	if hash != "" {
+		return client.sentOrderResp(order, hash), true, nil
+	}
+	return response.BaseResp{}, false, nil
+}
+
+// orderFailed returns the error of a request given up with err, an
+// *OrderUnconfirmedError when order may have been sent by an attempt failing with
+// maybeSent.
+func (client *RestClient) orderFailed(order *model.CallRestBizParam, maybeSent error, err error) error {
+	if order != nil && maybeSent != nil {
+		return client.unconfirmed(order, maybeSent)
+	}
+	return err
+}
+
+// confirmable tells whether the client can find out if a failed attempt of the
+// write param sent its transaction, which makes sending it again safe.
+func (client *RestClient) confirmable(info MethodInfo, param interface{}) bool {
+	return orderOf(info, param) != nil
+}
+
+// lookupOrder is the default OrderLookup, it queries QUERYTRANSACTIONBIZ for orderId.
+func (client *RestClient) lookupOrder(ctx context.Context, bizId, orderId string) (string, error) {
+	baseResp, err := client.ChainCallForBizContext(ctx, model.CallRestBizParam{
+		BaseParam: model.BaseParam{
+			AccessId: client.Properties().AccessId,
+			BizId:    bizId,
+			Method:   model.QUERYTRANSACTIONBIZ,
+		},
+		OrderId: orderId,
+	})
+	if err != nil {
+		return "", err
+	}
+	if !baseResp.Success {
+		if baseResp.Code == model.ServiceQueryNoResult {
+			return "", nil
+		}
+		return "", fmt.Errorf("%v return code %v", model.QUERYTRANSACTIONBIZ, baseResp.Code)
+	}
+	return txHashOf(baseResp.Data)
+}
+
+// txHashOf returns the hash a QUERYTRANSACTIONBIZ answered with in Data, the hash
+// itself or a json transaction carrying it in "hash".
+func txHashOf(data string) (string, error) {
+	var tx struct {
+		Hash string `json:"hash"`
+	}
+	if err := json.Unmarshal([]byte(data), &tx); err == nil && tx.Hash != "" {
+		return tx.Hash, nil
+	}
+	if _, err := hex.DecodeString(strings.TrimPrefix(data, "0x")); err == nil && data != "" {
+		return data, nil
+	}
+	return "", fmt.Errorf("no transaction hash in %v answer %v", model.QUERYTRANSACTIONBIZ, redacted(data))
+}
+
+// confirmOrder finds out whether the write param, an attempt of which may have
+// reached the server, sent its transaction. It returns the hash of the transaction,
+// "" when the OrderLookup of the client says there is none and param may be sent
+// again, else an *OrderUnconfirmedError wrapping cause.
+func (client *RestClient) confirmOrder(ctx context.Context, param *model.CallRestBizParam, cause error) (string, error) {
+	if hash, ok := client.orders().Load(param.BizId, param.OrderId); ok && hash != "" {
+		return hash, nil
+	}
+	if ctx.Err() != nil {
+		return "", client.unconfirmed(param, cause)
+	}
+	lookup := client.orderLookup
+	if lookup == nil {
+		lookup = client.lookupOrder
+	}
+	hash, err := lookup(ctx, param.BizId, param.OrderId)
+	if err != nil {
+		client.log().Warn("fail to look up order", "bizid", param.BizId, "orderId", param.OrderId, "err", err.Error())
+		return "", client.unconfirmed(param, cause)
+	}
+	if hash != "" {
+		client.orders().Store(param.BizId, param.OrderId, hash)
+	}
+	return hash, nil
+}
+
+// unconfirmed remembers the write param as maybe sent, so that it is not sent again
+// before an OrderLookup answers, and returns the error telling so.
+func (client *RestClient) unconfirmed(param *model.CallRestBizParam, cause error) error {
+	client.orders().Store(param.BizId, param.OrderId, "")
+	client.log().Warn("order may have been sent", "bizid", param.BizId, "orderId", param.OrderId)
+	return &OrderUnconfirmedError{BizId: param.BizId, OrderId: param.OrderId, Err: cause}
+}
+
+// sentOrderResp is the response of a write whose transaction was sent before.
+func (client *RestClient) sentOrderResp(param *model.CallRestBizParam, hash string) response.BaseResp {
+	client.log().Info("order already sent", "bizid", param.BizId, "orderId", param.OrderId, "hash", hash)
+	return response.BaseResp{Success: true, Code: "200", Data: hash}
+}
@@ -0,0 +1,271 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oldercn/restclient-go-sdk/model"
+	"github.com/oldercn/restclient-go-sdk/response"
+	"github.com/stretchr/testify/require"
+)
+
+func deposit(client *RestClient, orderId string) (response.BaseResp, error) {
+	return client.Deposit("biz", orderId, "account", "tenant", "content", "kms", 0)
+}
+
+func TestIdempotentRetry(t *testing.T) {
+	var lock sync.Mutex
+	sent := make(map[string]string)
+	var deposits int32
+	client, closeServer := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&deposits, 1)
+		lock.Lock()
+		sent["order-1"] = "abcd01"
+		lock.Unlock()
+		// the transaction is sent but the answer is lost
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	})
+	defer closeServer()
+	var lookups []string
+	client.orderLookup = func(ctx context.Context, bizId, orderId string) (string, error) {
+		lookups = append(lookups, bizId+"/"+orderId)
+		lock.Lock()
+		defer lock.Unlock()
+		return sent[orderId], nil
+	}
+
+	baseResp, err := deposit(client, "order-1")
+	require.NoError(t, err)
+	require.Equal(t, response.BaseResp{Success: true, Code: "200", Data: "abcd01", OrderId: "order-1"}, baseResp)
+	require.Equal(t, int32(1), atomic.LoadInt32(&deposits))
+	require.Equal(t, []string{"biz/order-1"}, lookups)
+
+	// sending the order again returns the hash from the local record
+	baseResp, err = deposit(client, "order-1")
+	require.NoError(t, err)
+	require.Equal(t, "abcd01", baseResp.Data)
+	require.Equal(t, int32(1), atomic.LoadInt32(&deposits))
+	require.Len(t, lookups, 1)
+}
+
+func TestIdempotentUnconfirmed(t *testing.T) {
+	var deposits, queries, drops int32
+	client, closeServer := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		var param model.CallRestBizParam
+		_ = json.NewDecoder(r.Body).Decode(&param)
+		if param.Method == model.QUERYTRANSACTIONBIZ {
+			atomic.AddInt32(&queries, 1)
+			writeResp(w, response.BaseResp{Success: false, Code: model.ServiceQueryNoResult})
+			return
+		}
+		atomic.AddInt32(&deposits, 1)
+		if atomic.AddInt32(&drops, -1) < 0 {
+			writeResp(w, response.BaseResp{Success: true, Code: "200", Data: "abcd05"})
+			return
+		}
+		// the answer is lost, the transaction may have been sent
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	})
+	defer closeServer()
+
+	// the gateway finds no transaction of the order, it is sent again
+	atomic.StoreInt32(&drops, 1)
+	baseResp, err := deposit(client, "order-5")
+	require.NoError(t, err)
+	require.Equal(t, "abcd05", baseResp.Data)
+	require.Equal(t, int32(2), atomic.LoadInt32(&deposits))
+	require.Equal(t, int32(1), atomic.LoadInt32(&queries))
+
+	// not when the lookup fails
+	client.orderLookup = func(ctx context.Context, bizId, orderId string) (string, error) {
+		return "", errors.New("lookup down")
+	}
+	atomic.StoreInt32(&drops, 1)
+	_, err = deposit(client, "order-6")
+	require.True(t, errors.Is(err, ErrOrderUnconfirmed))
+	unconfirmed, ok := err.(*OrderUnconfirmedError)
+	require.True(t, ok)
+	require.Equal(t, "biz", unconfirmed.BizId)
+	require.Equal(t, "order-6", unconfirmed.OrderId)
+	require.Error(t, unconfirmed.Err)
+	require.Equal(t, int32(3), atomic.LoadInt32(&deposits))
+
+	// nor by a later call
+	_, err = deposit(client, "order-6")
+	require.Equal(t, &OrderUnconfirmedError{BizId: "biz", OrderId: "order-6"}, err)
+	require.Equal(t, int32(3), atomic.LoadInt32(&deposits))
+
+	// it is sent again once the lookup finds no transaction
+	client.orderLookup = nil
+	baseResp, err = deposit(client, "order-6")
+	require.NoError(t, err)
+	require.Equal(t, "abcd05", baseResp.Data)
+	require.Equal(t, int32(4), atomic.LoadInt32(&deposits))
+	require.Equal(t, int32(2), atomic.LoadInt32(&queries))
+}
+
+func TestIdempotentTimeout(t *testing.T) {
+	info, _ := LookupMethod(model.DEPOSIT)
+	short := info
+	short.Timeout = 50 * time.Millisecond
+	RegisterMethod(short)
+	defer RegisterMethod(info)
+
+	var lock sync.Mutex
+	sent := make(map[string]string)
+	var deposits, queries int32
+	client, closeServer := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		var param model.CallRestBizParam
+		_ = json.NewDecoder(r.Body).Decode(&param)
+		lock.Lock()
+		hash, ok := sent[param.OrderId]
+		lock.Unlock()
+		if param.Method == model.QUERYTRANSACTIONBIZ {
+			atomic.AddInt32(&queries, 1)
+			if !ok {
+				writeResp(w, response.BaseResp{Success: false, Code: model.ServiceQueryNoResult})
+				return
+			}
+			writeResp(w, response.BaseResp{Success: true, Code: "200", Data: `{"hash":"` + hash + `","blockNumber":12}`})
+			return
+		}
+		atomic.AddInt32(&deposits, 1)
+		lock.Lock()
+		sent[param.OrderId] = "abcd09"
+		lock.Unlock()
+		// the gateway accepted the transaction but answers too late
+		time.Sleep(200 * time.Millisecond)
+		writeResp(w, response.BaseResp{Success: true, Code: "200", Data: "abcd09"})
+	})
+	defer closeServer()
+
+	baseResp, err := deposit(client, "order-9")
+	require.NoError(t, err)
+	require.Equal(t, response.BaseResp{Success: true, Code: "200", Data: "abcd09", OrderId: "order-9"}, baseResp)
+	require.Equal(t, int32(1), atomic.LoadInt32(&deposits))
+	require.Equal(t, int32(1), atomic.LoadInt32(&queries))
+}
+
+func TestTxHashOf(t *testing.T) {
+	hash, err := txHashOf(`{"hash":"abcd01","blockNumber":3}`)
+	require.NoError(t, err)
+	require.Equal(t, "abcd01", hash)
+	hash, err = txHashOf("0xabcd01")
+	require.NoError(t, err)
+	require.Equal(t, "0xabcd01", hash)
+	_, err = txHashOf(`{"blockNumber":3}`)
+	require.Error(t, err)
+	_, err = txHashOf("")
+	require.Error(t, err)
+}
+
+func TestConfirmSent(t *testing.T) {
+	client := &RestClient{}
+	order := &model.CallRestBizParam{BaseParam: model.BaseParam{BizId: "biz"}, OrderId: "order-7"}
+	lost := errors.New("connection reset")
+
+	// nothing to confirm
+	_, done, err := client.confirmSent(context.Background(), nil, lost)
+	require.False(t, done)
+	require.NoError(t, err)
+	_, done, _ = client.confirmSent(context.Background(), order, nil)
+	require.False(t, done)
+	require.Equal(t, lost, client.orderFailed(nil, lost, lost))
+
+	// the transaction was sent
+	client.orderLookup = func(ctx context.Context, bizId, orderId string) (string, error) {
+		return "abcd07", nil
+	}
+	baseResp, done, err := client.confirmSent(context.Background(), order, lost)
+	require.True(t, done)
+	require.NoError(t, err)
+	require.Equal(t, response.BaseResp{Success: true, Code: "200", Data: "abcd07"}, baseResp)
+
+	// it can not be told
+	order.OrderId = "order-8"
+	client.orderLookup = func(ctx context.Context, bizId, orderId string) (string, error) {
+		return "", errors.New("lookup down")
+	}
+	_, done, err = client.confirmSent(context.Background(), order, lost)
+	require.True(t, done)
+	require.Equal(t, &OrderUnconfirmedError{BizId: "biz", OrderId: "order-8", Err: lost}, err)
+	require.Equal(t, err, client.orderFailed(order, lost, context.Canceled))
+}
+
+func TestIdempotentOrderStore(t *testing.T) {
+	var deposits int32
+	client, closeServer := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&deposits, 1)
+		writeResp(w, response.BaseResp{Success: true, Code: "200", Data: "abcd02"})
+	})
+	defer closeServer()
+	client.orderStore = NewMemoryOrderStore(1)
+
+	for i := 0; i < 2; i++ {
+		baseResp, err := deposit(client, "order-2")
+		require.NoError(t, err)
+		require.Equal(t, "abcd02", baseResp.Data)
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&deposits))
+	hash, ok := client.orderStore.Load("biz", "order-2")
+	require.True(t, ok)
+	require.Equal(t, "abcd02", hash)
+
+	// the store forgets the oldest order beyond its capacity
+	_, err := deposit(client, "order-3")
+	require.NoError(t, err)
+	_, ok = client.orderStore.Load("biz", "order-2")
+	require.False(t, ok)
+
+	// queries are not deduplicated
+	for i := 0; i < 2; i++ {
+		require.NoError(t, queryReceipt(client))
+	}
+	require.Equal(t, int32(4), atomic.LoadInt32(&deposits))
+}
+
+func TestOrderInFlight(t *testing.T) {
+	arrived := make(chan struct{}, 1)
+	unblock := make(chan struct{})
+	client, closeServer := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		arrived <- struct{}{}
+		<-unblock
+		writeResp(w, response.BaseResp{Success: true, Code: "200", Data: "abcd03"})
+	})
+	defer closeServer()
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := deposit(client, "order-4")
+		errs <- err
+	}()
+	select {
+	case <-arrived:
+	case <-time.After(time.Second):
+		t.Fatal("no deposit arrived")
+	}
+	_, err := deposit(client, "order-4")
+	require.True(t, errors.Is(err, ErrOrderInFlight))
+	require.Equal(t, &OrderInFlightError{BizId: "biz", OrderId: "order-4"}, err)
+	close(unblock)
+	require.NoError(t, <-errs)
+
+	// once done the order is answered from the local record
+	baseResp, err := client.ChainCallForBiz(model.CallRestBizParam{
+		BaseParam:  model.BaseParam{AccessId: "access", BizId: "biz", Method: model.DEPOSIT},
+		OrderId:    "order-4",
+		Account:    "account",
+		Content:    "other content",
+		MykmsKeyId: "kms",
+	})
+	require.NoError(t, err)
+	require.Equal(t, "abcd03", baseResp.Data)
+}
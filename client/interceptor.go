@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/oldercn/restclient-go-sdk/model"
+	"github.com/oldercn/restclient-go-sdk/response"
+)
+
+// Call is a chain call as an Interceptor sees it.
+type Call struct {
+	Info MethodInfo
+	// Path is the rest api the call is posted to, on one of the endpoints.
+	Path string
+	// Param is the *model.CallRestBizParam or *model.CallRestParam of the call, with
+	// the token set and validated. Changes an interceptor makes to it are sent without
+	// being validated again.
+	Param interface{}
+}
+
+// BizParam returns the param of a ChainCallForBiz call, nil for a ChainCall.
+func (call *Call) BizParam() *model.CallRestBizParam {
+	param, _ := call.Param.(*model.CallRestBizParam)
+	return param
+}
+
+// RestParam returns the param of a ChainCall call, nil for a ChainCallForBiz.
+func (call *Call) RestParam() *model.CallRestParam {
+	param, _ := call.Param.(*model.CallRestParam)
+	return param
+}
+
+// Invoker sends a call and returns its result.
+type Invoker func(ctx context.Context, call *Call) (response.BaseResp, error)
+
+// Interceptor wraps every chain call of a client, once per call around all of its
+// attempts. It may change the call before passing it on to next, look at or replace
+// the result next returns, or answer itself without calling next at all.
+//
+// The interceptors of WithInterceptors run in the order given, the first one is the
+// outermost and sees the call first and the result last. They run inside the span
+// of the call, see WithTracer, and before the metrics of its attempts.
+//
+// Writes are deduplicated by orderId after the last interceptor, see WithOrderStore:
+// an orderId an interceptor sets is the one guarded and remembered, and a result an
+// interceptor returns without calling next is never remembered as a transaction.
+type Interceptor func(ctx context.Context, call *Call, next Invoker) (response.BaseResp, error)
+
+// TransportInterceptor wraps the http.RoundTripper of a client, e.g. to add headers
+// or encrypt bodies. It sees every http request: each attempt of a call, and the
+// handshakes, with the headers of WithTracer already set.
+//
+// The interceptors of WithTransportInterceptors are applied in the order given, the
+// first one is the outermost and sees a request first and its response last.
+type TransportInterceptor func(next http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc is a function serving as an http.RoundTripper.
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// WithInterceptors appends interceptors to the chain calls of the client.
+func WithInterceptors(interceptors ...Interceptor) Option {
+	return func(options *clientOptions) {
+		options.interceptors = append(options.interceptors, interceptors...)
+	}
+}
+
+// WithTransportInterceptors appends interceptors to the http transport of the client.
+func WithTransportInterceptors(interceptors ...TransportInterceptor) Option {
+	return func(options *clientOptions) {
+		options.transportInterceptors = append(options.transportInterceptors, interceptors...)
+	}
+}
+
+// invoke sends call through the interceptors of the client, send comes last.
+func (client *RestClient) invoke(ctx context.Context, call *Call, send Invoker) (response.BaseResp, error) {
+	next := send
+	for i := len(client.interceptors) - 1; i >= 0; i-- {
+		interceptor, inner := client.interceptors[i], next
+		next = func(ctx context.Context, call *Call) (response.BaseResp, error) {
+			return interceptor(ctx, call, inner)
+		}
+	}
+	return next(ctx, call)
+}
+
+// interceptedTransport is a transport wrapped in TransportInterceptors, it still
+// closes the idle connections of the transport beneath.
+type interceptedTransport struct {
+	http.RoundTripper
+	base *http.Transport
+}
+
+func (t interceptedTransport) CloseIdleConnections() {
+	t.base.CloseIdleConnections()
+}
+
+func wrapTransport(base *http.Transport, interceptors []TransportInterceptor) http.RoundTripper {
+	if len(interceptors) == 0 {
+		return base
+	}
+	var roundTripper http.RoundTripper = base
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		roundTripper = interceptors[i](roundTripper)
+	}
+	return interceptedTransport{RoundTripper: roundTripper, base: base}
+}
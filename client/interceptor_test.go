@@ -0,0 +1,153 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/oldercn/restclient-go-sdk/client/config"
+	"github.com/oldercn/restclient-go-sdk/model"
+	"github.com/oldercn/restclient-go-sdk/response"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterceptors(t *testing.T) {
+	var orderIds []string
+	client, closeServer := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		var param model.CallRestBizParam
+		_ = json.NewDecoder(r.Body).Decode(&param)
+		orderIds = append(orderIds, param.OrderId)
+		writeResp(w, response.BaseResp{Success: true, Code: "200", Data: "abcd"})
+	})
+	defer closeServer()
+
+	var order []string
+	observe := func(name string) Interceptor {
+		return func(ctx context.Context, call *Call, next Invoker) (response.BaseResp, error) {
+			order = append(order, name+" call "+string(call.Info.Method))
+			baseResp, err := next(ctx, call)
+			order = append(order, name+" result "+baseResp.Data)
+			return baseResp, err
+		}
+	}
+	mutate := func(ctx context.Context, call *Call, next Invoker) (response.BaseResp, error) {
+		if param := call.BizParam(); param != nil {
+			param.OrderId = "audited-" + param.OrderId
+		}
+		baseResp, err := next(ctx, call)
+		baseResp.Data = "hash " + baseResp.Data
+		return baseResp, err
+	}
+	client.interceptors = []Interceptor{observe("outer"), mutate, observe("inner")}
+
+	baseResp, err := client.Deposit("biz", "order-1", "account", "tenant", "content", "kms", 0)
+	require.NoError(t, err)
+	require.Equal(t, "hash abcd", baseResp.Data)
+	require.Equal(t, []string{"audited-order-1"}, orderIds)
+	require.Equal(t, []string{
+		"outer call DEPOSIT", "inner call DEPOSIT", "inner result abcd", "outer result hash abcd",
+	}, order)
+
+	// ChainCall params reach the interceptors too
+	client.interceptors = []Interceptor{func(ctx context.Context, call *Call, next Invoker) (response.BaseResp, error) {
+		require.Nil(t, call.BizParam())
+		require.Equal(t, "abcd", call.RestParam().Hash)
+		require.Equal(t, ChainCallPath, call.Path)
+		return next(ctx, call)
+	}}
+	_, err = client.ChainCall("abcd", "biz", "", model.QUERYRECEIPT)
+	require.NoError(t, err)
+}
+
+func TestInterceptorShortCircuit(t *testing.T) {
+	var calls int32
+	client, closeServer := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		writeResp(w, response.BaseResp{Success: true, Code: "200"})
+	})
+	defer closeServer()
+	denied := errors.New("denied by policy")
+	client.interceptors = []Interceptor{func(ctx context.Context, call *Call, next Invoker) (response.BaseResp, error) {
+		if call.BizParam().Account == "blocked" {
+			return response.BaseResp{}, denied
+		}
+		return next(ctx, call)
+	}}
+
+	_, err := client.Deposit("biz", "order-1", "blocked", "tenant", "content", "kms", 0)
+	require.Equal(t, denied, err)
+	require.Equal(t, int32(0), atomic.LoadInt32(&calls))
+	_, err = client.Deposit("biz", "order-1", "account", "tenant", "content", "kms", 0)
+	require.NoError(t, err)
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestInterceptorOrders(t *testing.T) {
+	var calls int32
+	client, closeServer := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		writeResp(w, response.BaseResp{Success: true, Code: "200", Data: "abcd"})
+	})
+	defer closeServer()
+	cached := true
+	client.interceptors = []Interceptor{func(ctx context.Context, call *Call, next Invoker) (response.BaseResp, error) {
+		if cached {
+			return response.BaseResp{Success: true, Code: "200", Data: "cached"}, nil
+		}
+		call.BizParam().OrderId = "shared"
+		return next(ctx, call)
+	}}
+
+	// a made up result is not taken for the transaction of the orderId
+	_, err := client.Deposit("biz", "order-1", "account", "tenant", "content", "kms", 0)
+	require.NoError(t, err)
+	cached = false
+	baseResp, err := client.Deposit("biz", "order-1", "account", "tenant", "content", "kms", 0)
+	require.NoError(t, err)
+	require.Equal(t, "abcd", baseResp.Data)
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// the orderId set by the interceptor is the one remembered
+	baseResp, err = client.Deposit("biz", "order-2", "account", "tenant", "content", "kms", 0)
+	require.NoError(t, err)
+	require.Equal(t, "abcd", baseResp.Data)
+	require.Equal(t, "shared", baseResp.OrderId)
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestTransportInterceptors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restclient-interceptor")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	server := newHandshakeServer()
+	defer server.Close()
+
+	var order []string
+	header := func(name string) TransportInterceptor {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				order = append(order, name+" "+req.Header.Get("X-Seen"))
+				req.Header.Set("X-Seen", name)
+				resp, err := next.RoundTrip(req)
+				order = append(order, name+" done")
+				return resp, err
+			})
+		}
+	}
+	properties := config.RestClientProperties{RestUrl: server.URL, AccessId: "access", AccessSecret: writeTestKey(t, dir)}
+	client, err := NewRestClientFromProperties(properties, WithTransportInterceptors(header("outer"), header("inner")))
+	require.NoError(t, err)
+	require.Equal(t, []string{"outer ", "inner outer", "inner done", "outer done"}, order)
+
+	// a new http client keeps the interceptors
+	properties.MaxIdleConns = 3
+	require.NoError(t, client.Reload(properties))
+	order = nil
+	require.NoError(t, queryReceipt(client))
+	require.Equal(t, []string{"outer ", "inner outer", "inner done", "outer done"}, order)
+}
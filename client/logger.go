@@ -0,0 +1,187 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/oldercn/restclient-go-sdk/response"
+	log "github.com/sirupsen/logrus"
+)
+
+// Logger receives the log records of a RestClient. keysAndValues alternate between
+// keys and values, as for log/slog, whose *slog.Logger is a Logger as it is.
+type Logger interface {
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Warn(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+}
+
+// WithLogger makes the client log to logger instead of the standard logrus logger.
+func WithLogger(logger Logger) Option {
+	return func(options *clientOptions) {
+		options.logger = logger
+	}
+}
+
+// WithUnredactedLogs makes the client log tokens, secrets and payloads in full.
+// Only for debugging, the logs then hold credentials.
+func WithUnredactedLogs() Option {
+	return func(options *clientOptions) {
+		options.unredactedLogs = true
+	}
+}
+
+type logrusLogger struct {
+	logger log.FieldLogger
+}
+
+// NewLogrusLogger logs to logger, e.g. logrus.StandardLogger() or an *logrus.Entry
+// carrying fields of its own.
+func NewLogrusLogger(logger log.FieldLogger) Logger {
+	return logrusLogger{logger: logger}
+}
+
+func (l logrusLogger) with(keysAndValues []interface{}) log.FieldLogger {
+	if len(keysAndValues) == 0 {
+		return l.logger
+	}
+	fields := make(log.Fields, len(keysAndValues)/2+1)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		key := fmt.Sprint(keysAndValues[i])
+		if i+1 == len(keysAndValues) {
+			fields["!BADKEY"] = keysAndValues[i]
+			break
+		}
+		fields[key] = keysAndValues[i+1]
+	}
+	return l.logger.WithFields(fields)
+}
+
+func (l logrusLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.with(keysAndValues).Debug(msg)
+}
+
+func (l logrusLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.with(keysAndValues).Info(msg)
+}
+
+func (l logrusLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.with(keysAndValues).Warn(msg)
+}
+
+func (l logrusLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.with(keysAndValues).Error(msg)
+}
+
+// ZapSugaredLogger is the part of *zap.SugaredLogger NewZapLogger needs, so that this
+// package does not depend on zap.
+type ZapSugaredLogger interface {
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+type zapLogger struct {
+	logger ZapSugaredLogger
+}
+
+// NewZapLogger logs to a zap logger, pass zapLogger.Sugar().
+func NewZapLogger(logger ZapSugaredLogger) Logger {
+	return zapLogger{logger: logger}
+}
+
+func (l zapLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.logger.Debugw(msg, keysAndValues...)
+}
+
+func (l zapLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.logger.Infow(msg, keysAndValues...)
+}
+
+func (l zapLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.logger.Warnw(msg, keysAndValues...)
+}
+
+func (l zapLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.logger.Errorw(msg, keysAndValues...)
+}
+
+type nopLogger struct{}
+
+// NopLogger discards every record.
+func NopLogger() Logger {
+	return nopLogger{}
+}
+
+func (nopLogger) Debug(string, ...interface{}) {}
+func (nopLogger) Info(string, ...interface{})  {}
+func (nopLogger) Warn(string, ...interface{})  {}
+func (nopLogger) Error(string, ...interface{}) {}
+
+func defaultLogger(logger Logger) Logger {
+	if logger == nil {
+		return NewLogrusLogger(log.StandardLogger())
+	}
+	return logger
+}
+
+// log returns the logger of the client, the standard logrus logger unless WithLogger
+// set another one.
+func (client *RestClient) log() Logger {
+	return defaultLogger(client.logger)
+}
+
+func (options *clientOptions) log() Logger {
+	return defaultLogger(options.logger)
+}
+
+// loggedParams are the request fields that are neither credentials nor payloads.
+var loggedParams = map[string]bool{
+	"accessId": true, "bizid": true, "hash": true, "method": true, "orderId": true,
+	"account": true, "tenantid": true, "uid": true, "contractName": true,
+	"methodSignature": true, "mykmsKeyId": true, "blockNumber": true,
+	"isLocalTransaction": true, "gas": true, "vmTypeEnum": true, "newAccountId": true,
+	"newAccountKmsId": true,
+}
+
+// redacted returns the text logged for a value that must not be logged.
+func redacted(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return fmt.Sprintf("****** (%d bytes)", len(s))
+	}
+	return "******"
+}
+
+// logParam returns what is logged of a request, all of it when the client logs
+// unredacted and else only the fields in loggedParams.
+func (client *RestClient) logParam(param interface{}) interface{} {
+	if client.unredactedLogs {
+		// the param itself, not the address of it
+		return reflect.Indirect(reflect.ValueOf(param)).Interface()
+	}
+	data, err := json.Marshal(param)
+	if err != nil {
+		return redacted(nil)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return redacted(nil)
+	}
+	for key := range fields {
+		if !loggedParams[key] {
+			fields[key] = redacted(fields[key])
+		}
+	}
+	return fields
+}
+
+// logResp returns what is logged of a response, whose data may be a payload.
+func (client *RestClient) logResp(baseResp response.BaseResp) interface{} {
+	if client.unredactedLogs || baseResp.Data == "" {
+		return baseResp
+	}
+	return map[string]interface{}{"success": baseResp.Success, "code": baseResp.Code, "data": redacted(baseResp.Data)}
+}
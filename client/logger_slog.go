@@ -0,0 +1,14 @@
+//go:build go1.21
+// +build go1.21
+
+package client
+
+import "log/slog"
+
+// NewSlogLogger logs to logger, slog.Default() when nil.
+func NewSlogLogger(logger *slog.Logger) Logger {
+	if logger == nil {
+		return slog.Default()
+	}
+	return logger
+}
@@ -0,0 +1,19 @@
+//go:build go1.21
+// +build go1.21
+
+package client
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	NewSlogLogger(slog.New(slog.NewTextHandler(&buf, nil))).Info("new rest client", "accessId", "access")
+	require.Contains(t, buf.String(), "msg=\"new rest client\" accessId=access")
+	require.NotNil(t, NewSlogLogger(nil))
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/oldercn/restclient-go-sdk/client/config"
+	"github.com/oldercn/restclient-go-sdk/response"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingLogger keeps every record as a line of text.
+type recordingLogger struct {
+	lock  sync.Mutex
+	lines []string
+}
+
+func (l *recordingLogger) record(level, msg string, keysAndValues []interface{}) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf("%s %s %v", level, msg, keysAndValues))
+}
+
+func (l *recordingLogger) Debug(msg string, keysAndValues ...interface{}) {
+	l.record("debug", msg, keysAndValues)
+}
+
+func (l *recordingLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.record("info", msg, keysAndValues)
+}
+
+func (l *recordingLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.record("warn", msg, keysAndValues)
+}
+
+func (l *recordingLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.record("error", msg, keysAndValues)
+}
+
+func (l *recordingLogger) String() string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return strings.Join(l.lines, "\n")
+}
+
+func TestLoggerRedaction(t *testing.T) {
+	client, closeServer := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		writeResp(w, response.BaseResp{Success: true, Code: "200", Data: "private output"})
+	})
+	defer closeServer()
+	logger := &recordingLogger{}
+	client.logger = logger
+	client.RestToken = "private token"
+
+	_, err := client.Deposit("biz", "order", "account", "tenant", "private content", "kms", 100)
+	require.NoError(t, err)
+	logs := logger.String()
+	require.Contains(t, logs, "request and resp")
+	require.Contains(t, logs, "order")
+	require.NotContains(t, logs, "private content")
+	require.NotContains(t, logs, "private output")
+	require.NotContains(t, logs, "private token")
+
+	client.unredactedLogs = true
+	_, err = client.Deposit("biz", "order-2", "account", "tenant", "private content", "kms", 100)
+	require.NoError(t, err)
+	require.Contains(t, logger.String(), "private content")
+}
+
+func TestLoggerHandshake(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restclient-logger")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	server := newHandshakeServer()
+	defer server.Close()
+
+	logger := &recordingLogger{}
+	client, err := NewRestClientFromProperties(config.RestClientProperties{RestUrl: server.URL, AccessId: "access", AccessSecret: writeTestKey(t, dir)}, WithLogger(logger))
+	require.NoError(t, err)
+	require.Equal(t, "token-1", client.Token())
+	logs := logger.String()
+	require.Contains(t, logs, "new rest token")
+	require.NotContains(t, logs, "token-1")
+	require.NotContains(t, logs, dir, "the key file is redacted")
+
+	// creating a client leaves the global logrus setup alone
+	require.IsType(t, &log.TextFormatter{}, log.StandardLogger().Formatter)
+}
+
+// zapRecorder has the methods of a *zap.SugaredLogger NewZapLogger uses.
+type zapRecorder struct {
+	recordingLogger
+}
+
+func (z *zapRecorder) Debugw(msg string, keysAndValues ...interface{}) {
+	z.Debug(msg, keysAndValues...)
+}
+func (z *zapRecorder) Infow(msg string, keysAndValues ...interface{}) { z.Info(msg, keysAndValues...) }
+func (z *zapRecorder) Warnw(msg string, keysAndValues ...interface{}) { z.Warn(msg, keysAndValues...) }
+func (z *zapRecorder) Errorw(msg string, keysAndValues ...interface{}) {
+	z.Error(msg, keysAndValues...)
+}
+
+func TestLoggerAdapters(t *testing.T) {
+	var buf bytes.Buffer
+	logrusLogger := log.New()
+	logrusLogger.SetOutput(&buf)
+	logrusLogger.SetFormatter(&log.JSONFormatter{})
+	NewLogrusLogger(logrusLogger).Warn("fail to get chainCall successfully", "restCode", "500", "dangling")
+	require.Contains(t, buf.String(), `"restCode":"500"`)
+	require.Contains(t, buf.String(), `"level":"warning"`)
+	require.Contains(t, buf.String(), `"!BADKEY":"dangling"`)
+
+	zap := &zapRecorder{}
+	NewZapLogger(zap).Error("fail to sign secret", "err", "boom")
+	require.Equal(t, "error fail to sign secret [err boom]", zap.String())
+
+	NopLogger().Info("dropped")
+}
@@ -0,0 +1,190 @@
+package client
+
+import (
+	"sync"
+	"time"
+
+	"github.com/oldercn/restclient-go-sdk/model"
+)
+
+// RetryClass tells retryableSendRequest which failures of a method may be retried.
+type RetryClass int
+
+const (
+	// RetryAll retries transport errors, expired tokens and 5xx result codes. The
+	// default, also of writes: a write carrying an orderId whose failed attempt may have
+	// reached the server is looked up first, and not sent again when it sent its
+	// transaction, see WithOrderLookup.
+	RetryAll RetryClass = iota
+	// RetryTokenOnly only retries requests the server did not execute: after refreshing
+	// an expired token, or when no connection could be made. For methods a repeated
+	// request of which has another effect, like APPLYKEY.
+	RetryTokenOnly
+	// RetryNever sends the request once.
+	RetryNever
+)
+
+// MethodClass groups the methods sharing a circuit breaker on an endpoint.
+type MethodClass string
+
+const (
+	ClassRead   MethodClass = "read"
+	ClassWrite  MethodClass = "write"
+	ClassDeploy MethodClass = "deploy"
+)
+
+var (
+	DefaultReadTimeout   = 10 * time.Second
+	DefaultWriteTimeout  = 30 * time.Second
+	DefaultDeployTimeout = 60 * time.Second
+)
+
+// MethodInfo describes how the client sends a model.Method.
+type MethodInfo struct {
+	Method model.Method
+	// Path is the endpoint the method is posted to, ChainCallForBizPath by default.
+	Path string
+	// UnsignedChainCall methods are sent through ChainCall instead when the call
+	// carries neither a KMS key nor a uid.
+	UnsignedChainCall bool
+	// ReadOnly methods do not change the chain.
+	ReadOnly bool
+	// Idempotent methods have the same effect however often they are sent.
+	Idempotent bool
+	// NeedsSigner methods must carry a KMS key id or a uid.
+	NeedsSigner bool
+	// ProducesTxHash methods return the hash of the transaction they sent in Data.
+	ProducesTxHash bool
+	// Timeout bounds a single attempt, zero means no limit.
+	Timeout    time.Duration
+	RetryClass RetryClass
+	// Class selects the circuit breaker of the method, ClassRead or ClassWrite by
+	// ReadOnly when empty.
+	Class MethodClass
+}
+
+// class returns the Class of info or its default.
+func (info MethodInfo) class() MethodClass {
+	switch {
+	case info.Class != "":
+		return info.Class
+	case info.ReadOnly:
+		return ClassRead
+	default:
+		return ClassWrite
+	}
+}
+
+func readMethod(method model.Method) MethodInfo {
+	return MethodInfo{Method: method, Path: ChainCallForBizPath, ReadOnly: true, Idempotent: true, Timeout: DefaultReadTimeout, Class: ClassRead}
+}
+
+func writeMethod(method model.Method) MethodInfo {
+	return MethodInfo{Method: method, Path: ChainCallForBizPath, NeedsSigner: true, ProducesTxHash: true, Timeout: DefaultWriteTimeout, Class: ClassWrite}
+}
+
+func deployMethod(method model.Method) MethodInfo {
+	info := writeMethod(method)
+	info.Timeout, info.Class = DefaultDeployTimeout, ClassDeploy
+	return info
+}
+
+var (
+	methodRegistryLock sync.RWMutex
+	methodRegistry     = make(map[model.Method]MethodInfo)
+)
+
+func init() {
+	for _, method := range []model.Method{
+		model.QUERYRECEIPT, model.QUERYTRANSACTION, model.QUERYRECEIPTBIZ, model.QUERYTRANSACTIONBIZ,
+		model.QUERYACCESSLIST, model.QUERYTENANTKMSLIST,
+	} {
+		RegisterMethod(readMethod(method))
+	}
+	for _, method := range []model.Method{
+		model.QUERYTRANSACTIONFROMBLOCKCHAIN, model.QUERYBLOCK, model.QUERYBLOCKBODY, model.QUERYLASTBLOCK,
+		model.QUERYBLOCKHEADERINFOSRAW, model.GETMYTFINFO, model.GETTAPPINFO, model.GETRESOURCEMAP,
+		model.GETEVENTTOPICBLOCKNUM, model.PARSEOUTPUT, model.SIGNHASH,
+	} {
+		info := readMethod(method)
+		info.NeedsSigner = true
+		RegisterMethod(info)
+	}
+	queryAccount := readMethod(model.QUERYACCOUNT)
+	queryAccount.UnsignedChainCall = true
+	RegisterMethod(queryAccount)
+
+	for _, method := range []model.Method{
+		model.DEPOSIT, model.DEPOSITTEST, model.CALLCONTRACTBIZASYNC, model.CALLWASMCONTRACTASYNC,
+		model.CALLNATIVECONTRACTASYNC, model.CALLNATIVECONTRACTFORBIZASYNC, model.TENANTCREATEACCOUNT,
+		model.FREEZEACCOUNTASYN, model.UNFREEZEACCOUNTASYN,
+	} {
+		RegisterMethod(writeMethod(method))
+	}
+	depositWithAdmin := writeMethod(model.DEPOSITWITHADMIN)
+	depositWithAdmin.NeedsSigner = false
+	RegisterMethod(depositWithAdmin)
+	// synchronous calls return the outputs instead of the hash
+	for _, method := range []model.Method{
+		model.CALLCONTRACT, model.CALLCONTRACTBIZ, model.CALLWASMCONTRACT, model.CALLNATIVECONTRACT,
+		model.CALLNATIVECONTRACTFORBIZ, model.EXECUTETAPP, model.EXECUTETAPPPRIVATE,
+	} {
+		info := writeMethod(method)
+		info.ProducesTxHash = false
+		RegisterMethod(info)
+	}
+	for _, method := range []model.Method{
+		model.DEPLOYCONTRACT, model.DEPLOYCONTRACTFORBIZ, model.DEPLOYWASMCONTRACT, model.UPDATECONTRACT,
+		model.UPDATECONTRACTFORBIZ, model.INSTALLTAPP,
+	} {
+		RegisterMethod(deployMethod(method))
+	}
+	for _, method := range []model.Method{model.DEPLOYNATIVECONTRACT, model.CREATEACCOUNT} {
+		info := deployMethod(method)
+		info.UnsignedChainCall = true
+		RegisterMethod(info)
+	}
+	for _, method := range []model.Method{
+		model.UPDATERESOURCEMAP, model.SETRESOURCEMAP, model.UPDATEEVENTTOPICBLOCKNUM, model.INVITEUSER,
+		model.NEWCHAIN, model.REGISTERBLOCKCHAINCONFIG,
+	} {
+		info := writeMethod(method)
+		info.ProducesTxHash = false
+		RegisterMethod(info)
+	}
+	for _, method := range []model.Method{model.FROZENTENANT, model.UNFROZENTENANT} {
+		info := writeMethod(method)
+		info.NeedsSigner, info.ProducesTxHash, info.Idempotent = false, false, true
+		RegisterMethod(info)
+	}
+	// a repeated key request issues another key
+	for _, method := range []model.Method{model.APPLYKEY, model.RESETAPPLYKEY} {
+		info := writeMethod(method)
+		info.NeedsSigner, info.ProducesTxHash, info.RetryClass = false, false, RetryTokenOnly
+		RegisterMethod(info)
+	}
+}
+
+// RegisterMethod adds or replaces the description of info.Method. An empty Path
+// defaults to ChainCallForBizPath.
+func RegisterMethod(info MethodInfo) {
+	if info.Path == "" {
+		info.Path = ChainCallForBizPath
+	}
+	methodRegistryLock.Lock()
+	defer methodRegistryLock.Unlock()
+	methodRegistry[info.Method] = info
+}
+
+// LookupMethod returns the description of method. Unknown methods are treated as
+// signed writes posted to ChainCallForBizPath and reported with ok false.
+func LookupMethod(method model.Method) (info MethodInfo, ok bool) {
+	methodRegistryLock.RLock()
+	info, ok = methodRegistry[method]
+	methodRegistryLock.RUnlock()
+	if !ok {
+		info = writeMethod(method)
+		info.ProducesTxHash = false
+	}
+	return info, ok
+}
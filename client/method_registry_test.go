@@ -0,0 +1,99 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/oldercn/restclient-go-sdk/client/config"
+	"github.com/oldercn/restclient-go-sdk/model"
+	"github.com/oldercn/restclient-go-sdk/response"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestClient returns a client talking to handler without a handshake, and the
+// function closing its server.
+func newTestClient(handler http.HandlerFunc) (*RestClient, func()) {
+	server := httptest.NewServer(handler)
+	return &RestClient{
+		RestClientProperties: config.RestClientProperties{
+			RestUrl:          server.URL,
+			AccessId:         "access",
+			RetryMaxAttempts: 3,
+			BackOffPeriod:    1,
+		},
+		RestToken:  "token",
+		httpClient: server.Client(),
+	}, server.Close
+}
+
+func writeResp(w http.ResponseWriter, baseResp response.BaseResp) {
+	_ = json.NewEncoder(w).Encode(baseResp)
+}
+
+func TestMethodRegistryRouting(t *testing.T) {
+	var paths []string
+	client, closeServer := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		writeResp(w, response.BaseResp{Success: true, Code: "200"})
+	})
+	defer closeServer()
+	param := model.CallRestBizParam{
+		BaseParam: model.BaseParam{AccessId: "access", BizId: "biz", Method: model.QUERYACCOUNT},
+		OrderId:   "order",
+	}
+	_, err := client.ChainCallForBiz(param)
+	require.NoError(t, err)
+	param.MykmsKeyId = "kms"
+	_, err = client.ChainCallForBiz(param)
+	require.NoError(t, err)
+	require.Equal(t, []string{ChainCallPath, ChainCallForBizPath}, paths)
+
+	info, ok := LookupMethod(model.CALLCONTRACTBIZASYNC)
+	require.True(t, ok)
+	require.True(t, info.ProducesTxHash && info.NeedsSigner && !info.ReadOnly)
+	info, ok = LookupMethod(model.QUERYRECEIPT)
+	require.True(t, ok)
+	require.True(t, info.ReadOnly && info.Idempotent)
+	_, ok = LookupMethod("CUSTOM")
+	require.False(t, ok)
+}
+
+func TestMethodRegistryRetryClass(t *testing.T) {
+	var requests int32
+	client, closeServer := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		writeResp(w, response.BaseResp{Success: false, Code: "500"})
+	})
+	defer closeServer()
+	param := model.CallRestBizParam{
+		BaseParam: model.BaseParam{AccessId: "access", BizId: "biz", Method: "CUSTOMRETRY"},
+		OrderId:   "order",
+		Uid:       "uid",
+	}
+	// unknown methods are writes, which are retried in full
+	_, err := client.ChainCallForBiz(param)
+	require.Error(t, err)
+	require.Equal(t, int32(3), atomic.LoadInt32(&requests))
+
+	RegisterMethod(MethodInfo{Method: "CUSTOMRETRY", RetryClass: RetryTokenOnly})
+	defer func() {
+		methodRegistryLock.Lock()
+		delete(methodRegistry, "CUSTOMRETRY")
+		methodRegistryLock.Unlock()
+	}()
+	atomic.StoreInt32(&requests, 0)
+	baseResp, err := client.ChainCallForBiz(param)
+	require.NoError(t, err)
+	require.Equal(t, "500", baseResp.Code)
+	require.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	info, _ := LookupMethod(model.DEPOSIT)
+	require.Equal(t, RetryAll, info.RetryClass)
+	info, _ = LookupMethod(model.APPLYKEY)
+	require.Equal(t, RetryTokenOnly, info.RetryClass)
+	info, _ = LookupMethod(model.QUERYRECEIPT)
+	require.Equal(t, RetryAll, info.RetryClass)
+}
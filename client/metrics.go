@@ -0,0 +1,76 @@
+package client
+
+import (
+	"time"
+
+	"github.com/oldercn/restclient-go-sdk/model"
+)
+
+// Codes RequestDone reports for attempts without a BaaS result code.
+const (
+	// CodeTransportError is reported when no http response arrived.
+	CodeTransportError = "transport_error"
+	// CodeInvalidResponse is reported when the response body is not a BaseResp.
+	CodeInvalidResponse = "invalid_response"
+	// CodeCircuitOpen is reported when the circuit breakers let no attempt through.
+	CodeCircuitOpen = "circuit_open"
+	// CodeRateLimited is reported when a RateLimit let no attempt through.
+	CodeRateLimited = "rate_limited"
+)
+
+// Final states of a receipt or transaction poller, see Metrics.ReceiptWaited.
+const (
+	ReceiptSuccess = "success"
+	// ReceiptFailed is a final answer other than success.
+	ReceiptFailed = "failed"
+	// ReceiptPending means the poller gave up while the transaction was still pending.
+	ReceiptPending = "pending"
+	// ReceiptError means a request of the poller failed.
+	ReceiptError = "error"
+)
+
+// Metrics receives the measurements of a RestClient. The methods are called on the
+// request path and must be quick and safe for concurrent use. Collector implements
+// it for Prometheus and expvar.
+type Metrics interface {
+	// RequestDone is called after every attempt of a request posted to endpoint, the
+	// path of the rest api. code is the BaaS result code, http_<status> for non 2xx
+	// answers, CodeTransportError, CodeInvalidResponse, CodeCircuitOpen or
+	// CodeRateLimited.
+	RequestDone(method model.Method, endpoint, code string, duration time.Duration)
+	// Retry is called before another attempt, reason is the code of the failed one.
+	Retry(method model.Method, endpoint, reason string)
+	// Handshake is called after every handshake, err is nil for a new token.
+	Handshake(err error, duration time.Duration)
+	// ReceiptWaited is called when MultipleQueryReceipt or MultipleQueryTransaction
+	// return, with the time spent polling and one of the Receipt states.
+	ReceiptWaited(method model.Method, state string, duration time.Duration)
+	// InFlight changes the number of requests of method in flight by delta.
+	InFlight(method model.Method, delta int)
+	// CircuitChanged is called when the circuit breaker of class on the endpoint url
+	// moves to state.
+	CircuitChanged(url string, class MethodClass, state CircuitState)
+}
+
+// WithMetrics makes the client report to metrics.
+func WithMetrics(metrics Metrics) Option {
+	return func(options *clientOptions) {
+		options.metrics = metrics
+	}
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) RequestDone(model.Method, string, string, time.Duration) {}
+func (nopMetrics) Retry(model.Method, string, string)                      {}
+func (nopMetrics) Handshake(error, time.Duration)                          {}
+func (nopMetrics) ReceiptWaited(model.Method, string, time.Duration)       {}
+func (nopMetrics) InFlight(model.Method, int)                              {}
+func (nopMetrics) CircuitChanged(string, MethodClass, CircuitState)        {}
+
+func (client *RestClient) metrics() Metrics {
+	if client.metricsSink == nil {
+		return nopMetrics{}
+	}
+	return client.metricsSink
+}
@@ -0,0 +1,307 @@
+package client
+
+import (
+	"bufio"
+	"expvar"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/oldercn/restclient-go-sdk/model"
+)
+
+// DefaultBuckets are the upper bounds in seconds of the latency histograms.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// Collector is a Metrics keeping every measurement in memory. It serves them in the
+// Prometheus text format, or OpenMetrics when asked for, and publishes them to expvar,
+// so that no metrics service or client library is needed.
+type Collector struct {
+	lock    sync.Mutex
+	buckets []float64
+
+	requests          map[string]float64 // by method, endpoint, code
+	requestDurations  map[string]*histogram
+	retries           map[string]float64 // by method, endpoint, reason
+	handshakes        map[string]float64 // by result
+	handshakeDuration *histogram
+	receiptWaits      map[string]*histogram // by method, state
+	inFlight          map[string]float64    // by method
+	circuits          map[string]float64    // by url, class, state, 1 for the current state
+	circuitChanges    map[string]float64    // by url, class, state
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, not cumulative
+	sum    float64
+	count  uint64
+}
+
+// NewCollector returns an empty Collector whose histograms use buckets, DefaultBuckets
+// when none are given.
+func NewCollector(buckets ...float64) *Collector {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &Collector{
+		buckets:           buckets,
+		requests:          make(map[string]float64),
+		requestDurations:  make(map[string]*histogram),
+		retries:           make(map[string]float64),
+		handshakes:        make(map[string]float64),
+		handshakeDuration: &histogram{counts: make([]uint64, len(buckets))},
+		receiptWaits:      make(map[string]*histogram),
+		inFlight:          make(map[string]float64),
+		circuits:          make(map[string]float64),
+		circuitChanges:    make(map[string]float64),
+	}
+}
+
+// labels joins label pairs into a map key, which is also their text form.
+func labels(pairs ...string) string {
+	var b strings.Builder
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(pairs[i])
+		b.WriteString(`="`)
+		b.WriteString(escapeLabel(pairs[i+1]))
+		b.WriteByte('"')
+	}
+	return b.String()
+}
+
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func (c *Collector) observe(histograms map[string]*histogram, key string, duration time.Duration) {
+	h, ok := histograms[key]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(c.buckets))}
+		histograms[key] = h
+	}
+	c.observeHistogram(h, duration)
+}
+
+func (c *Collector) observeHistogram(h *histogram, duration time.Duration) {
+	seconds := duration.Seconds()
+	if i := sort.SearchFloat64s(c.buckets, seconds); i < len(c.buckets) {
+		h.counts[i]++
+	}
+	h.sum += seconds
+	h.count++
+}
+
+func (c *Collector) RequestDone(method model.Method, endpoint, code string, duration time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.requests[labels("method", string(method), "endpoint", endpoint, "code", code)]++
+	c.observe(c.requestDurations, labels("method", string(method), "endpoint", endpoint), duration)
+}
+
+func (c *Collector) Retry(method model.Method, endpoint, reason string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.retries[labels("method", string(method), "endpoint", endpoint, "reason", reason)]++
+}
+
+func (c *Collector) Handshake(err error, duration time.Duration) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.handshakes[labels("result", result)]++
+	c.observeHistogram(c.handshakeDuration, duration)
+}
+
+func (c *Collector) ReceiptWaited(method model.Method, state string, duration time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.observe(c.receiptWaits, labels("method", string(method), "state", state), duration)
+}
+
+func (c *Collector) InFlight(method model.Method, delta int) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.inFlight[labels("method", string(method))] += float64(delta)
+}
+
+func (c *Collector) CircuitChanged(url string, class MethodClass, state CircuitState) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, s := range []CircuitState{CircuitClosed, CircuitOpen, CircuitHalfOpen} {
+		value := 0.0
+		if s == state {
+			value = 1
+		}
+		c.circuits[labels("url", url, "class", string(class), "state", string(s))] = value
+	}
+	c.circuitChanges[labels("url", url, "class", string(class), "state", string(state))]++
+}
+
+// family is a metric with all its label sets, as the exporters see it.
+type family struct {
+	name, help, typ string
+	values          map[string]float64
+	histograms      map[string]*histogram
+}
+
+// families copies the measurements, the caller holds the lock.
+func (c *Collector) families() []family {
+	copyValues := func(values map[string]float64) map[string]float64 {
+		copied := make(map[string]float64, len(values))
+		for key, value := range values {
+			copied[key] = value
+		}
+		return copied
+	}
+	copyHistograms := func(histograms map[string]*histogram) map[string]*histogram {
+		copied := make(map[string]*histogram, len(histograms))
+		for key, h := range histograms {
+			counts := append([]uint64(nil), h.counts...)
+			copied[key] = &histogram{counts: counts, sum: h.sum, count: h.count}
+		}
+		return copied
+	}
+	return []family{
+		{name: "restclient_requests", help: "Attempts of requests to the rest server by result code.", typ: "counter", values: copyValues(c.requests)},
+		{name: "restclient_request_duration_seconds", help: "Duration of an attempt of a request.", typ: "histogram", histograms: copyHistograms(c.requestDurations)},
+		{name: "restclient_retries", help: "Attempts repeated, by the code of the failed one.", typ: "counter", values: copyValues(c.retries)},
+		{name: "restclient_handshakes", help: "Handshakes refreshing the rest token.", typ: "counter", values: copyValues(c.handshakes)},
+		{name: "restclient_handshake_duration_seconds", help: "Duration of a handshake.", typ: "histogram", histograms: copyHistograms(map[string]*histogram{"": c.handshakeDuration})},
+		{name: "restclient_receipt_wait_seconds", help: "Time spent polling for a receipt or transaction by final state.", typ: "histogram", histograms: copyHistograms(c.receiptWaits)},
+		{name: "restclient_in_flight_requests", help: "Requests waiting for the rest server.", typ: "gauge", values: copyValues(c.inFlight)},
+		{name: "restclient_circuit_state", help: "State of a circuit breaker, 1 for the current one.", typ: "gauge", values: copyValues(c.circuits)},
+		{name: "restclient_circuit_changes", help: "Circuit breakers moving to a state.", typ: "counter", values: copyValues(c.circuitChanges)},
+	}
+}
+
+func (c *Collector) snapshot() []family {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.families()
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case map[string]float64:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	case map[string]*histogram:
+		for key := range m {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func sample(name, labelText string) string {
+	if labelText == "" {
+		return name
+	}
+	return name + "{" + labelText + "}"
+}
+
+func withLabel(labelText, pair string) string {
+	if labelText == "" {
+		return pair
+	}
+	return labelText + "," + pair
+}
+
+// WriteText writes the metrics in the Prometheus text format 0.0.4.
+func (c *Collector) WriteText(w io.Writer) error {
+	return c.write(w, false)
+}
+
+// WriteOpenMetrics writes the metrics in the OpenMetrics text format 1.0.0.
+func (c *Collector) WriteOpenMetrics(w io.Writer) error {
+	return c.write(w, true)
+}
+
+func (c *Collector) write(w io.Writer, openMetrics bool) error {
+	buf := bufio.NewWriter(w)
+	for _, f := range c.snapshot() {
+		// OpenMetrics names the counter family without the _total of its samples
+		name := f.name
+		if f.typ == "counter" && !openMetrics {
+			name += "_total"
+		}
+		fmt.Fprintf(buf, "# HELP %s %s\n# TYPE %s %s\n", name, f.help, name, f.typ)
+		for _, key := range sortedKeys(f.values) {
+			sampleName := f.name
+			if f.typ == "counter" {
+				sampleName += "_total"
+			}
+			fmt.Fprintf(buf, "%s %s\n", sample(sampleName, key), formatFloat(f.values[key]))
+		}
+		for _, key := range sortedKeys(f.histograms) {
+			h := f.histograms[key]
+			var cumulative uint64
+			for i, upper := range c.buckets {
+				cumulative += h.counts[i]
+				fmt.Fprintf(buf, "%s %d\n", sample(f.name+"_bucket", withLabel(key, `le="`+formatFloat(upper)+`"`)), cumulative)
+			}
+			fmt.Fprintf(buf, "%s %d\n", sample(f.name+"_bucket", withLabel(key, `le="+Inf"`)), h.count)
+			fmt.Fprintf(buf, "%s %s\n", sample(f.name+"_sum", key), formatFloat(h.sum))
+			fmt.Fprintf(buf, "%s %d\n", sample(f.name+"_count", key), h.count)
+		}
+	}
+	if openMetrics {
+		buf.WriteString("# EOF\n")
+	}
+	return buf.Flush()
+}
+
+// ServeHTTP serves the metrics to a Prometheus scraper, in OpenMetrics when the
+// scraper accepts it.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.Contains(r.Header.Get("Accept"), "application/openmetrics-text") {
+		w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+		_ = c.WriteOpenMetrics(w)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = c.WriteText(w)
+}
+
+// Snapshot returns the metrics as a json friendly map from sample name and labels to
+// value, histograms by their count and sum.
+func (c *Collector) Snapshot() map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, f := range c.snapshot() {
+		for key, value := range f.values {
+			values[sample(f.name, key)] = value
+		}
+		for key, h := range f.histograms {
+			values[sample(f.name, key)] = map[string]interface{}{"count": h.count, "sum": h.sum}
+		}
+	}
+	return values
+}
+
+// PublishExpvar publishes the Snapshot under name in expvar, which serves it at
+// /debug/vars. Like expvar.Publish it panics when name is taken.
+func (c *Collector) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return c.Snapshot()
+	}))
+}
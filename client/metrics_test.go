@@ -0,0 +1,94 @@
+package client
+
+import (
+	"bytes"
+	"expvar"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+
+	"github.com/oldercn/restclient-go-sdk/client/config"
+	"github.com/oldercn/restclient-go-sdk/model"
+	"github.com/oldercn/restclient-go-sdk/response"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsRequests(t *testing.T) {
+	var calls int32
+	client, closeServer := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			writeResp(w, response.BaseResp{Success: false, Code: "500"})
+		case 3:
+			writeResp(w, response.BaseResp{Success: false, Code: model.ServiceTxWaitingVerify})
+		default:
+			writeResp(w, response.BaseResp{Success: true, Code: "200"})
+		}
+	})
+	defer closeServer()
+	collector := NewCollector()
+	client.metricsSink = collector
+
+	_, err := client.QueryReceipt("biz", "abcd")
+	require.NoError(t, err)
+	_, err = client.MultipleQueryReceipt("biz", "abcd")
+	require.NoError(t, err)
+
+	var text bytes.Buffer
+	require.NoError(t, collector.WriteText(&text))
+	for _, line := range []string{
+		"# TYPE restclient_requests_total counter",
+		`restclient_requests_total{method="QUERYRECEIPT",endpoint="/api/contract/chainCallForBiz",code="500"} 1`,
+		`restclient_requests_total{method="QUERYRECEIPT",endpoint="/api/contract/chainCallForBiz",code="200"} 1`,
+		`restclient_requests_total{method="QUERYRECEIPT",endpoint="/api/contract/chainCall",code="413"} 1`,
+		`restclient_retries_total{method="QUERYRECEIPT",endpoint="/api/contract/chainCallForBiz",reason="500"} 1`,
+		`restclient_request_duration_seconds_count{method="QUERYRECEIPT",endpoint="/api/contract/chainCallForBiz"} 2`,
+		`restclient_request_duration_seconds_bucket{method="QUERYRECEIPT",endpoint="/api/contract/chainCallForBiz",le="+Inf"} 2`,
+		`restclient_receipt_wait_seconds_count{method="QUERYRECEIPT",state="success"} 1`,
+		`restclient_in_flight_requests{method="QUERYRECEIPT"} 0`,
+	} {
+		require.Contains(t, text.String(), line+"\n")
+	}
+
+	// a scraper asking for OpenMetrics gets it
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	request.Header.Set("Accept", "application/openmetrics-text; version=1.0.0")
+	collector.ServeHTTP(recorder, request)
+	require.Contains(t, recorder.Header().Get("Content-Type"), "application/openmetrics-text")
+	require.Contains(t, recorder.Body.String(), "# TYPE restclient_requests counter\n")
+	require.Contains(t, recorder.Body.String(), "# EOF\n")
+
+	collector.PublishExpvar("restclient_test")
+	require.Contains(t, expvar.Get("restclient_test").String(), `"restclient_requests{method=\"QUERYRECEIPT\",endpoint=\"/api/contract/chainCallForBiz\",code=\"200\"}":1`)
+}
+
+func TestMetricsTransportError(t *testing.T) {
+	client, closeServer := newTestClient(func(w http.ResponseWriter, r *http.Request) {})
+	closeServer()
+	collector := NewCollector()
+	client.metricsSink = collector
+
+	_, err := client.QueryReceipt("biz", "abcd")
+	require.Error(t, err)
+	snapshot := collector.Snapshot()
+	require.Equal(t, float64(3), snapshot[`restclient_requests{method="QUERYRECEIPT",endpoint="/api/contract/chainCallForBiz",code="transport_error"}`])
+	require.Equal(t, float64(2), snapshot[`restclient_retries{method="QUERYRECEIPT",endpoint="/api/contract/chainCallForBiz",reason="transport_error"}`])
+}
+
+func TestMetricsHandshake(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restclient-metrics")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	server := newHandshakeServer()
+	defer server.Close()
+
+	collector := NewCollector()
+	_, err = NewRestClientFromProperties(config.RestClientProperties{RestUrl: server.URL, AccessId: "access", AccessSecret: writeTestKey(t, dir)}, WithMetrics(collector))
+	require.NoError(t, err)
+	require.Equal(t, float64(1), collector.Snapshot()[`restclient_handshakes{result="success"}`])
+	require.Equal(t, map[string]interface{}{"count": uint64(1), "sum": collector.handshakeDuration.sum}, collector.Snapshot()["restclient_handshake_duration_seconds"])
+}
@@ -0,0 +1,158 @@
+package client
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/oldercn/restclient-go-sdk/model"
+	"github.com/oldercn/restclient-go-sdk/utils"
+)
+
+// DefaultOrderIdPrefix prefixes the orderIds of a client without WithOrderIDGenerator.
+const DefaultOrderIdPrefix = "order_"
+
+// OrderIDGenerator makes the orderIds of the calls sent without one. It must be safe
+// for concurrent use and return ids of at most 128 bytes that never repeat.
+type OrderIDGenerator interface {
+	NewOrderID() string
+}
+
+// OrderIDGeneratorFunc is a function serving as an OrderIDGenerator.
+type OrderIDGeneratorFunc func() string
+
+func (f OrderIDGeneratorFunc) NewOrderID() string {
+	return f()
+}
+
+// WithOrderIDGenerator makes the client fill missing orderIds with generator, by
+// default they are UUIDv4 prefixed with DefaultOrderIdPrefix.
+func WithOrderIDGenerator(generator OrderIDGenerator) Option {
+	return func(options *clientOptions) {
+		options.orderIDGenerator = generator
+	}
+}
+
+// UUIDv4Generator returns random UUIDs.
+func UUIDv4Generator() OrderIDGenerator {
+	return OrderIDGeneratorFunc(func() string {
+		return uuid.New().String()
+	})
+}
+
+// UUIDv7Generator returns UUIDv7, which sort by the time they were made. Ids made in
+// the same millisecond by one generator sort in the order they were made as well.
+func UUIDv7Generator() OrderIDGenerator {
+	return &uuidV7Generator{}
+}
+
+type uuidV7Generator struct {
+	lock sync.Mutex
+	last int64  // unix milliseconds of the last id
+	seq  uint16 // 12 bit counter within the millisecond
+}
+
+func (g *uuidV7Generator) NewOrderID() string {
+	var id [16]byte
+	if _, err := rand.Read(id[6:]); err != nil {
+		panic(fmt.Sprintf("fail to read random bytes,err:%v", err))
+	}
+	g.lock.Lock()
+	now := time.Now().UnixNano() / 1e6
+	if now > g.last {
+		// start the counter at a random value of its lower half, leaving room to count
+		g.last, g.seq = now, binary.BigEndian.Uint16(id[6:8])&0x7ff
+	} else if g.seq++; g.seq > 0xfff {
+		// the counter ran out, borrow the next millisecond
+		g.last, g.seq = g.last+1, 0
+	}
+	ms, seq := g.last, g.seq
+	g.lock.Unlock()
+
+	id[0], id[1], id[2] = byte(ms>>40), byte(ms>>32), byte(ms>>24)
+	id[3], id[4], id[5] = byte(ms>>16), byte(ms>>8), byte(ms)
+	id[6], id[7] = 0x70|byte(seq>>8), byte(seq)
+	id[8] = 0x80 | id[8]&0x3f
+	return formatUUID(id)
+}
+
+func formatUUID(id [16]byte) string {
+	s := hex.EncodeToString(id[:])
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
+}
+
+// SnowflakeEpoch is the time the milliseconds of snowflake ids count from.
+var SnowflakeEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// MaxSnowflakeNode is the largest node of SnowflakeGenerator.
+const MaxSnowflakeNode = 1<<10 - 1
+
+// SnowflakeGenerator returns decimal snowflake ids: 41 bits of milliseconds since
+// SnowflakeEpoch, 10 bits of node and a 12 bit sequence. Generators of different
+// nodes never make the same id, the ids of one node increase.
+func SnowflakeGenerator(node int) (OrderIDGenerator, error) {
+	if node < 0 || node > MaxSnowflakeNode {
+		return nil, fmt.Errorf("snowflake node %d is out of range [0, %d]", node, MaxSnowflakeNode)
+	}
+	return &snowflakeGenerator{node: int64(node)}, nil
+}
+
+type snowflakeGenerator struct {
+	lock sync.Mutex
+	node int64
+	last int64 // milliseconds since SnowflakeEpoch of the last id
+	seq  int64
+}
+
+func (g *snowflakeGenerator) NewOrderID() string {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	now := time.Since(SnowflakeEpoch).Nanoseconds() / 1e6
+	if now > g.last {
+		g.last, g.seq = now, 0
+	} else if g.seq++; g.seq > 0xfff {
+		// the sequence ran out, or the clock went back: go on from the last millisecond
+		g.last, g.seq = g.last+1, 0
+	}
+	return strconv.FormatInt(g.last<<22|g.node<<12|g.seq, 10)
+}
+
+// PrefixGenerator returns the ids of next prefixed with prefix, e.g. the name of a
+// service to keep the orderIds of services sharing a chain apart.
+func PrefixGenerator(prefix string, next OrderIDGenerator) OrderIDGenerator {
+	return OrderIDGeneratorFunc(func() string {
+		return prefix + next.NewOrderID()
+	})
+}
+
+var defaultOrderIDGenerator = PrefixGenerator(DefaultOrderIdPrefix, UUIDv4Generator())
+
+func (client *RestClient) orderIDs() OrderIDGenerator {
+	if client.orderIDGenerator == nil {
+		return defaultOrderIDGenerator
+	}
+	return client.orderIDGenerator
+}
+
+// orderIdOf returns orderId, or a new one when it is empty.
+func (client *RestClient) orderIdOf(orderId string) string {
+	if orderId == "" {
+		return client.orderIDs().NewOrderID()
+	}
+	return orderId
+}
+
+// needsOrderId tells whether the rule of method requires an orderId.
+func needsOrderId(method model.Method) bool {
+	for _, name := range utils.LookupParamRule(method).Required {
+		if name == "orderId" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/oldercn/restclient-go-sdk/model"
+	"github.com/oldercn/restclient-go-sdk/mychain/mychain-sdk-go/common/codec/contract/abi"
+	"github.com/oldercn/restclient-go-sdk/response"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderIDGenerators(t *testing.T) {
+	uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-([0-9a-f])[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	v4 := UUIDv4Generator().NewOrderID()
+	require.Equal(t, "4", uuidPattern.FindStringSubmatch(v4)[1])
+
+	// time ordered, also within a millisecond
+	v7 := UUIDv7Generator()
+	var ids []string
+	for i := 0; i < 5000; i++ {
+		id := v7.NewOrderID()
+		require.Equal(t, "7", uuidPattern.FindStringSubmatch(id)[1], id)
+		ids = append(ids, id)
+	}
+	require.True(t, sort.StringsAreSorted(ids))
+	requireUnique(t, ids)
+
+	_, err := SnowflakeGenerator(MaxSnowflakeNode + 1)
+	require.Error(t, err)
+	snowflake, err := SnowflakeGenerator(7)
+	require.NoError(t, err)
+	other, err := SnowflakeGenerator(8)
+	require.NoError(t, err)
+	var last int64
+	ids = nil
+	for i := 0; i < 5000; i++ {
+		id := snowflake.NewOrderID()
+		n, err := strconv.ParseInt(id, 10, 64)
+		require.NoError(t, err)
+		require.True(t, n > last)
+		require.Equal(t, int64(7), n>>12&MaxSnowflakeNode)
+		last = n
+		ids = append(ids, id, other.NewOrderID())
+	}
+	requireUnique(t, ids)
+
+	prefixed := PrefixGenerator("payments_", snowflake).NewOrderID()
+	require.True(t, strings.HasPrefix(prefixed, "payments_"))
+}
+
+func requireUnique(t *testing.T, ids []string) {
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		require.False(t, seen[id], id)
+		seen[id] = true
+	}
+}
+
+func TestOrderIdFilled(t *testing.T) {
+	var lock sync.Mutex
+	var sent []string
+	client, closeServer := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		var param model.CallRestBizParam
+		_ = json.NewDecoder(r.Body).Decode(&param)
+		lock.Lock()
+		sent = append(sent, param.OrderId)
+		lock.Unlock()
+		writeResp(w, response.BaseResp{Success: true, Code: "200", Data: "abcd"})
+	})
+	defer closeServer()
+
+	baseResp, err := deposit(client, "")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(baseResp.OrderId, DefaultOrderIdPrefix))
+	require.Equal(t, []string{baseResp.OrderId}, sent)
+
+	client.orderIDGenerator = OrderIDGeneratorFunc(func() string { return "generated" })
+	baseResp, err = client.CallContract("biz", "", "account", "tenant", "contract", "Foo()", "[]", "[]", "kms", false, 0)
+	require.NoError(t, err)
+	require.Equal(t, "generated", baseResp.OrderId)
+	// given ids are kept, queries need none
+	baseResp, err = deposit(client, "given")
+	require.NoError(t, err)
+	require.Equal(t, "given", baseResp.OrderId)
+	baseResp, err = client.QueryReceipt("biz", "abcd")
+	require.NoError(t, err)
+	require.Empty(t, baseResp.OrderId)
+	require.Equal(t, []string{sent[0], "generated", "given", ""}, sent)
+}
+
+func TestOrderIdOnError(t *testing.T) {
+	client, closeServer := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		var param model.CallRestBizParam
+		_ = json.NewDecoder(r.Body).Decode(&param)
+		switch param.Method {
+		case model.DEPOSIT:
+			writeResp(w, response.BaseResp{Success: false, Code: "400", Data: "bad deposit"})
+		case model.QUERYRECEIPT:
+			// the receipt never shows up
+			writeResp(w, response.BaseResp{Success: false, Code: model.ServiceQueryNoResult})
+		default:
+			writeResp(w, response.BaseResp{Success: true, Code: "200", Data: "abcd"})
+		}
+	})
+	defer closeServer()
+	generated := 0
+	client.orderIDGenerator = OrderIDGeneratorFunc(func() string {
+		generated++
+		return "generated-" + strconv.Itoa(generated)
+	})
+	contractABI, err := abi.JSON(strings.NewReader(`[{"type":"function","name":"Foo","inputs":[],"outputs":[{"name":"","type":"uint256"}]}]`))
+	require.NoError(t, err)
+
+	baseResp, err := client.CallSolcContractSyncWithReceipt(contractABI, "biz", "", "account", "tenant", "kms", "contract", "Foo()", "[]", `["uint256"]`, 0, new(struct{}))
+	require.Error(t, err)
+	require.Equal(t, "generated-1", baseResp.OrderId)
+	baseResp, _, err = client.CallContractDynamic(contractABI, "biz", "", "account", "tenant", "kms", "contract", "Foo()", "[]", `["uint256"]`, 0)
+	require.Error(t, err)
+	require.Equal(t, "generated-2", baseResp.OrderId)
+	baseResp, err = client.DepositSyncWithTransaction("biz", "", "account", "tenant", "content", "kms", 0)
+	require.Error(t, err)
+	require.Equal(t, "generated-3", baseResp.OrderId)
+}
@@ -0,0 +1,258 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/oldercn/restclient-go-sdk/client/config"
+	"github.com/oldercn/restclient-go-sdk/model"
+)
+
+// ErrRateLimited is what a *RateLimitError is, for errors.Is.
+var ErrRateLimited = errors.New("rate limited")
+
+// RateLimitError is returned without sending a request when a RateLimit of the
+// properties has no room for it and RateLimitWait is false.
+type RateLimitError struct {
+	Method model.Method
+	Limit  config.RateLimit
+	// InFlight is true when MaxInFlight requests were in flight, false when the
+	// Rate was exceeded.
+	InFlight bool
+}
+
+func (e *RateLimitError) Error() string {
+	if e.InFlight {
+		return fmt.Sprintf("rate limited %v,%v requests of bizid:%q tenantid:%q class:%q in flight", e.Method, e.Limit.MaxInFlight, e.Limit.BizId, e.Limit.TenantId, e.Limit.Class)
+	}
+	return fmt.Sprintf("rate limited %v,more than %v requests per second of bizid:%q tenantid:%q class:%q", e.Method, e.Limit.Rate, e.Limit.BizId, e.Limit.TenantId, e.Limit.Class)
+}
+
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// SetRateLimits replaces the RateLimits of the client at runtime, see Reload.
+// Requests waiting for or holding room in a limit that is kept go on counting.
+func (client *RestClient) SetRateLimits(limits []config.RateLimit) error {
+	properties := client.Properties()
+	properties.RateLimits = limits
+	return client.Reload(properties)
+}
+
+// limitScope is what a RateLimit matches.
+type limitScope struct {
+	bizId, tenantId, class string
+}
+
+func scopeOf(limit config.RateLimit) limitScope {
+	return limitScope{bizId: limit.BizId, tenantId: limit.TenantId, class: limit.Class}
+}
+
+func (scope limitScope) matches(bizId, tenantId string, class MethodClass) bool {
+	return (scope.bizId == "" || scope.bizId == bizId) &&
+		(scope.tenantId == "" || scope.tenantId == tenantId) &&
+		(scope.class == "" || scope.class == string(class))
+}
+
+// limiter is a token bucket and a count of requests in flight.
+type limiter struct {
+	lock     sync.Mutex
+	limit    config.RateLimit
+	burst    float64
+	tokens   float64
+	last     time.Time
+	inFlight int
+	released chan struct{} // closed when room is made
+}
+
+func newLimiter(limit config.RateLimit) *limiter {
+	l := &limiter{released: make(chan struct{}), last: time.Now()}
+	l.setLimit(limit)
+	l.tokens = l.burst
+	return l
+}
+
+// setLimit changes the limits, the caller holds the lock or owns l.
+func (l *limiter) setLimit(limit config.RateLimit) {
+	l.limit = limit
+	l.burst = float64(limit.Burst)
+	if limit.Burst == 0 {
+		l.burst = math.Max(1, math.Ceil(limit.Rate))
+	}
+	l.tokens = math.Min(l.tokens, l.burst)
+}
+
+func (l *limiter) update(limit config.RateLimit) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.refill(time.Now())
+	l.setLimit(limit)
+	l.wake()
+}
+
+func (l *limiter) refill(now time.Time) {
+	if l.limit.Rate > 0 {
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.limit.Rate)
+	}
+	l.last = now
+}
+
+// wake lets the requests waiting for room try again, the caller holds the lock.
+func (l *limiter) wake() {
+	close(l.released)
+	l.released = make(chan struct{})
+}
+
+// acquire takes a token and room for a request in flight, waiting for them when wait
+// is true.
+func (l *limiter) acquire(ctx context.Context, method model.Method, wait bool) error {
+	for {
+		l.lock.Lock()
+		l.refill(time.Now())
+		var delay time.Duration
+		inFlight := l.limit.MaxInFlight > 0 && l.inFlight >= l.limit.MaxInFlight
+		if !inFlight && l.limit.Rate > 0 && l.tokens < 1 {
+			delay = time.Duration((1 - l.tokens) / l.limit.Rate * float64(time.Second))
+		}
+		if !inFlight && delay == 0 {
+			if l.limit.Rate > 0 {
+				l.tokens--
+			}
+			l.inFlight++
+			l.lock.Unlock()
+			return nil
+		}
+		limit, released := l.limit, l.released
+		l.lock.Unlock()
+		if !wait {
+			return &RateLimitError{Method: method, Limit: limit, InFlight: inFlight}
+		}
+		if err := waitForRoom(ctx, released, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// waitForRoom waits until released is closed or, when there is a delay, it is over.
+func waitForRoom(ctx context.Context, released chan struct{}, delay time.Duration) error {
+	var timeout <-chan time.Time
+	if delay > 0 {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-released:
+		return nil
+	case <-timeout:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// release frees the room of a request, refund gives its token back as well for a
+// request that was never sent.
+func (l *limiter) release(refund bool) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.inFlight--
+	if refund && l.limit.Rate > 0 {
+		l.tokens = math.Min(l.burst, l.tokens+1)
+	}
+	l.wake()
+}
+
+// rateLimits are the limiters of the RateLimits of a client, which outlive reloads.
+type rateLimits struct {
+	lock     sync.RWMutex
+	limiters map[limitScope]*limiter
+	order    []limitScope
+}
+
+func newRateLimits(limits []config.RateLimit) *rateLimits {
+	r := &rateLimits{}
+	r.update(limits)
+	return r
+}
+
+// update replaces the limits, the limiters of the scopes kept keep their state.
+func (r *rateLimits) update(limits []config.RateLimit) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	limiters := make(map[limitScope]*limiter, len(limits))
+	order := make([]limitScope, 0, len(limits))
+	for _, limit := range limits {
+		scope := scopeOf(limit)
+		if l, ok := r.limiters[scope]; ok {
+			l.update(limit)
+			limiters[scope] = l
+		} else {
+			limiters[scope] = newLimiter(limit)
+		}
+		order = append(order, scope)
+	}
+	r.limiters, r.order = limiters, order
+}
+
+// acquire takes room in every limiter matching the request and returns the function
+// releasing it, to be called with true when the request was not sent after all.
+func (r *rateLimits) acquire(ctx context.Context, bizId, tenantId string, info MethodInfo, wait bool) (release func(refund bool), err error) {
+	r.lock.RLock()
+	var matched []*limiter
+	for _, scope := range r.order {
+		if scope.matches(bizId, tenantId, info.class()) {
+			matched = append(matched, r.limiters[scope])
+		}
+	}
+	r.lock.RUnlock()
+	release = func(refund bool) {
+		for _, l := range matched {
+			l.release(refund)
+		}
+	}
+	for i, l := range matched {
+		if err := l.acquire(ctx, info.Method, wait); err != nil {
+			// give back what the request got so far
+			for _, acquired := range matched[:i] {
+				acquired.release(true)
+			}
+			return nil, err
+		}
+	}
+	return release, nil
+}
+
+// acquireLimits takes room for a request of info in the rate limits of state, see
+// rateLimits.acquire.
+func (client *RestClient) acquireLimits(ctx context.Context, state *clientState, bizId, tenantId string, info MethodInfo, path, chainCallType string) (func(refund bool), error) {
+	release, err := state.limits.acquire(ctx, bizId, tenantId, info, state.properties.RateLimitWait)
+	if err != nil {
+		if _, ok := err.(*RateLimitError); ok {
+			client.metrics().RequestDone(info.Method, path, CodeRateLimited, 0)
+		}
+		client.log().Warn(fmt.Sprintf("fail to send %v request", chainCallType), "err", err.Error())
+		return nil, err
+	}
+	return release, nil
+}
+
+// limitKeyOf returns the bizid and tenant id of the param of a call.
+func limitKeyOf(param interface{}) (bizId, tenantId string) {
+	switch p := param.(type) {
+	case *model.CallRestBizParam:
+		return p.BizId, p.TenantId
+	case model.CallRestBizParam:
+		return p.BizId, p.TenantId
+	case *model.CallRestParam:
+		return p.BizId, ""
+	case model.CallRestParam:
+		return p.BizId, ""
+	}
+	return "", ""
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oldercn/restclient-go-sdk/client/config"
+	"github.com/oldercn/restclient-go-sdk/model"
+	"github.com/oldercn/restclient-go-sdk/response"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimit(t *testing.T) {
+	var calls int32
+	client, closeServer := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		writeResp(w, response.BaseResp{Success: true, Code: "200"})
+	})
+	defer closeServer()
+	client.RestClientProperties.RateLimits = []config.RateLimit{
+		{BizId: "biz", Rate: 0.001, Burst: 2},
+		{Class: string(ClassWrite), Rate: 0.001},
+	}
+	collector := NewCollector()
+	client.metricsSink = collector
+
+	require.NoError(t, queryReceipt(client))
+	require.NoError(t, queryReceipt(client))
+	err := queryReceipt(client)
+	require.True(t, errors.Is(err, ErrRateLimited))
+	require.Equal(t, &RateLimitError{Method: model.QUERYRECEIPT, Limit: client.RestClientProperties.RateLimits[0]}, err)
+	require.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	require.Equal(t, float64(1), collector.Snapshot()[`restclient_requests{method="QUERYRECEIPT",endpoint="/api/contract/chainCallForBiz",code="rate_limited"}`])
+
+	// other chains are not limited
+	_, err = client.QueryReceipt("other", "abcd")
+	require.NoError(t, err)
+}
+
+func TestRateLimitInFlight(t *testing.T) {
+	var inFlight, maxInFlight int32
+	unblock := make(chan struct{})
+	client, closeServer := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		if n > atomic.LoadInt32(&maxInFlight) {
+			atomic.StoreInt32(&maxInFlight, n)
+		}
+		<-unblock
+		writeResp(w, response.BaseResp{Success: true, Code: "200"})
+	})
+	defer closeServer()
+	client.RestClientProperties.RateLimits = []config.RateLimit{{BizId: "biz", MaxInFlight: 1}}
+	client.RestClientProperties.RateLimitWait = true
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- queryReceipt(client)
+		}()
+	}
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&inFlight) == 1 }, time.Second, time.Millisecond)
+
+	// a waiting call gives up with its context
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	param := model.CallRestBizParam{BaseParam: model.BaseParam{AccessId: "access", BizId: "biz", Hash: "abcd", Method: model.QUERYRECEIPT}}
+	_, err := client.ChainCallForBizContext(ctx, param)
+	require.Equal(t, context.DeadlineExceeded, err)
+
+	close(unblock)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&maxInFlight))
+}
+
+func TestSetRateLimits(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restclient-ratelimit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	server := newHandshakeServer()
+	defer server.Close()
+
+	properties := config.RestClientProperties{RestUrl: server.URL, AccessId: "access", AccessSecret: writeTestKey(t, dir)}
+	client, err := NewRestClientFromProperties(properties)
+	require.NoError(t, err)
+	require.NoError(t, client.SetRateLimits([]config.RateLimit{{Rate: 0.001}}))
+	require.NoError(t, queryReceipt(client))
+	require.True(t, errors.Is(queryReceipt(client), ErrRateLimited))
+
+	// the bucket is kept, only its rate changes
+	require.NoError(t, client.SetRateLimits([]config.RateLimit{{Rate: 1000}}))
+	require.Eventually(t, func() bool { return queryReceipt(client) == nil }, time.Second, time.Millisecond)
+	require.Error(t, client.SetRateLimits([]config.RateLimit{{Burst: 1}}))
+	require.NoError(t, client.SetRateLimits(nil))
+	for i := 0; i < 5; i++ {
+		require.NoError(t, queryReceipt(client))
+	}
+}
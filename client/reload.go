@@ -0,0 +1,250 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/url"
+	"reflect"
+	"time"
+
+	"github.com/oldercn/restclient-go-sdk/client/config"
+	"github.com/oldercn/restclient-go-sdk/utils"
+)
+
+// DefaultWatchInterval is how often a watched config source is polled.
+var DefaultWatchInterval = 10 * time.Second
+
+// clientState is what a request needs from the client. It is replaced as a whole,
+// so a request sees either the old or the new state, never a mix of both.
+type clientState struct {
+	properties config.RestClientProperties
+	httpClient *http.Client
+	signer     utils.Signer
+	token      string
+	certs      []*x509.Certificate // the certificates of the TLS properties
+	tlsFiles   tlsFiles            // the TLS files certs were read from
+	endpoints  *endpointPool
+	limits     *rateLimits // shared by all states of a client
+}
+
+// ConfigProvider returns the current properties of a client.
+type ConfigProvider func() (config.RestClientProperties, error)
+
+// FileConfigProvider provides the properties loaded from a file, see config.Load.
+func FileConfigProvider(path, profile string) ConfigProvider {
+	return func() (config.RestClientProperties, error) {
+		return config.Load(path, profile)
+	}
+}
+
+// Option configures a RestClient when it is created.
+type Option func(*clientOptions)
+
+type clientOptions struct {
+	provider              ConfigProvider
+	watchFile             bool
+	watchInterval         time.Duration
+	signer                utils.Signer
+	logger                Logger
+	unredactedLogs        bool
+	metrics               Metrics
+	tracer                Tracer
+	tlsConfig             *tls.Config
+	proxy                 func(*http.Request) (*url.URL, error)
+	interceptors          []Interceptor
+	transportInterceptors []TransportInterceptor
+	orderStore            OrderStore
+	orderLookup           OrderLookup
+	orderIDGenerator      OrderIDGenerator
+	callValidation        bool
+}
+
+// WithSigner makes the client sign its handshakes with signer instead of the key
+// file in AccessSecret, which may then be empty. Load still requires AccessSecret,
+// so properties without one go through NewRestClientFromProperties.
+func WithSigner(signer utils.Signer) Option {
+	return func(options *clientOptions) {
+		options.signer = signer
+	}
+}
+
+// WithWatch makes the client poll provider every interval and apply the properties
+// it returns, see Reload. A zero interval uses DefaultWatchInterval.
+func WithWatch(provider ConfigProvider, interval time.Duration) Option {
+	return func(options *clientOptions) {
+		options.provider = provider
+		options.watchInterval = interval
+	}
+}
+
+// WithFileWatch makes a client created from a file watch that file for changes.
+func WithFileWatch(interval time.Duration) Option {
+	return func(options *clientOptions) {
+		options.watchFile = true
+		options.watchInterval = interval
+	}
+}
+
+// newSigner returns the signer set by WithSigner, else one for the key file in
+// AccessSecret that picks up a key rotated in place, after checking it signs with
+// SignAlgorithm.
+func newSigner(restClientProperties config.RestClientProperties, signer utils.Signer) (utils.Signer, error) {
+	if signer == nil {
+		var err error
+		if signer, err = utils.NewRotatingPEMFileSigner(restClientProperties.AccessSecret); err != nil {
+			return nil, err
+		}
+	}
+	if err := utils.CheckSignAlgorithm(signer, restClientProperties.SignAlgorithm); err != nil {
+		return nil, err
+	}
+	return signer, nil
+}
+
+// current returns the state requests use, clients built as struct literals start
+// from their exported fields.
+func (client *RestClient) current() *clientState {
+	client.initOnce.Do(func() {
+		if client.state.Load() == nil {
+			client.state.Store(&clientState{
+				properties: client.RestClientProperties,
+				httpClient: client.httpClient,
+				signer:     client.signer,
+				token:      client.RestToken,
+				tlsFiles:   stampTLSFiles(client.RestClientProperties),
+				endpoints:  newEndpointPool(client.RestClientProperties),
+				limits:     newRateLimits(client.RestClientProperties.RateLimits),
+			})
+		}
+	})
+	return client.state.Load().(*clientState)
+}
+
+// Properties returns the properties the client currently uses.
+func (client *RestClient) Properties() config.RestClientProperties {
+	return client.current().properties
+}
+
+// Token returns the rest token of the latest handshake.
+func (client *RestClient) Token() string {
+	return client.current().token
+}
+
+// Reload swaps the properties of the client at runtime. Requests in flight finish
+// with the properties they started with. Changed endpoints, AccessId, AccessSecret
+// or SignAlgorithm make the client handshake with the new properties first, nothing
+// changes when that fails. Changed pool sizes, TLS or proxy properties, or TLS files
+// rewritten in place, replace the http client. A key rotated in place at the
+// AccessSecret path needs no Reload, it signs the next handshake.
+func (client *RestClient) Reload(restClientProperties config.RestClientProperties) error {
+	if err := client.validate(restClientProperties); err != nil {
+		return err
+	}
+	client.reloadLock.Lock()
+	defer client.reloadLock.Unlock()
+
+	old := client.current()
+	files := stampTLSFiles(restClientProperties)
+	if reflect.DeepEqual(old.properties, restClientProperties) && !old.tlsFiles.changed(files) {
+		return nil
+	}
+	next := &clientState{properties: restClientProperties, httpClient: old.httpClient, signer: old.signer, token: old.token, certs: old.certs, tlsFiles: old.tlsFiles, endpoints: old.endpoints, limits: old.limits}
+	if transportChanged(old, restClientProperties, files) {
+		httpClient, certs, err := client.newHTTPClient(restClientProperties)
+		if err != nil {
+			return err
+		}
+		next.httpClient, next.certs, next.tlsFiles = httpClient, certs, files
+	}
+	handshook := false
+	if endpointsChanged(old.properties, restClientProperties) ||
+		old.properties.AccessId != restClientProperties.AccessId ||
+		old.properties.AccessSecret != restClientProperties.AccessSecret ||
+		old.properties.SignAlgorithm != restClientProperties.SignAlgorithm {
+		if old.properties.AccessSecret != restClientProperties.AccessSecret ||
+			old.properties.SignAlgorithm != restClientProperties.SignAlgorithm {
+			signer, err := newSigner(restClientProperties, client.signer)
+			if err != nil {
+				return err
+			}
+			next.signer = signer
+		}
+		// the tokens of the nodes are of the old credentials
+		next.endpoints = newEndpointPool(restClientProperties)
+		token, err := client.handshakeAny(context.Background(), next)
+		if err != nil {
+			return err
+		}
+		next.token, handshook = token, true
+	}
+	// requests go on refreshing the token while the client connects, the lock is only
+	// taken to swap the state
+	client.shakeLock.Lock()
+	if !handshook {
+		next.token = client.current().token
+	}
+	if !reflect.DeepEqual(old.properties.RateLimits, restClientProperties.RateLimits) {
+		next.limits.update(restClientProperties.RateLimits)
+	}
+	client.state.Store(next)
+	client.shakeLock.Unlock()
+	if next.httpClient != old.httpClient {
+		// in-flight requests keep their connections, only idle ones are closed
+		old.httpClient.CloseIdleConnections()
+		client.warnExpiringCerts(next.certs)
+	}
+	client.log().Info("reload rest client", "restClientProperties", restClientProperties.Dump())
+	return nil
+}
+
+// validate checks restClientProperties, clients with a signer of their own need no
+// AccessSecret.
+func (client *RestClient) validate(restClientProperties config.RestClientProperties) error {
+	if client.signer != nil {
+		return restClientProperties.ValidateWithoutSecret()
+	}
+	return restClientProperties.Validate()
+}
+
+// watch polls provider until Close is called.
+func (client *RestClient) watch(provider ConfigProvider, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	ticker := time.NewTicker(interval)
+	go func(stop chan struct{}) {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				restClientProperties, err := provider()
+				if err == nil {
+					err = client.Reload(restClientProperties)
+				}
+				if err != nil {
+					client.log().Error("fail to reload restClientProperties", "err", err.Error())
+				}
+			}
+		}
+	}(client.stopped())
+}
+
+// stopped returns the channel Close closes.
+func (client *RestClient) stopped() chan struct{} {
+	client.stopOnce.Do(func() {
+		client.stop = make(chan struct{})
+	})
+	return client.stop
+}
+
+// Close stops watching the config source and probing endpoints, every client made
+// by NewRestClient and the like needs it.
+func (client *RestClient) Close() {
+	client.closeOnce.Do(func() {
+		close(client.stopped())
+	})
+}
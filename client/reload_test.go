@@ -0,0 +1,239 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/oldercn/restclient-go-sdk/client/config"
+	"github.com/oldercn/restclient-go-sdk/model"
+	"github.com/oldercn/restclient-go-sdk/response"
+	"github.com/oldercn/restclient-go-sdk/utils"
+	"github.com/oldercn/restclient-go-sdk/utils/sm2"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestKey writes a fresh rsa key in PKCS#8 PEM to dir and returns its path.
+func writeTestKey(t *testing.T, dir string) string {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	path := filepath.Join(dir, fmt.Sprintf("access-%d.key", time.Now().UnixNano()))
+	require.NoError(t, ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+	return path
+}
+
+// handshakeServer hands out token-<n> on every handshake and remembers the token of every call.
+type handshakeServer struct {
+	*httptest.Server
+	shakes int32
+	lock   sync.Mutex
+	tokens []string
+}
+
+func newHandshakeServer() *handshakeServer {
+	server := &handshakeServer{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == ShakeHandPath {
+			writeResp(w, response.BaseResp{Success: true, Code: "200", Data: fmt.Sprintf("token-%d", atomic.AddInt32(&server.shakes, 1))})
+			return
+		}
+		var param model.CallRestBizParam
+		_ = json.NewDecoder(r.Body).Decode(&param)
+		server.lock.Lock()
+		server.tokens = append(server.tokens, param.Token)
+		server.lock.Unlock()
+		writeResp(w, response.BaseResp{Success: true, Code: "200"})
+	}))
+	return server
+}
+
+func queryReceipt(client *RestClient) error {
+	_, err := client.QueryReceipt("biz", "abcd")
+	return err
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restclient-reload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	server := newHandshakeServer()
+	defer server.Close()
+
+	properties := config.RestClientProperties{RestUrl: server.URL, AccessId: "access", AccessSecret: writeTestKey(t, dir)}
+	client, err := NewRestClientFromProperties(properties)
+	require.NoError(t, err)
+	require.Equal(t, "token-1", client.Token())
+
+	properties.RetryMaxAttempts = 2
+	properties.MaxIdleConns = 3
+	require.NoError(t, client.Reload(properties))
+	require.Equal(t, "token-1", client.Token())
+	require.Equal(t, 2, client.Properties().RetryMaxAttempts)
+
+	properties.AccessSecret = writeTestKey(t, dir)
+	require.NoError(t, client.Reload(properties))
+	require.Equal(t, "token-2", client.Token())
+	require.NoError(t, queryReceipt(client))
+	require.Equal(t, []string{"token-2"}, server.tokens)
+
+	broken := properties
+	broken.RestUrl = "tcp://" + server.Listener.Addr().String()
+	require.Error(t, client.Reload(broken))
+	require.Equal(t, properties, client.Properties())
+
+	// requests keep working while the credentials rotate
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				require.NoError(t, queryReceipt(client))
+			}
+		}()
+	}
+	for i := 0; i < 3; i++ {
+		properties.AccessId = fmt.Sprintf("access-%d", i)
+		require.NoError(t, client.Reload(properties))
+	}
+	wg.Wait()
+	require.Equal(t, "token-5", client.Token())
+}
+
+func TestReloadHandshakeUnlocked(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restclient-reload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	arrived := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var shake model.ShakeRequest
+		_ = json.NewDecoder(r.Body).Decode(&shake)
+		if shake.AccessId == "rotated" {
+			close(arrived)
+			<-release
+		}
+		writeResp(w, response.BaseResp{Success: true, Code: "200", Data: "token-" + shake.AccessId})
+	}))
+	defer server.Close()
+
+	properties := config.RestClientProperties{RestUrl: server.URL, AccessId: "access", AccessSecret: writeTestKey(t, dir)}
+	client, err := NewRestClientFromProperties(properties)
+	require.NoError(t, err)
+
+	properties.AccessId = "rotated"
+	reloaded := make(chan error)
+	go func() {
+		reloaded <- client.Reload(properties)
+	}()
+	<-arrived
+	// the token can be refreshed while the reload handshakes
+	shaken := make(chan error, 1)
+	go func() {
+		shaken <- client.shake(context.Background())
+	}()
+	select {
+	case err := <-shaken:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		close(release)
+		t.Fatal("the handshake of the reload blocks refreshing the token")
+	}
+	require.Equal(t, "token-access", client.Token())
+	close(release)
+	require.NoError(t, <-reloaded)
+	require.Equal(t, "token-rotated", client.Token())
+}
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restclient-reload")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	server := newHandshakeServer()
+	defer server.Close()
+
+	path := filepath.Join(dir, "rest-config.json")
+	writeConfig := func(accessId string) {
+		data, err := json.Marshal(config.RestClientProperties{RestUrl: server.URL, AccessId: accessId, AccessSecret: writeTestKey(t, dir)})
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(path, data, 0600))
+	}
+	writeConfig("access")
+	client, err := NewRestClient(path, WithFileWatch(10*time.Millisecond))
+	require.NoError(t, err)
+	defer client.Close()
+
+	writeConfig("rotated")
+	require.Eventually(t, func() bool { return client.Properties().AccessId == "rotated" }, time.Second, 10*time.Millisecond)
+	require.Equal(t, "token-2", client.Token())
+}
+
+func TestWithSigner(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	sm2Key, err := sm2.GenerateKey(sm2.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		signer    utils.Signer
+		algorithm string
+	}{
+		{utils.NewKeySigner(ecKey), utils.SignAlgorithmSHA256},
+		{utils.NewKeySigner(sm2Key), utils.SignAlgorithmSM2},
+	} {
+		signer := tc.signer
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var shakeRequest model.ShakeRequest
+			_ = json.NewDecoder(r.Body).Decode(&shakeRequest)
+			sig, _ := hex.DecodeString(shakeRequest.Secret)
+			if err := utils.VerifySignature(signer.Public(), []byte(shakeRequest.AccessId+shakeRequest.Time), sig); err != nil {
+				writeResp(w, response.BaseResp{Success: false, Code: "400"})
+				return
+			}
+			writeResp(w, response.BaseResp{Success: true, Code: "200", Data: "token-" + shakeRequest.AccessId})
+		}))
+
+		// the signer stands in for AccessSecret
+		properties := config.RestClientProperties{RestUrl: server.URL, AccessId: "access", SignAlgorithm: tc.algorithm}
+		_, err = NewRestClientFromProperties(properties)
+		require.Error(t, err)
+		client, err := NewRestClientFromProperties(properties, WithSigner(signer))
+		require.NoError(t, err, tc.algorithm)
+		require.Equal(t, "token-access", client.Token())
+
+		properties.AccessId = "rotated"
+		require.NoError(t, client.Reload(properties))
+		require.Equal(t, "token-rotated", client.Token())
+
+		// the key has to fit SignAlgorithm
+		properties.SignAlgorithm = ""
+		require.NoError(t, client.Reload(properties))
+		for _, algorithm := range []string{utils.SignAlgorithmSHA256, utils.SignAlgorithmSM2} {
+			if algorithm != tc.algorithm {
+				properties.SignAlgorithm = algorithm
+				require.Error(t, client.Reload(properties))
+				_, err = NewRestClientFromProperties(properties, WithSigner(signer))
+				require.Error(t, err)
+			}
+		}
+		server.Close()
+	}
+}
@@ -2,22 +2,26 @@ package client
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
-	"os"
+	"net/url"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/oldercn/restclient-go-sdk/bind"
 	"github.com/oldercn/restclient-go-sdk/client/config"
 	"github.com/oldercn/restclient-go-sdk/model"
-	"github.com/oldercn/restclient-go-sdk/mychain"
 	"github.com/oldercn/restclient-go-sdk/mychain/mychain-sdk-go/common/codec/contract/abi"
 	"github.com/oldercn/restclient-go-sdk/response"
 	"github.com/oldercn/restclient-go-sdk/utils"
-	log "github.com/sirupsen/logrus"
 )
 
 var (
@@ -39,65 +43,113 @@ const (
 )
 
 type RestClient struct {
-	RestClientProperties config.RestClientProperties
-	RestToken            string
-	httpClient           *http.Client
+	// RestClientProperties and RestToken are the values the client was created with,
+	// use Properties and Token for the current ones.
+	RestClientProperties  config.RestClientProperties
+	RestToken             string
+	httpClient            *http.Client
+	signer                utils.Signer // set by WithSigner
+	logger                Logger       // set by WithLogger
+	unredactedLogs        bool
+	metricsSink           Metrics // set by WithMetrics
+	tracerSink            Tracer  // set by WithTracer
+	interceptors          []Interceptor
+	transportInterceptors []TransportInterceptor
+	tlsConfig             *tls.Config                           // set by WithTLSConfig
+	proxy                 func(*http.Request) (*url.URL, error) // set by WithProxy
+	orderStore            OrderStore                            // set by WithOrderStore
+	orderLookup           OrderLookup                           // set by WithOrderLookup
+	orderIDGenerator      OrderIDGenerator                      // set by WithOrderIDGenerator
+	callValidation        bool                                  // set by WithCallValidation
+
+	initOnce   sync.Once
+	state      atomic.Value // *clientState
+	shakeLock  sync.Mutex
+	reloadLock sync.Mutex // serializes Reload, which handshakes without shakeLock
+	stopOnce   sync.Once
+	stop       chan struct{} // closed by Close
+	closeOnce  sync.Once
+	probing    int32 // 1 while a probe runs, see probe
+
+	orderStoreOnce sync.Once
+	pendingLock    sync.Mutex
+	pendingOrders  map[orderKey]struct{} // the orders being sent
 }
 
-func init() {
-	// Log as JSON instead of the default ASCII formatter.
-	log.SetFormatter(&log.JSONFormatter{})
-
-	// Output to stdout instead of the default stderr
-	// Can be any io.Writer, see below for File example
-	log.SetOutput(os.Stdout)
-
-	// Only log the warning severity or above.
-	log.SetLevel(log.InfoLevel)
+// NewRestClient loads the properties from a json or yaml file, see config.Load,
+// and connects to the rest server with them. Call Close when done with the client,
+// it stops the goroutines watching the file and probing endpoints.
+func NewRestClient(restClientPropertiesPath string, opts ...Option) (*RestClient, error) {
+	return NewRestClientWithProfile(restClientPropertiesPath, "", opts...)
 }
 
-func NewRestClient(restClientPropertiesPath string) (*RestClient, error) {
-	data, err := ioutil.ReadFile(restClientPropertiesPath)
-	if err != nil {
-		log.WithFields(log.Fields{
-			"restClientPropertiesPath": restClientPropertiesPath,
-			"err":                      err.Error(),
-		}).Error("fail to read restClientProperties")
-		return nil, err
-	}
-	restClientProperties := config.RestClientProperties{}
-	err = json.Unmarshal(data, &restClientProperties)
+// NewRestClientWithProfile is NewRestClient using the named profile of the file.
+func NewRestClientWithProfile(restClientPropertiesPath, profile string, opts ...Option) (*RestClient, error) {
+	restClientProperties, err := config.Load(restClientPropertiesPath, profile)
 	if err != nil {
-		log.WithFields(log.Fields{
-			"restClientPropertiesPath": restClientPropertiesPath,
-			"err":                      err.Error(),
-		}).Error("fail to parse restClientProperties")
+		loadOptions := &clientOptions{}
+		for _, opt := range opts {
+			opt(loadOptions)
+		}
+		loadOptions.log().Error("fail to load restClientProperties", "restClientPropertiesPath", restClientPropertiesPath, "err", err.Error())
 		return nil, err
 	}
+	opts = append(opts, func(options *clientOptions) {
+		if options.watchFile && options.provider == nil {
+			options.provider = FileConfigProvider(restClientPropertiesPath, profile)
+		}
+	})
+	return NewRestClientFromProperties(restClientProperties, opts...)
+}
 
-	maxIdleConns := DefaultMaxIdleConns
-	if restClientProperties.MaxIdleConns != 0 {
-		maxIdleConns = restClientProperties.MaxIdleConns
+// NewRestClientFromProperties validates restClientProperties and connects to the rest server.
+// Call Close when done with the client, see NewRestClient.
+func NewRestClientFromProperties(restClientProperties config.RestClientProperties, opts ...Option) (*RestClient, error) {
+	options := &clientOptions{}
+	for _, opt := range opts {
+		opt(options)
 	}
-	idleConnTimeout := DefaultIdleConnTimeout
-	if restClientProperties.IdleConnTimeout != 0 {
-		idleConnTimeout = restClientProperties.IdleConnTimeout
+	restClient := &RestClient{
+		RestClientProperties:  restClientProperties,
+		signer:                options.signer,
+		logger:                options.logger,
+		unredactedLogs:        options.unredactedLogs,
+		metricsSink:           options.metrics,
+		tracerSink:            options.tracer,
+		interceptors:          options.interceptors,
+		transportInterceptors: options.transportInterceptors,
+		tlsConfig:             options.tlsConfig,
+		proxy:                 options.proxy,
+		orderStore:            options.orderStore,
+		orderLookup:           options.orderLookup,
+		orderIDGenerator:      options.orderIDGenerator,
+		callValidation:        options.callValidation,
+	}
+	if err := restClient.validate(restClientProperties); err != nil {
+		return nil, err
 	}
-	tr := &http.Transport{
-		MaxIdleConns:    maxIdleConns,
-		IdleConnTimeout: time.Duration(idleConnTimeout) * time.Second,
+	tlsFiles := stampTLSFiles(restClientProperties)
+	httpClient, certs, err := restClient.newHTTPClient(restClientProperties)
+	if err != nil {
+		return nil, err
 	}
-	client := &http.Client{Transport: tr}
+	restClient.httpClient = httpClient
+	restClient.log().Info("new rest client", "restClientProperties", restClientProperties.Dump())
 
-	restClient := &RestClient{
-		RestClientProperties: restClientProperties,
-		httpClient:           client,
+	signer, err := newSigner(restClientProperties, options.signer)
+	if err != nil {
+		return nil, err
 	}
+	restClient.state.Store(&clientState{properties: restClientProperties, httpClient: httpClient, signer: signer, certs: certs, tlsFiles: tlsFiles, endpoints: newEndpointPool(restClientProperties), limits: newRateLimits(restClientProperties.RateLimits)})
 
-	err = restClient.shake()
+	err = restClient.shake(context.Background())
 	if err != nil {
 		return nil, err
 	}
+	restClient.RestToken = restClient.Token()
+	if options.provider != nil {
+		restClient.watch(options.provider, options.watchInterval)
+	}
 	return restClient, nil
 }
 
@@ -114,45 +166,68 @@ func (client *RestClient) CreateQueryAccountParam(queryAccount string) (model.Cl
 	return queryAccountParam, nil
 }
 
-func (client *RestClient) shake() error {
-	log.Info("start shake hand")
-	nowMill := time.Now().UnixNano() / 1e6
-	secret, err := utils.Sign(fmt.Sprintf("%v%v", client.RestClientProperties.AccessId, nowMill), client.RestClientProperties.AccessSecret)
+// shake refreshes the rest token.
+func (client *RestClient) shake(ctx context.Context) error {
+	client.shakeLock.Lock()
+	defer client.shakeLock.Unlock()
+	state := client.current()
+	token, err := client.handshakeAny(ctx, state)
 	if err != nil {
-		log.WithFields(log.Fields{
-			"err": err.Error(),
-		}).Error("fail to sign secret")
 		return err
 	}
+	next := *state
+	next.token = token
+	client.state.Store(&next)
+	// long running clients learn of expiring certificates when the token expires
+	client.warnExpiringCerts(state.certs)
+	return nil
+}
+
+// handshake gets a rest token from the endpoint restUrl with the properties of state.
+func (client *RestClient) handshake(ctx context.Context, state *clientState, restUrl string) (token string, err error) {
+	start := time.Now()
+	ctx, span := client.tracer().Start(ctx, SpanHandshake)
+	defer func() {
+		client.metrics().Handshake(err, time.Since(start))
+		endSpan(span, err)
+	}()
+	client.log().Info("start shake hand")
+	nowMill := time.Now().UnixNano() / 1e6
+	signer := state.signer
+	if signer == nil {
+		// a client built as a struct literal
+		var err error
+		if signer, err = newSigner(state.properties, nil); err != nil {
+			return "", err
+		}
+	}
+	sig, err := signer.Sign([]byte(fmt.Sprintf("%v%v", state.properties.AccessId, nowMill)))
+	if err != nil {
+		client.log().Error("fail to sign secret", "err", err.Error())
+		return "", err
+	}
 	shakeRequest := &model.ShakeRequest{
-		AccessId: client.RestClientProperties.AccessId,
+		AccessId: state.properties.AccessId,
 		Time:     fmt.Sprintf("%v", nowMill),
-		Secret:   secret,
+		Secret:   fmt.Sprintf("%x", sig),
 	}
 	jsonStr, err := json.Marshal(shakeRequest)
 	if err != nil {
-		log.WithFields(log.Fields{
-			"shakeRequest": shakeRequest,
-			"err":          err.Error(),
-		}).Error("fail to marshal shakeRequest")
-		return err
+		client.log().Error("fail to marshal shakeRequest", "accessId", shakeRequest.AccessId, "err", err.Error())
+		return "", err
 	}
-	req, err := http.NewRequest(http.MethodPost, client.RestClientProperties.RestUrl+ShakeHandPath, bytes.NewBuffer(jsonStr))
+	req, err := http.NewRequest(http.MethodPost, restUrl+ShakeHandPath, bytes.NewBuffer(jsonStr))
 	if err != nil {
-		log.WithFields(log.Fields{
-			"shakeRequest": shakeRequest,
-			"err":          err.Error(),
-		}).Error("fail to new shakeRequest")
-		return err
+		client.log().Error("fail to new shakeRequest", "accessId", shakeRequest.AccessId, "err", err.Error())
+		return "", err
 	}
+	req = req.WithContext(ctx)
 	req.Header.Add("Content-Type", "application/json;charset=utf-8")
-	resp, err := client.httpClient.Do(req)
+	client.tracer().Inject(ctx, req.Header)
+	resp, err := state.httpClient.Do(req)
 	if err != nil {
-		log.WithFields(log.Fields{
-			"req": req,
-			"err": err.Error(),
-		}).Error("fail to get shakeResponse")
-		return err
+		client.log().Error("fail to get shakeResponse", "url", req.URL.String(), "err", err.Error())
+		return "", err
 	}
 	defer resp.Body.Close()
 
@@ -160,18 +235,33 @@ func (client *RestClient) shake() error {
 	baseResp := response.BaseResp{}
 	err = json.Unmarshal(body, &baseResp)
 	if err != nil {
-		log.WithFields(log.Fields{
-			"body": string(body),
-			"err":  err.Error(),
-		}).Error("fail to unmarshal shakeResponse")
-		return err
+		client.log().Error("fail to unmarshal shakeResponse", "body", redacted(string(body)), "err", err.Error())
+		return "", err
 	}
-	client.RestToken = baseResp.Data
-	log.Info("new rest token:" + client.RestToken)
-	return nil
+	if client.unredactedLogs {
+		client.log().Info("new rest token", "token", baseResp.Data)
+	} else {
+		client.log().Info("new rest token", "token", redacted(baseResp.Data))
+	}
+	return baseResp.Data, nil
 }
 
 func (client *RestClient) ChainCall(hash, bizid, requestStr string, method model.Method) (response.BaseResp, error) {
+	return client.ChainCallContext(context.Background(), hash, bizid, requestStr, method)
+}
+
+// ChainCallContext is ChainCall tracing its span as a child of the span in ctx. The
+// request is canceled when ctx is done.
+func (client *RestClient) ChainCallContext(ctx context.Context, hash, bizid, requestStr string, method model.Method) (baseResp response.BaseResp, err error) {
+	info, _ := LookupMethod(method)
+	ctx, span := client.startCallSpan(ctx, method, bizid, "")
+	defer func() {
+		client.endCallSpan(span, info, hash, baseResp, err)
+	}()
+	return client.chainCall(ctx, hash, bizid, requestStr, method)
+}
+
+func (client *RestClient) chainCall(ctx context.Context, hash, bizid, requestStr string, method model.Method) (response.BaseResp, error) {
 	if bizid == "" {
 		return response.BaseResp{}, fmt.Errorf("bizid is empty")
 	}
@@ -179,195 +269,432 @@ func (client *RestClient) ChainCall(hash, bizid, requestStr string, method model
 		return response.BaseResp{}, fmt.Errorf("method is empty")
 	}
 	param := &model.CallRestParam{}
-	param.AccessId = client.RestClientProperties.AccessId
-	param.Token = client.RestToken
+	state := client.current()
+	param.AccessId = state.properties.AccessId
+	param.Token = state.token
 	param.Hash = hash
 	param.BizId = bizid
 	param.RequestStr = requestStr
 	param.Method = method
-	return client.retryableSendRequest(param, client.RestClientProperties.RestUrl+ChainCallPath, ChainCall)
+	info, _ := LookupMethod(method)
+	call := &Call{Info: info, Path: ChainCallPath, Param: param}
+	return client.invoke(ctx, call, func(ctx context.Context, call *Call) (response.BaseResp, error) {
+		return client.retryableSendRequest(ctx, call.Param, call.Path, ChainCall, call.Info)
+	})
 }
 
 func (client *RestClient) ChainCallForBiz(param model.CallRestBizParam) (response.BaseResp, error) {
-	param.Token = client.RestToken
-	baseResp := utils.CheckCallRestBizParams(param)
-	if !baseResp.Success {
-		return response.BaseResp{}, fmt.Errorf("%v", baseResp.Data)
-	}
-	if param.Method == model.CREATEACCOUNT || param.Method == model.DEPLOYNATIVECONTRACT || param.Method == model.QUERYACCOUNT {
-		if param.MykmsKeyId == "" {
-			return client.ChainCall("", param.BizId, param.RequestStr, param.Method)
-		}
+	return client.ChainCallForBizContext(context.Background(), param)
+}
+
+// ChainCallForBizContext is ChainCallForBiz tracing its span as a child of the span
+// in ctx. The request is canceled when ctx is done.
+func (client *RestClient) ChainCallForBizContext(ctx context.Context, param model.CallRestBizParam) (baseResp response.BaseResp, err error) {
+	info, _ := LookupMethod(param.Method)
+	if param.OrderId == "" && needsOrderId(param.Method) {
+		param.OrderId = client.orderIDs().NewOrderID()
+	}
+	ctx, span := client.startCallSpan(ctx, param.Method, param.BizId, param.OrderId)
+	defer func() {
+		// on errors as well, the transaction of a failed write can be looked up by it
+		baseResp.OrderId = param.OrderId
+		client.endCallSpan(span, info, param.Hash, baseResp, err)
+	}()
+
+	state := client.current()
+	param.Token = state.token
+	if err := utils.ValidateCallRestBizParams(param); err != nil {
+		return response.BaseResp{}, err
+	}
+	if info.UnsignedChainCall && param.MykmsKeyId == "" && param.Uid == "" {
+		return client.chainCall(ctx, "", param.BizId, param.RequestStr, param.Method)
 	}
 
-	return client.retryableSendRequest(param, client.RestClientProperties.RestUrl+ChainCallForBizPath, ChainCallForBiz)
+	call := &Call{Info: info, Path: info.Path, Param: &param}
+	return client.invoke(ctx, call, func(ctx context.Context, call *Call) (response.BaseResp, error) {
+		return client.sendOnce(ctx, call, func(ctx context.Context, call *Call) (response.BaseResp, error) {
+			return client.retryableSendRequest(ctx, call.Param, call.Path, ChainCallForBiz, call.Info)
+		})
+	})
 }
 
-func (client *RestClient) retryableSendRequest(param interface{}, url string, chainCallType string) (response.BaseResp, error) {
-	retryMaxAttempts := DefaultRetryMaxAttempts
-	if client.RestClientProperties.RetryMaxAttempts != 0 {
-		retryMaxAttempts = client.RestClientProperties.RetryMaxAttempts
+// startCallSpan starts the span of a chain call of method.
+func (client *RestClient) startCallSpan(ctx context.Context, method model.Method, bizid, orderId string) (context.Context, Span) {
+	attributes := []Attribute{{AttrBizID, bizid}, {AttrMethod, string(method)}}
+	if orderId != "" {
+		attributes = append(attributes, Attribute{AttrOrderID, orderId})
 	}
-	backoffPeriod := DefaultBackOffPeriod
-	if client.RestClientProperties.BackOffPeriod != 0 {
-		backoffPeriod = client.RestClientProperties.BackOffPeriod
+	return client.tracer().Start(ctx, SpanChainCall+string(method), attributes...)
+}
+
+// endCallSpan ends the span of a chain call with its result. The tx hash is the one
+// queried, or the one a write returned.
+func (client *RestClient) endCallSpan(span Span, info MethodInfo, hash string, baseResp response.BaseResp, err error) {
+	if err == nil {
+		span.SetAttributes(Attribute{AttrResultCode, baseResp.Code})
+		if info.ProducesTxHash && baseResp.Success {
+			hash = baseResp.Data
+		}
+	}
+	if hash != "" {
+		span.SetAttributes(Attribute{AttrTxHash, hash})
 	}
+	endSpan(span, err)
+}
 
-	tick := time.Tick(time.Duration(backoffPeriod) * time.Millisecond)
+// retryableSendRequest posts param to path, spreading the attempts over the endpoints.
+func (client *RestClient) retryableSendRequest(ctx context.Context, param interface{}, path string, chainCallType string, info MethodInfo) (response.BaseResp, error) {
+	state := client.current()
+	retryMaxAttempts := DefaultRetryMaxAttempts
+	if state.properties.RetryMaxAttempts != 0 {
+		retryMaxAttempts = state.properties.RetryMaxAttempts
+	}
+	if info.RetryClass == RetryNever {
+		retryMaxAttempts = 1
+	}
+	httpClient := *state.httpClient
+	httpClient.Timeout = info.Timeout
+	backoffPeriod := DefaultBackOffPeriod
+	if state.properties.BackOffPeriod != 0 {
+		backoffPeriod = state.properties.BackOffPeriod
+	}
+
+	// 5xx results and requests that may have reached the server are only sent again
+	// when that is safe
+	fullRetry := info.RetryClass == RetryAll || client.confirmable(info, param)
+	// the write deduplicated by orderId, if param is one
+	order := orderOf(info, param)
+	// the error of the last attempt that may have reached the server
+	var maybeSent error
+
+	metrics := client.metrics()
+	tracer := client.tracer()
+	class := info.class()
+	lastCode := ""
+	metrics.InFlight(info.Method, 1)
+	defer metrics.InFlight(info.Method, -1)
+
+	bizId, tenantId := limitKeyOf(param)
+	// frees the room the last attempt took in the rate limits
+	var release func(refund bool)
+	defer func() {
+		if release != nil {
+			release(false)
+		}
+	}()
+	// endpoints that failed since the last backoff
+	var tried []*endpoint
+	ticker := time.NewTicker(time.Duration(backoffPeriod) * time.Millisecond)
+	defer ticker.Stop()
 	for i := 0; i < retryMaxAttempts; i++ {
-		jsonStr, err := json.Marshal(&param)
-		if err != nil {
+		if release != nil {
+			release(false)
+			release = nil
+		}
+		// the lookup takes room in the rate limits of its own
+		if baseResp, done, err := client.confirmSent(ctx, order, maybeSent); done {
+			return baseResp, err
+		}
+		if i > 0 {
+			metrics.Retry(info.Method, path, lastCode)
+		}
+		var err error
+		if release, err = client.acquireLimits(ctx, state, bizId, tenantId, info, path, chainCallType); err != nil {
 			return response.BaseResp{}, err
 		}
-		req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonStr))
+		e, b, err := client.pickOpenEndpoint(state, info, path, chainCallType, tried)
 		if err != nil {
-			log.WithFields(log.Fields{
-				"req": req,
-				"err": err.Error(),
-			}).Errorf("fail to new %v request", chainCallType)
+			release(true)
+			release = nil
 			return response.BaseResp{}, err
 		}
-		req.Header.Add("Content-Type", "application/json;charset=utf-8")
-		resp, err := client.httpClient.Do(req)
+		url := e.url + path
+		attemptCtx, span := tracer.Start(ctx, SpanAttempt, Attribute{AttrMethod, string(info.Method)}, Attribute{AttrAttempt, i + 1}, Attribute{AttrEndpoint, e.url})
+		start := time.Now()
+		var resp *http.Response
+		// whether the server may have got the request
+		sent := false
+		token, err := client.tokenFor(attemptCtx, state, e)
+		if err == nil {
+			param = withToken(param, token)
+			var req *http.Request
+			if req, err = client.newRequest(attemptCtx, url, param, chainCallType); err != nil {
+				release(true)
+				release = nil
+				client.recordOutcome(state, e, b, class, outcomeIgnored)
+				endSpan(span, err)
+				return response.BaseResp{}, err
+			}
+			start = time.Now()
+			resp, err = httpClient.Do(req)
+			sent = err == nil || !dialFailed(err)
+		}
 		if err != nil {
-			log.WithFields(log.Fields{
-				"req": req,
-				"err": err.Error(),
-			}).Errorf("fail to get %v response", chainCallType)
-			// retry later An error is returned if caused by client policy (such as
-			// CheckRedirect), or failure to speak HTTP (such as a network
-			// connectivity problem). A non-2xx status code doesn't cause an
-			// error.
+			lastCode = CodeTransportError
+			metrics.RequestDone(info.Method, path, lastCode, time.Since(start))
+			client.log().Error(fmt.Sprintf("fail to get %v response", chainCallType), "url", url, "err", err.Error())
+			client.attemptFailed(ctx, state, e, b, class)
+			tried = append(tried, e)
+			span.SetAttributes(Attribute{AttrResultCode, lastCode})
+			endSpan(span, err)
+			if sent {
+				maybeSent = err
+			}
+			if sent && !fullRetry || ctx.Err() != nil {
+				return response.BaseResp{}, client.orderFailed(order, maybeSent, err)
+			}
+			if failover(state, tried) {
+				client.log().Info(fmt.Sprintf("fail over %v request", chainCallType), "url", url)
+				continue
+			}
+			tried = nil
+			// no room is held during the backoff
+			release(false)
+			release = nil
 			select {
-			case <-tick:
-				log.WithFields(log.Fields{
-					"req": req,
-				}).Infof("retry %v request", chainCallType)
+			case <-ticker.C:
+				client.log().Info(fmt.Sprintf("retry %v request", chainCallType), "url", url)
+			case <-ctx.Done():
+				return response.BaseResp{}, client.orderFailed(order, maybeSent, ctx.Err())
 			}
+			continue
+		}
+		client.endpointSucceeded(e, time.Since(start))
+		span.SetAttributes(Attribute{AttrHTTPStatus, resp.StatusCode})
+		if resp.StatusCode >= 300 && resp.StatusCode < 600 {
 			resp.Body.Close()
+			metrics.RequestDone(info.Method, path, fmt.Sprintf("http_%d", resp.StatusCode), time.Since(start))
+			client.recordResult(state, e, b, class, resp.StatusCode >= 500)
+			client.log().Warn(fmt.Sprintf("%v return non 2xx code", chainCallType), "url", url, "statusCode", resp.StatusCode)
+			err = fmt.Errorf("%v return non 2xx code,statusCode:%v", chainCallType, resp.StatusCode)
+			endSpan(span, err)
+			return response.BaseResp{}, err
+		}
+		baseResp, err := client.decodeResp(resp, chainCallType)
+		if err != nil {
+			metrics.RequestDone(info.Method, path, CodeInvalidResponse, time.Since(start))
+			client.recordResult(state, e, b, class, true)
+			span.SetAttributes(Attribute{AttrResultCode, CodeInvalidResponse})
+			endSpan(span, err)
+			return response.BaseResp{}, err
+		}
+		lastCode = baseResp.Code
+		metrics.RequestDone(info.Method, path, lastCode, time.Since(start))
+		client.recordResult(state, e, b, class, !baseResp.Success && strings.HasPrefix(baseResp.Code, "5"))
+		span.SetAttributes(Attribute{AttrResultCode, lastCode})
+		client.log().Info("request and resp", "param", client.logParam(param), "resp", client.logResp(baseResp))
+		if !retryResp(baseResp, fullRetry) {
+			endSpan(span, nil)
+			return baseResp, nil
+		}
+		if baseResp.Code == "202" {
+			// the next attempt takes the new token
+			client.refreshToken(attemptCtx, state, e)
 		} else {
-			if resp.StatusCode >= 300 && resp.StatusCode < 600 {
-				log.WithFields(log.Fields{
-					"req":        req,
-					"statusCode": resp.StatusCode,
-				}).Warnf("%v return non 2xx code", chainCallType)
-				resp.Body.Close()
-				return response.BaseResp{}, fmt.Errorf("%v return non 2xx code,statusCode:%v", chainCallType, resp.StatusCode)
-			} else {
-				body, _ := ioutil.ReadAll(resp.Body)
-				baseResp := response.BaseResp{}
-				err = json.Unmarshal(body, &baseResp)
-				if err != nil {
-					log.WithFields(log.Fields{
-						"body": string(body),
-						"err":  err.Error(),
-					}).Errorf("fail to unmarshal %v", chainCallType)
-					resp.Body.Close()
-					return response.BaseResp{}, fmt.Errorf("fail to unmarshal %v,err:%+v", chainCallType, err)
-				}
-				log.WithFields(log.Fields{
-					"param": param,
-					"resp":  baseResp,
-				}).Info("request and resp")
-				if !baseResp.Success {
-					if baseResp.Code == "202" {
-						client.shake()
-						switch param.(type) {
-						case model.CallRestParam:
-							newParam := param.(model.CallRestParam)
-							newParam.Token = client.RestToken
-							param = newParam
-						case model.CallRestBizParam:
-							newParam := param.(model.CallRestBizParam)
-							newParam.Token = client.RestToken
-							param = newParam
-						}
-					}
-					if baseResp.Code == "202" || strings.HasPrefix(baseResp.Code, "5") {
-						log.WithFields(log.Fields{
-							"restCode": baseResp.Code,
-						}).Warnf("fail to get %v successfully", chainCallType)
-						resp.Body.Close()
-						continue // retry next time
-					}
-				}
-				resp.Body.Close()
-				return baseResp, nil
-			}
+			maybeSent = fmt.Errorf("%v return code %v", chainCallType, baseResp.Code)
 		}
+		client.log().Warn(fmt.Sprintf("fail to get %v successfully", chainCallType), "restCode", baseResp.Code)
+		endSpan(span, nil)
+	}
+	if release != nil {
+		release(false)
+		release = nil
+	}
+	if baseResp, done, err := client.confirmSent(ctx, order, maybeSent); done {
+		return baseResp, err
 	}
 	return response.BaseResp{}, fmt.Errorf("fail to get %v response", chainCallType)
 }
 
+// newRequest returns the request posting param to url.
+func (client *RestClient) newRequest(ctx context.Context, url string, param interface{}, chainCallType string) (*http.Request, error) {
+	jsonStr, err := json.Marshal(&param)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewBuffer(jsonStr))
+	if err != nil {
+		client.log().Error(fmt.Sprintf("fail to new %v request", chainCallType), "url", url, "err", err.Error())
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Add("Content-Type", "application/json;charset=utf-8")
+	client.tracer().Inject(ctx, req.Header)
+	return req, nil
+}
+
+// decodeResp reads the BaseResp of a 2xx resp and closes its body.
+func (client *RestClient) decodeResp(resp *http.Response, chainCallType string) (response.BaseResp, error) {
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	baseResp := response.BaseResp{}
+	if err := json.Unmarshal(body, &baseResp); err != nil {
+		client.log().Error(fmt.Sprintf("fail to unmarshal %v", chainCallType), "body", redacted(string(body)), "err", err.Error())
+		return response.BaseResp{}, fmt.Errorf("fail to unmarshal %v,err:%+v", chainCallType, err)
+	}
+	return baseResp, nil
+}
+
+// retryResp tells whether an attempt answered with baseResp is sent again: after the
+// token expired, or on a 5xx result when fullRetry allows it.
+func retryResp(baseResp response.BaseResp, fullRetry bool) bool {
+	return !baseResp.Success && (baseResp.Code == "202" || strings.HasPrefix(baseResp.Code, "5") && fullRetry)
+}
+
+// refreshToken handshakes again after the token sent to e expired.
+func (client *RestClient) refreshToken(ctx context.Context, state *clientState, e *endpoint) {
+	if state.properties.TokenPerEndpoint {
+		client.shakeEndpoint(ctx, state, e)
+	} else {
+		client.shake(ctx)
+	}
+}
+
+// dialFailed tells whether err of an http request is a failure to connect to the server
+// or the proxy, the server did not get such a request.
+func dialFailed(err error) bool {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	opErr, ok := err.(*net.OpError)
+	return ok && (opErr.Op == "dial" || opErr.Op == "proxyconnect")
+}
+
+// withToken returns param carrying token.
+func withToken(param interface{}, token string) interface{} {
+	switch p := param.(type) {
+	case *model.CallRestParam:
+		p.Token = token
+	case model.CallRestParam:
+		p.Token = token
+		return p
+	case *model.CallRestBizParam:
+		p.Token = token
+	case model.CallRestBizParam:
+		p.Token = token
+		return p
+	}
+	return param
+}
+
 func (client *RestClient) DepositSyncWithTransaction(bizid, orderId, account, tenantId, content, mykmsKeyId string, gas int64) (response.BaseResp, error) {
 	baseResp, err := client.Deposit(bizid, orderId, account, tenantId, content, mykmsKeyId, gas)
 	if err != nil {
-		return response.BaseResp{}, err
+		return response.BaseResp{OrderId: baseResp.OrderId}, err
 	}
 	if !baseResp.Success || baseResp.Code != "200" {
-		return response.BaseResp{}, fmt.Errorf("deposit failed,code:%+v err msg:%+v", baseResp.Code, baseResp.Data)
+		return response.BaseResp{OrderId: baseResp.OrderId}, fmt.Errorf("deposit failed,code:%+v err msg:%+v", baseResp.Code, baseResp.Data)
 	}
-	return client.MultipleQueryTransaction(bizid, baseResp.Data)
+	transactionResp, err := client.MultipleQueryTransaction(bizid, baseResp.Data)
+	transactionResp.OrderId = baseResp.OrderId
+	return transactionResp, err
 }
 
 func (client *RestClient) CallSolcContractSyncWithReceipt(abi abi.ABI, bizid, orderId, account, tenantId, kmsId, contractName, methodSignature, inputParamListStr, outTypes string, gas int64, respStruct interface{}) (response.BaseResp, error) {
+	return client.callSolcContract(abi, bizid, orderId, account, tenantId, kmsId, contractName, methodSignature, inputParamListStr, outTypes, gas, respStruct, false)
+}
+
+// CallSolcContractLocal works like CallSolcContractSyncWithReceipt but executes the call locally
+// on the node, which answers with the receipt, so no transaction is sent. For reads.
+func (client *RestClient) CallSolcContractLocal(abi abi.ABI, bizid, orderId, account, tenantId, kmsId, contractName, methodSignature, inputParamListStr, outTypes string, gas int64, respStruct interface{}) (response.BaseResp, error) {
+	return client.callSolcContract(abi, bizid, orderId, account, tenantId, kmsId, contractName, methodSignature, inputParamListStr, outTypes, gas, respStruct, true)
+}
+
+func (client *RestClient) callSolcContract(abi abi.ABI, bizid, orderId, account, tenantId, kmsId, contractName, methodSignature, inputParamListStr, outTypes string, gas int64, respStruct interface{}, local bool) (response.BaseResp, error) {
+	orderId = client.orderIdOf(orderId)
+	decodedOutput, err := client.callContractForOutput(&abi, bizid, orderId, account, tenantId, kmsId, contractName, methodSignature, inputParamListStr, outTypes, gas, local)
+	if err != nil {
+		return response.BaseResp{OrderId: orderId}, err
+	}
+	err = abi.Unpack(respStruct, methodSignature, decodedOutput)
+	if err != nil {
+		return response.BaseResp{OrderId: orderId}, err
+	}
+	jsonStr, err := json.Marshal(respStruct)
+	if err != nil {
+		return response.BaseResp{OrderId: orderId}, err
+	}
+	return response.BaseResp{Success: true, Code: "200", Data: string(jsonStr), OrderId: orderId}, nil
+}
+
+// CallContractDynamic works like CallSolcContractSyncWithReceipt but needs no Go struct, the outputs
+// are decoded with the abi into a map and returned as canonical json in the Data of the response.
+func (client *RestClient) CallContractDynamic(abi abi.ABI, bizid, orderId, account, tenantId, kmsId, contractName, methodSignature, inputParamListStr, outTypes string, gas int64) (response.BaseResp, map[string]interface{}, error) {
+	orderId = client.orderIdOf(orderId)
+	decodedOutput, err := client.callContractForOutput(&abi, bizid, orderId, account, tenantId, kmsId, contractName, methodSignature, inputParamListStr, outTypes, gas, false)
+	if err != nil {
+		return response.BaseResp{OrderId: orderId}, nil, err
+	}
+	outputs, err := abi.UnpackDynamic(methodSignature, decodedOutput)
+	if err != nil {
+		return response.BaseResp{OrderId: orderId}, nil, err
+	}
+	jsonStr, err := json.Marshal(outputs)
+	if err != nil {
+		return response.BaseResp{OrderId: orderId}, nil, err
+	}
+	return response.BaseResp{Success: true, Code: "200", Data: string(jsonStr), OrderId: orderId}, outputs, nil
+}
+
+// callContractForOutput calls the contract asynchronously, waits for the receipt and returns its decoded output.
+// A local call is answered with the receipt at once.
+func (client *RestClient) callContractForOutput(contractABI *abi.ABI, bizid, orderId, account, tenantId, kmsId, contractName, methodSignature, inputParamListStr, outTypes string, gas int64, local bool) ([]byte, error) {
+	if client.callValidation {
+		if err := bind.ValidateCall(*contractABI, methodSignature, inputParamListStr, outTypes); err != nil {
+			return nil, err
+		}
+	}
 	callRestBizParam := model.CallRestBizParam{
 		BaseParam: model.BaseParam{
-			AccessId: client.RestClientProperties.AccessId,
+			AccessId: client.Properties().AccessId,
 			BizId:    bizid,
 			Method:   model.CALLCONTRACTBIZASYNC,
 		},
-		OrderId:           orderId,
-		Account:           account,
-		TenantId:          tenantId,
-		ContractName:      contractName,
-		MethodSignature:   methodSignature,
-		InputParamListStr: inputParamListStr,
-		OutTypes:          outTypes,
-		MykmsKeyId:        kmsId,
-		Gas:               gas, // 0表示不受限
+		OrderId:            orderId,
+		Account:            account,
+		TenantId:           tenantId,
+		ContractName:       contractName,
+		MethodSignature:    methodSignature,
+		InputParamListStr:  inputParamListStr,
+		OutTypes:           outTypes,
+		MykmsKeyId:         kmsId,
+		IsLocalTransaction: local,
+		Gas:                gas, // 0表示不受限
 	}
 	callResp, err := client.ChainCallForBiz(callRestBizParam)
 	if err != nil {
-		return response.BaseResp{}, err
+		return nil, err
 	}
 	if callResp.Success && callResp.Code == "200" {
-		baseResp, err := client.MultipleQueryReceipt(bizid, callResp.Data)
-		if err != nil {
-			return response.BaseResp{}, err
+		hash, receiptData := "", callResp.Data
+		if !local {
+			hash = callResp.Data
+			baseResp, err := client.MultipleQueryReceipt(bizid, hash)
+			if err != nil {
+				return nil, err
+			}
+			if !baseResp.Success {
+				return nil, fmt.Errorf("no succ receipt,hash:%v code:%v data:%v", hash, baseResp.Code, baseResp.Data)
+			}
+			receiptData = baseResp.Data
 		}
-		transactionReceipt := mychain.GetDefaultTransactionReceipt()
-		err = json.Unmarshal([]byte(baseResp.Data), &transactionReceipt)
+		transactionReceipt, err := ParseReceipt(contractABI, hash, receiptData)
 		if err != nil {
-			return response.BaseResp{}, err
+			return nil, err
 		}
 		output := make([]string, 0)
 		err = json.Unmarshal([]byte(outTypes), &output)
 		if err != nil {
-			return response.BaseResp{}, err
+			return nil, err
 		}
 		if len(output) > 0 {
 			if transactionReceipt.Output == "" && output[0] != model.VOID {
-				return response.BaseResp{}, fmt.Errorf("function has no any output")
-			}
-			decodedOutput, err := base64.StdEncoding.DecodeString(transactionReceipt.Output)
-			if err != nil {
-				return response.BaseResp{}, err
-			}
-			strParts := strings.Split(methodSignature, "(")
-			methodName := strParts[0]
-			err = abi.Unpack(respStruct, methodName, decodedOutput)
-			if err != nil {
-				return response.BaseResp{}, err
-			}
-			jsonStr, err := json.Marshal(respStruct)
-			if err != nil {
-				return response.BaseResp{}, err
+				return nil, fmt.Errorf("function has no any output")
 			}
-			return response.BaseResp{Success: true, Code: "200", Data: string(jsonStr)}, nil
+			return base64.StdEncoding.DecodeString(transactionReceipt.Output)
 		}
 	}
-	return response.BaseResp{}, fmt.Errorf("no succ call contract resp,Success:%v Code:%v", callResp.Success, callResp.Code)
+	return nil, fmt.Errorf("no succ call contract resp,Success:%v Code:%v", callResp.Success, callResp.Code)
 }
 
 func (client *RestClient) QueryAccount(bizid, account string) (response.BaseResp, error) {
@@ -381,7 +708,7 @@ func (client *RestClient) QueryAccount(bizid, account string) (response.BaseResp
 func (client *RestClient) CreateAccountWithKmsId(bizid, orderId, account, tenantId, kmsId string) (response.BaseResp, error) {
 	callRestBizParam := model.CallRestBizParam{
 		BaseParam: model.BaseParam{
-			AccessId: client.RestClientProperties.AccessId,
+			AccessId: client.Properties().AccessId,
 			BizId:    bizid,
 			Method:   model.CREATEACCOUNT,
 		},
@@ -396,7 +723,7 @@ func (client *RestClient) CreateAccountWithKmsId(bizid, orderId, account, tenant
 func (client *RestClient) CreateAccount(bizid, orderId, account, kmsId, tenantId, newAccount, newAccountKmsId string) (response.BaseResp, error) {
 	callRestBizParam := model.CallRestBizParam{
 		BaseParam: model.BaseParam{
-			AccessId: client.RestClientProperties.AccessId,
+			AccessId: client.Properties().AccessId,
 			BizId:    bizid,
 			Method:   model.TENANTCREATEACCOUNT,
 		},
@@ -411,9 +738,14 @@ func (client *RestClient) CreateAccount(bizid, orderId, account, kmsId, tenantId
 }
 
 func (client *RestClient) CallContract(bizid, orderId, account, tenantId, contractName, methodSignature, inputParamListStr, outTypes, kmsId string, isLocal bool, gas int64) (response.BaseResp, error) {
+	return client.CallContractContext(context.Background(), bizid, orderId, account, tenantId, contractName, methodSignature, inputParamListStr, outTypes, kmsId, isLocal, gas)
+}
+
+// CallContractContext is CallContract with a context, see ChainCallForBizContext.
+func (client *RestClient) CallContractContext(ctx context.Context, bizid, orderId, account, tenantId, contractName, methodSignature, inputParamListStr, outTypes, kmsId string, isLocal bool, gas int64) (response.BaseResp, error) {
 	callRestBizParam := model.CallRestBizParam{
 		BaseParam: model.BaseParam{
-			AccessId: client.RestClientProperties.AccessId,
+			AccessId: client.Properties().AccessId,
 			BizId:    bizid,
 			Method:   model.CALLCONTRACTBIZASYNC,
 		},
@@ -428,14 +760,38 @@ func (client *RestClient) CallContract(bizid, orderId, account, tenantId, contra
 		IsLocalTransaction: isLocal,
 		Gas:                gas, // 0表示不受限
 	}
-	return client.ChainCallForBiz(callRestBizParam)
+	return client.ChainCallForBizContext(ctx, callRestBizParam)
+}
+
+// WithCallValidation makes CallSolcContractSyncWithReceipt, CallSolcContractLocal and
+// CallContractDynamic validate calls against their abi before sending them, like
+// CallContractWithABI always does. The check is stricter than the server, inputs must
+// be in the form bind.EncodeCall produces, so it is off by default.
+func WithCallValidation() Option {
+	return func(options *clientOptions) {
+		options.callValidation = true
+	}
+}
+
+// CallContractWithABI works like CallContract but first validates the call against contractABI
+// locally, see bind.ValidateCall, so malformed calls fail before they reach the chain.
+func (client *RestClient) CallContractWithABI(contractABI abi.ABI, bizid, orderId, account, tenantId, contractName, methodSignature, inputParamListStr, outTypes, kmsId string, isLocal bool, gas int64) (response.BaseResp, error) {
+	if err := bind.ValidateCall(contractABI, methodSignature, inputParamListStr, outTypes); err != nil {
+		return response.BaseResp{}, err
+	}
+	return client.CallContract(bizid, orderId, account, tenantId, contractName, methodSignature, inputParamListStr, outTypes, kmsId, isLocal, gas)
 }
 
 func (client *RestClient) DeployContract(bizid, orderId, account, tenantId, kmsId, contractName, contractCode string, gas int64) (response.BaseResp, error) {
+	return client.DeployContractContext(context.Background(), bizid, orderId, account, tenantId, kmsId, contractName, contractCode, gas)
+}
+
+// DeployContractContext is DeployContract with a context, see ChainCallForBizContext.
+func (client *RestClient) DeployContractContext(ctx context.Context, bizid, orderId, account, tenantId, kmsId, contractName, contractCode string, gas int64) (response.BaseResp, error) {
 	//deploy contract
 	callRestBizParam := model.CallRestBizParam{
 		BaseParam: model.BaseParam{
-			AccessId: client.RestClientProperties.AccessId,
+			AccessId: client.Properties().AccessId,
 			BizId:    bizid,
 			Method:   model.DEPLOYCONTRACTFORBIZ,
 		},
@@ -447,13 +803,18 @@ func (client *RestClient) DeployContract(bizid, orderId, account, tenantId, kmsI
 		ContractCode: contractCode,
 		Gas:          gas, // 0表示不受限
 	}
-	return client.ChainCallForBiz(callRestBizParam)
+	return client.ChainCallForBizContext(ctx, callRestBizParam)
 }
 
 func (client *RestClient) Deposit(bizid, orderId, account, tenantId, content, mykmsKeyId string, gas int64) (response.BaseResp, error) {
+	return client.DepositContext(context.Background(), bizid, orderId, account, tenantId, content, mykmsKeyId, gas)
+}
+
+// DepositContext is Deposit with a context, see ChainCallForBizContext.
+func (client *RestClient) DepositContext(ctx context.Context, bizid, orderId, account, tenantId, content, mykmsKeyId string, gas int64) (response.BaseResp, error) {
 	callRestBizParam := model.CallRestBizParam{
 		BaseParam: model.BaseParam{
-			AccessId: client.RestClientProperties.AccessId,
+			AccessId: client.Properties().AccessId,
 			BizId:    bizid,
 			Method:   model.DEPOSIT,
 		},
@@ -464,13 +825,13 @@ func (client *RestClient) Deposit(bizid, orderId, account, tenantId, content, my
 		TenantId:   tenantId,
 		Gas:        gas, // 0表示不受限
 	}
-	return client.ChainCallForBiz(callRestBizParam)
+	return client.ChainCallForBizContext(ctx, callRestBizParam)
 }
 
 func (client *RestClient) QueryReceipt(bizid, hash string) (response.BaseResp, error) {
 	callRestBizParam := model.CallRestBizParam{
 		BaseParam: model.BaseParam{
-			AccessId: client.RestClientProperties.AccessId,
+			AccessId: client.Properties().AccessId,
 			BizId:    bizid,
 			Hash:     hash,
 			Method:   model.QUERYRECEIPT,
@@ -482,7 +843,7 @@ func (client *RestClient) QueryReceipt(bizid, hash string) (response.BaseResp, e
 func (client *RestClient) QueryTransaction(bizid, hash string) (response.BaseResp, error) {
 	callRestBizParam := model.CallRestBizParam{
 		BaseParam: model.BaseParam{
-			AccessId: client.RestClientProperties.AccessId,
+			AccessId: client.Properties().AccessId,
 			BizId:    bizid,
 			Hash:     hash,
 			Method:   model.QUERYTRANSACTION,
@@ -492,34 +853,50 @@ func (client *RestClient) QueryTransaction(bizid, hash string) (response.BaseRes
 }
 
 func (client *RestClient) MultipleQueryReceipt(bizid, hash string) (response.BaseResp, error) {
-	var baseResp response.BaseResp
-	var err error
-	for i := 0; i < client.RestClientProperties.RetryMaxAttempts; i++ {
-		baseResp, err = client.ChainCall(hash, bizid, "", model.QUERYRECEIPT)
-		if err != nil {
-			return baseResp, err
-		} else if !baseResp.Success && (baseResp.Code == model.ServiceQueryNoResult ||
-			baseResp.Code == model.ServiceTxWaitingVerify ||
-			baseResp.Code == model.ServiceTxWaitingExecute) {
-			continue
-		}
-		return baseResp, err
-	}
-	return baseResp, err
+	return client.waitForResult(context.Background(), bizid, hash, model.QUERYRECEIPT)
+}
+
+// MultipleQueryReceiptContext is MultipleQueryReceipt with a context, see
+// ChainCallForBizContext.
+func (client *RestClient) MultipleQueryReceiptContext(ctx context.Context, bizid, hash string) (response.BaseResp, error) {
+	return client.waitForResult(ctx, bizid, hash, model.QUERYRECEIPT)
 }
 
 func (client *RestClient) MultipleQueryTransaction(bizid, hash string) (response.BaseResp, error) {
-	var baseResp response.BaseResp
-	var err error
-	for i := 0; i < client.RestClientProperties.RetryMaxAttempts; i++ {
-		baseResp, err = client.ChainCall(hash, bizid, "", model.QUERYTRANSACTION)
+	return client.waitForResult(context.Background(), bizid, hash, model.QUERYTRANSACTION)
+}
+
+// MultipleQueryTransactionContext is MultipleQueryTransaction with a context, see
+// ChainCallForBizContext.
+func (client *RestClient) MultipleQueryTransactionContext(ctx context.Context, bizid, hash string) (response.BaseResp, error) {
+	return client.waitForResult(ctx, bizid, hash, model.QUERYTRANSACTION)
+}
+
+// waitForResult queries hash with method until the transaction is no longer pending,
+// at most RetryMaxAttempts times.
+func (client *RestClient) waitForResult(ctx context.Context, bizid, hash string, method model.Method) (baseResp response.BaseResp, err error) {
+	start := time.Now()
+	state := ReceiptPending
+	ctx, span := client.tracer().Start(ctx, SpanWait, Attribute{AttrBizID, bizid}, Attribute{AttrMethod, string(method)}, Attribute{AttrTxHash, hash})
+	defer func() {
+		client.metrics().ReceiptWaited(method, state, time.Since(start))
+		span.SetAttributes(Attribute{AttrWaitState, state})
+		endSpan(span, err)
+	}()
+	for i := 0; i < client.Properties().RetryMaxAttempts; i++ {
+		baseResp, err = client.ChainCallContext(ctx, hash, bizid, "", method)
 		if err != nil {
+			state = ReceiptError
 			return baseResp, err
 		} else if !baseResp.Success && (baseResp.Code == model.ServiceQueryNoResult ||
 			baseResp.Code == model.ServiceTxWaitingVerify ||
 			baseResp.Code == model.ServiceTxWaitingExecute) {
 			continue
 		}
+		state = ReceiptFailed
+		if baseResp.Success {
+			state = ReceiptSuccess
+		}
 		return baseResp, err
 	}
 	return baseResp, err
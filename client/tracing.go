@@ -0,0 +1,93 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Span names of a RestClient. The span of a chain call is named SpanChainCall
+// followed by the method, e.g. "restclient DEPOSIT".
+const (
+	SpanChainCall = "restclient "
+	SpanHandshake = "restclient handshake"
+	SpanAttempt   = "restclient attempt"
+	SpanWait      = "restclient wait"
+)
+
+// Attribute keys set on the spans of a RestClient.
+const (
+	AttrBizID      = "restclient.bizid"
+	AttrMethod     = "restclient.method"
+	AttrOrderID    = "restclient.order_id"
+	AttrTxHash     = "restclient.tx_hash"
+	AttrResultCode = "restclient.result_code"
+	// AttrAttempt is the number of an attempt, starting at 1.
+	AttrAttempt = "restclient.attempt"
+	// AttrEndpoint is the endpoint an attempt went to.
+	AttrEndpoint = "restclient.endpoint"
+	// AttrHTTPStatus is the http status of an attempt.
+	AttrHTTPStatus = "http.status_code"
+	// AttrWaitState is one of the Receipt states a wait ended in.
+	AttrWaitState = "restclient.wait_state"
+)
+
+// Attribute is a key and a string, int or bool value of a span.
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Tracer starts the spans of a RestClient. It is implemented on top of a tracing
+// library, e.g. by the contrib/otel module for OpenTelemetry, so that this module
+// does not depend on one.
+type Tracer interface {
+	// Start starts a span as a child of the span in ctx and returns a context
+	// holding the new span.
+	Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span)
+	// Inject writes the trace context of ctx into the headers of an outgoing request.
+	Inject(ctx context.Context, header http.Header)
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	SetAttributes(attributes ...Attribute)
+	// RecordError marks the span as failed with err.
+	RecordError(err error)
+	End()
+}
+
+// WithTracer makes the client trace its calls with tracer. The calls taking a
+// context, e.g. DepositContext, start their spans as children of the span in it.
+func WithTracer(tracer Tracer) Option {
+	return func(options *clientOptions) {
+		options.tracer = tracer
+	}
+}
+
+type nopTracer struct{}
+
+func (nopTracer) Start(ctx context.Context, _ string, _ ...Attribute) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+func (nopTracer) Inject(context.Context, http.Header) {}
+
+type nopSpan struct{}
+
+func (nopSpan) SetAttributes(...Attribute) {}
+func (nopSpan) RecordError(error)          {}
+func (nopSpan) End()                       {}
+
+func (client *RestClient) tracer() Tracer {
+	if client.tracerSink == nil {
+		return nopTracer{}
+	}
+	return client.tracerSink
+}
+
+// endSpan records err, if any, and ends span.
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
@@ -0,0 +1,174 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/oldercn/restclient-go-sdk/client/config"
+	"github.com/oldercn/restclient-go-sdk/model"
+	"github.com/oldercn/restclient-go-sdk/response"
+	"github.com/stretchr/testify/require"
+)
+
+type recordedSpan struct {
+	id, parent int
+	name       string
+	attributes map[string]interface{}
+	err        error
+	ended      bool
+}
+
+func (s *recordedSpan) SetAttributes(attributes ...Attribute) {
+	for _, attribute := range attributes {
+		s.attributes[attribute.Key] = attribute.Value
+	}
+}
+
+func (s *recordedSpan) RecordError(err error) { s.err = err }
+func (s *recordedSpan) End()                  { s.ended = true }
+
+type spanKey struct{}
+
+// recordingTracer keeps every span and injects the id of the current one as traceparent.
+type recordingTracer struct {
+	lock  sync.Mutex
+	spans []*recordedSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string, attributes ...Attribute) (context.Context, Span) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	span := &recordedSpan{id: len(t.spans) + 1, name: name, attributes: make(map[string]interface{})}
+	if parent, ok := ctx.Value(spanKey{}).(*recordedSpan); ok {
+		span.parent = parent.id
+	}
+	span.SetAttributes(attributes...)
+	t.spans = append(t.spans, span)
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+func (t *recordingTracer) Inject(ctx context.Context, header http.Header) {
+	if span, ok := ctx.Value(spanKey{}).(*recordedSpan); ok {
+		header.Set("traceparent", fmt.Sprint(span.id))
+	}
+}
+
+func (t *recordingTracer) named(name string) []*recordedSpan {
+	var spans []*recordedSpan
+	for _, span := range t.spans {
+		if span.name == name {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+func TestTracing(t *testing.T) {
+	var calls int32
+	var traceparents []string
+	client, closeServer := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			writeResp(w, response.BaseResp{Success: false, Code: "500"})
+		case 3:
+			writeResp(w, response.BaseResp{Success: false, Code: model.ServiceTxWaitingVerify})
+		default:
+			writeResp(w, response.BaseResp{Success: true, Code: "200", Data: "abcd"})
+		}
+	})
+	defer closeServer()
+	tracer := &recordingTracer{}
+	client.tracerSink = tracer
+	// the deposit is sent again once the lookup confirms the failed attempt sent nothing
+	client.orderLookup = func(ctx context.Context, bizId, orderId string) (string, error) {
+		return "", nil
+	}
+
+	ctx, parent := tracer.Start(context.Background(), "caller")
+	_, err := client.DepositContext(ctx, "biz", "order-1", "account", "tenant", "content", "kms", 0)
+	require.NoError(t, err)
+	parent.End()
+
+	deposit := tracer.named(SpanChainCall + string(model.DEPOSIT))
+	require.Len(t, deposit, 1)
+	require.Equal(t, 1, deposit[0].parent)
+	require.True(t, deposit[0].ended)
+	require.Equal(t, map[string]interface{}{
+		AttrBizID: "biz", AttrMethod: "DEPOSIT", AttrOrderID: "order-1", AttrResultCode: "200", AttrTxHash: "abcd",
+	}, deposit[0].attributes)
+
+	// every attempt is a span of its own and its id goes out in the headers
+	attempts := tracer.named(SpanAttempt)
+	require.Len(t, attempts, 2)
+	for i, attempt := range attempts {
+		require.Equal(t, deposit[0].id, attempt.parent)
+		require.Equal(t, i+1, attempt.attributes[AttrAttempt])
+		require.Equal(t, 200, attempt.attributes[AttrHTTPStatus])
+		require.Equal(t, fmt.Sprint(attempt.id), traceparents[i])
+		require.True(t, attempt.ended)
+	}
+	require.Equal(t, "500", attempts[0].attributes[AttrResultCode])
+
+	// the polls of a wait are children of its span
+	_, err = client.MultipleQueryReceiptContext(ctx, "biz", "abcd")
+	require.NoError(t, err)
+	wait := tracer.named(SpanWait)
+	require.Len(t, wait, 1)
+	require.Equal(t, ReceiptSuccess, wait[0].attributes[AttrWaitState])
+	require.Equal(t, "abcd", wait[0].attributes[AttrTxHash])
+	queries := tracer.named(SpanChainCall + string(model.QUERYRECEIPT))
+	require.Len(t, queries, 2)
+	for _, query := range queries {
+		require.Equal(t, wait[0].id, query.parent)
+		require.Equal(t, "abcd", query.attributes[AttrTxHash])
+	}
+	require.Equal(t, model.ServiceTxWaitingVerify, queries[0].attributes[AttrResultCode])
+
+	// a failed call records its error
+	_, err = client.DepositContext(ctx, "", "order-2", "account", "tenant", "content", "kms", 0)
+	require.Error(t, err)
+	failed := tracer.named(SpanChainCall + string(model.DEPOSIT))[1]
+	require.Equal(t, err, failed.err)
+	require.True(t, failed.ended)
+}
+
+func TestTracingHandshake(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restclient-tracing")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	server := newHandshakeServer()
+	defer server.Close()
+
+	tracer := &recordingTracer{}
+	properties := config.RestClientProperties{RestUrl: server.URL, AccessId: "access", AccessSecret: writeTestKey(t, dir)}
+	_, err = NewRestClientFromProperties(properties, WithTracer(tracer))
+	require.NoError(t, err)
+	handshakes := tracer.named(SpanHandshake)
+	require.Len(t, handshakes, 1)
+	require.True(t, handshakes[0].ended)
+	require.NoError(t, handshakes[0].err)
+
+	properties.RestUrl = "http://" + server.Listener.Addr().String() + "/missing"
+	server.Close()
+	_, err = NewRestClientFromProperties(properties, WithTracer(tracer))
+	require.Error(t, err)
+	require.Error(t, tracer.named(SpanHandshake)[1].err)
+}
+
+func TestCanceledContext(t *testing.T) {
+	client, closeServer := newTestClient(func(w http.ResponseWriter, r *http.Request) {
+		writeResp(w, response.BaseResp{Success: true, Code: "200"})
+	})
+	defer closeServer()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := client.MultipleQueryReceiptContext(ctx, "biz", "abcd")
+	require.Error(t, err)
+}
@@ -0,0 +1,176 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/oldercn/restclient-go-sdk/client/config"
+	"github.com/oldercn/restclient-go-sdk/utils"
+)
+
+// DefaultCertExpiryWarning is how long before a certificate of the TLS properties
+// expires the client starts to log warnings about it.
+var DefaultCertExpiryWarning = 30 * 24 * time.Hour
+
+// WithTLSConfig makes the client start from tlsConfig, e.g. for a custom
+// VerifyPeerCertificate. The TLS properties are applied to a copy of it.
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(options *clientOptions) {
+		options.tlsConfig = tlsConfig
+	}
+}
+
+// WithProxy makes the client choose the proxy of a request with proxy, e.g.
+// http.ProxyFromEnvironment. A ProxyUrl in the properties takes precedence.
+func WithProxy(proxy func(*http.Request) (*url.URL, error)) Option {
+	return func(options *clientOptions) {
+		options.proxy = proxy
+	}
+}
+
+// newHTTPClient returns an http client for restClientProperties and the certificates
+// of its TLS properties.
+func (client *RestClient) newHTTPClient(restClientProperties config.RestClientProperties) (*http.Client, []*x509.Certificate, error) {
+	maxIdleConns := DefaultMaxIdleConns
+	if restClientProperties.MaxIdleConns != 0 {
+		maxIdleConns = restClientProperties.MaxIdleConns
+	}
+	idleConnTimeout := DefaultIdleConnTimeout
+	if restClientProperties.IdleConnTimeout != 0 {
+		idleConnTimeout = restClientProperties.IdleConnTimeout
+	}
+	tlsConfig, certs, err := client.newTLSConfig(restClientProperties)
+	if err != nil {
+		return nil, nil, err
+	}
+	tr := &http.Transport{
+		MaxIdleConns:    maxIdleConns,
+		IdleConnTimeout: time.Duration(idleConnTimeout) * time.Second,
+		TLSClientConfig: tlsConfig,
+		Proxy:           client.proxy,
+	}
+	if restClientProperties.ProxyUrl != "" {
+		proxyURL, err := url.Parse(restClientProperties.ProxyUrl)
+		if err != nil {
+			return nil, nil, err
+		}
+		// socks5 proxies are supported by http.Transport as well
+		tr.Proxy = http.ProxyURL(proxyURL)
+	}
+	return &http.Client{Transport: wrapTransport(tr, client.transportInterceptors)}, certs, nil
+}
+
+// newTLSConfig returns the tls config of restClientProperties.
+func (client *RestClient) newTLSConfig(restClientProperties config.RestClientProperties) (*tls.Config, []*x509.Certificate, error) {
+	tlsConfig := &tls.Config{}
+	if client.tlsConfig != nil {
+		tlsConfig = client.tlsConfig.Clone()
+	}
+	var certs []*x509.Certificate
+	if restClientProperties.TLSCAFile != "" {
+		pool, caCerts, err := utils.LoadCertPool(restClientProperties.TLSCAFile)
+		if err != nil {
+			client.log().Error("fail to load TLSCAFile", "path", restClientProperties.TLSCAFile, "err", err.Error())
+			return nil, nil, err
+		}
+		tlsConfig.RootCAs = pool
+		certs = append(certs, caCerts...)
+	}
+	if restClientProperties.TLSCertFile != "" {
+		keyPair, chain, err := utils.LoadKeyPair(restClientProperties.TLSCertFile, restClientProperties.TLSKeyFile)
+		if err != nil {
+			client.log().Error("fail to load TLSCertFile", "path", restClientProperties.TLSCertFile, "err", err.Error())
+			return nil, nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{keyPair}
+		for _, cert := range chain {
+			if !containsCert(certs, cert) {
+				certs = append(certs, cert)
+			}
+		}
+	}
+	minVersion, err := config.ParseTLSVersion(restClientProperties.TLSMinVersion)
+	if err != nil {
+		return nil, nil, err
+	}
+	if minVersion != 0 {
+		tlsConfig.MinVersion = minVersion
+	} else if tlsConfig.MinVersion == 0 {
+		// older go versions accept TLS 1.0 by default
+		tlsConfig.MinVersion = tls.VersionTLS12
+	}
+	if restClientProperties.TLSServerName != "" {
+		tlsConfig.ServerName = restClientProperties.TLSServerName
+	}
+	return tlsConfig, certs, nil
+}
+
+// warnExpiringCerts logs a warning for every certificate of certs that expires within
+// DefaultCertExpiryWarning.
+func (client *RestClient) warnExpiringCerts(certs []*x509.Certificate) {
+	for _, cert := range utils.ExpiringCertificates(certs, time.Now(), DefaultCertExpiryWarning) {
+		client.log().Warn("certificate expires soon", "subject", cert.Subject.String(), "notAfter", cert.NotAfter.Format(time.RFC3339))
+	}
+}
+
+func containsCert(certs []*x509.Certificate, cert *x509.Certificate) bool {
+	for _, c := range certs {
+		if c.Equal(cert) {
+			return true
+		}
+	}
+	return false
+}
+
+// fileStamp tells a file rewritten in place apart, like the signers cached by utils.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// tlsFiles stamps the TLSCAFile, TLSCertFile and TLSKeyFile of a state, missing files
+// stamp zero.
+type tlsFiles [3]fileStamp
+
+func stampTLSFiles(restClientProperties config.RestClientProperties) tlsFiles {
+	var files tlsFiles
+	for i, path := range []string{restClientProperties.TLSCAFile, restClientProperties.TLSCertFile, restClientProperties.TLSKeyFile} {
+		if path == "" {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil {
+			files[i] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return files
+}
+
+func (files tlsFiles) changed(other tlsFiles) bool {
+	for i := range files {
+		if !files[i].modTime.Equal(other[i].modTime) || files[i].size != other[i].size {
+			return true
+		}
+	}
+	return false
+}
+
+// transportChanged tells whether the http client of old has to be replaced for next,
+// whose TLS files are stamped files. Certificates rotated in place replace it too.
+func transportChanged(old *clientState, next config.RestClientProperties, files tlsFiles) bool {
+	return old.tlsFiles.changed(files) || transportPropertiesChanged(old.properties, next)
+}
+
+func transportPropertiesChanged(old, next config.RestClientProperties) bool {
+	return old.MaxIdleConns != next.MaxIdleConns ||
+		old.IdleConnTimeout != next.IdleConnTimeout ||
+		old.TLSCAFile != next.TLSCAFile ||
+		old.TLSCertFile != next.TLSCertFile ||
+		old.TLSKeyFile != next.TLSKeyFile ||
+		old.TLSMinVersion != next.TLSMinVersion ||
+		old.TLSServerName != next.TLSServerName ||
+		old.ProxyUrl != next.ProxyUrl
+}
@@ -0,0 +1,214 @@
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oldercn/restclient-go-sdk/client/config"
+	"github.com/oldercn/restclient-go-sdk/response"
+	"github.com/stretchr/testify/require"
+)
+
+// testPKI issues certificates for a TLS test server and its clients.
+type testPKI struct {
+	t   *testing.T
+	dir string
+	ca  *x509.Certificate
+	key *ecdsa.PrivateKey
+}
+
+func newTestPKI(t *testing.T, dir string) *testPKI {
+	pki := &testPKI{t: t, dir: dir}
+	pki.ca, pki.key = pki.issue("ca", 365*24*time.Hour, func(template *x509.Certificate) {
+		template.IsCA = true
+		template.KeyUsage = x509.KeyUsageCertSign
+	})
+	return pki
+}
+
+func (pki *testPKI) issue(name string, validity time.Duration, customize func(*x509.Certificate)) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(pki.t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(validity),
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+	}
+	customize(template)
+	parent, parentKey := pki.ca, pki.key
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	require.NoError(pki.t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(pki.t, err)
+	return cert, key
+}
+
+// write writes cert and the ca in one file and key in another and returns their paths.
+func (pki *testPKI) write(name string, cert *x509.Certificate, key *ecdsa.PrivateKey) (string, string) {
+	certFile := filepath.Join(pki.dir, name+".pem")
+	chain := append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: pki.ca.Raw})...)
+	require.NoError(pki.t, ioutil.WriteFile(certFile, chain, 0600))
+	der, err := x509.MarshalECPrivateKey(key)
+	require.NoError(pki.t, err)
+	keyFile := filepath.Join(pki.dir, name+".key")
+	require.NoError(pki.t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600))
+	return certFile, keyFile
+}
+
+func shakeHandler(w http.ResponseWriter, r *http.Request) {
+	writeResp(w, response.BaseResp{Success: true, Code: "200", Data: "token-" + r.Host})
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restclient-tls")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	pki := newTestPKI(t, dir)
+	caFile, _ := pki.write("ca", pki.ca, pki.key)
+
+	serverCert, serverKey := pki.issue("rest", time.Hour, func(template *x509.Certificate) {
+		template.DNSNames = []string{"rest.internal"}
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	})
+	clientUsage := func(template *x509.Certificate) {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	}
+	clientCert, clientKey := pki.issue("client", 90*24*time.Hour, clientUsage)
+	clientCertFile, clientKeyFile := pki.write("client", clientCert, clientKey)
+	expiringCert, expiringKey := pki.issue("expiring", 24*time.Hour, clientUsage)
+	expiringCertFile, expiringKeyFile := pki.write("expiring", expiringCert, expiringKey)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(shakeHandler))
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(pki.ca)
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{serverCert.Raw}, PrivateKey: serverKey}},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+		MaxVersion:   tls.VersionTLS12,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	properties := config.RestClientProperties{
+		RestUrl:       server.URL,
+		AccessId:      "access",
+		AccessSecret:  writeTestKey(t, dir),
+		TLSCAFile:     caFile,
+		TLSCertFile:   clientCertFile,
+		TLSKeyFile:    clientKeyFile,
+		TLSServerName: "rest.internal",
+	}
+	logger := &recordingLogger{}
+	client, err := NewRestClientFromProperties(properties, WithLogger(logger))
+	require.NoError(t, err)
+	require.NotContains(t, logger.String(), "certificate expires soon")
+
+	// the server certificate is not valid for the ip of the server
+	noServerName := properties
+	noServerName.TLSServerName = ""
+	_, err = NewRestClientFromProperties(noServerName)
+	require.Error(t, err)
+	// the server wants a client certificate
+	noClientCert := properties
+	noClientCert.TLSCertFile, noClientCert.TLSKeyFile = "", ""
+	_, err = NewRestClientFromProperties(noClientCert)
+	require.Error(t, err)
+	// the server speaks TLS 1.2 at most
+	tls13 := properties
+	tls13.TLSMinVersion = "1.3"
+	_, err = NewRestClientFromProperties(tls13)
+	require.Error(t, err)
+
+	// a certificate close to expiry is warned about
+	properties.TLSCertFile, properties.TLSKeyFile = expiringCertFile, expiringKeyFile
+	require.NoError(t, client.Reload(properties))
+	require.Contains(t, logger.String(), "warn certificate expires soon [subject CN=expiring")
+
+	// so is one rotated in place under the same path
+	properties.TLSCertFile, properties.TLSKeyFile = clientCertFile, clientKeyFile
+	require.NoError(t, client.Reload(properties))
+	rotatedCert, rotatedKey := pki.issue("rotated", 24*time.Hour, clientUsage)
+	pki.write("client", rotatedCert, rotatedKey)
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(clientCertFile, later, later))
+	require.NoError(t, client.Reload(properties))
+	require.Contains(t, logger.String(), "warn certificate expires soon [subject CN=rotated")
+
+	properties.TLSCAFile = clientKeyFile
+	require.Error(t, client.Reload(properties))
+}
+
+func TestTLSMinVersion(t *testing.T) {
+	client := &RestClient{}
+	tlsConfig, _, err := client.newTLSConfig(config.RestClientProperties{})
+	require.NoError(t, err)
+	require.Equal(t, uint16(tls.VersionTLS12), tlsConfig.MinVersion)
+	tlsConfig, _, err = client.newTLSConfig(config.RestClientProperties{TLSMinVersion: "1.1"})
+	require.NoError(t, err)
+	require.Equal(t, uint16(tls.VersionTLS11), tlsConfig.MinVersion)
+
+	// the version of WithTLSConfig is kept
+	client.tlsConfig = &tls.Config{MinVersion: tls.VersionTLS13}
+	tlsConfig, _, err = client.newTLSConfig(config.RestClientProperties{})
+	require.NoError(t, err)
+	require.Equal(t, uint16(tls.VersionTLS13), tlsConfig.MinVersion)
+}
+
+func TestProxy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restclient-proxy")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	// a forward proxy sees the absolute url of the rest server
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		shakeHandler(w, r)
+	}))
+	defer proxy.Close()
+
+	properties := config.RestClientProperties{RestUrl: "http://rest.internal:8080", AccessId: "access", AccessSecret: writeTestKey(t, dir), ProxyUrl: proxy.URL}
+	client, err := NewRestClientFromProperties(properties)
+	require.NoError(t, err)
+	require.Equal(t, "token-rest.internal:8080", client.Token())
+	require.Equal(t, []string{"http://rest.internal:8080" + ShakeHandPath}, proxied)
+
+	// without ProxyUrl the proxy of WithProxy is used
+	properties.ProxyUrl = ""
+	proxyURL, err := url.Parse(proxy.URL)
+	require.NoError(t, err)
+	_, err = NewRestClientFromProperties(properties, WithProxy(http.ProxyURL(proxyURL)))
+	require.NoError(t, err)
+	require.Len(t, proxied, 2)
+
+	// a socks5 proxy that is not there
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	properties.ProxyUrl = "socks5://" + listener.Addr().String()
+	listener.Close()
+	_, err = NewRestClientFromProperties(properties)
+	require.Error(t, err)
+	require.True(t, strings.Contains(err.Error(), "socks") || strings.Contains(err.Error(), "proxy"), err.Error())
+}
@@ -0,0 +1,65 @@
+// Command abigen generates a typed Go package wrapping a contract ABI for RestClient.
+//
+//	abigen -abi SayHello.abi -bin SayHello.bin -pkg sayhello -type SayHello -out sayhello.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/oldercn/restclient-go-sdk/bind"
+)
+
+func main() {
+	abiPath := flag.String("abi", "", "path to the contract ABI json, - for stdin")
+	binPath := flag.String("bin", "", "path to the contract bytecode in hex, optional")
+	pkg := flag.String("pkg", "", "package name of the generated code")
+	typeName := flag.String("type", "", "go type name of the contract binding, defaults to the package name")
+	outPath := flag.String("out", "", "output file, defaults to stdout")
+	flag.Parse()
+
+	if *abiPath == "" || *pkg == "" {
+		fmt.Fprintln(os.Stderr, "abigen: -abi and -pkg are required")
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *typeName == "" {
+		*typeName = *pkg
+	}
+
+	var abiJSON []byte
+	var err error
+	if *abiPath == "-" {
+		abiJSON, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		abiJSON, err = ioutil.ReadFile(*abiPath)
+	}
+	if err != nil {
+		fatalf("fail to read abi,err:%+v", err)
+	}
+	var bytecode []byte
+	if *binPath != "" {
+		if bytecode, err = ioutil.ReadFile(*binPath); err != nil {
+			fatalf("fail to read bytecode,err:%+v", err)
+		}
+	}
+
+	code, err := bind.Bind(*pkg, *typeName, string(abiJSON), string(bytecode))
+	if err != nil {
+		fatalf("fail to generate binding,err:%+v", err)
+	}
+	if *outPath == "" {
+		fmt.Print(code)
+		return
+	}
+	if err := ioutil.WriteFile(*outPath, []byte(code), 0644); err != nil {
+		fatalf("fail to write binding,err:%+v", err)
+	}
+}
+
+func fatalf(format string, args ...interface{}) {
+	fmt.Fprintf(os.Stderr, "abigen: "+format+"\n", args...)
+	os.Exit(1)
+}
@@ -0,0 +1,90 @@
+// Package otel traces a RestClient with OpenTelemetry:
+//
+//	restClient, err := client.NewRestClient(path, client.WithTracer(otel.NewTracer(nil, nil)))
+//
+// It is a module of its own, so that the SDK does not depend on OpenTelemetry.
+package otel
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/oldercn/restclient-go-sdk/client"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentationName is the name of the OpenTelemetry tracer of a RestClient.
+const InstrumentationName = "github.com/oldercn/restclient-go-sdk/client"
+
+type tracer struct {
+	tracer     trace.Tracer
+	propagator propagation.TextMapPropagator
+}
+
+// NewTracer returns a client.Tracer starting its spans with a tracer of provider and
+// writing the trace context to the requests with propagator. nil stands for the
+// global provider or propagator, see otel.SetTracerProvider.
+func NewTracer(provider trace.TracerProvider, propagator propagation.TextMapPropagator) client.Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	if propagator == nil {
+		propagator = otel.GetTextMapPropagator()
+	}
+	return tracer{tracer: provider.Tracer(InstrumentationName), propagator: propagator}
+}
+
+func (t tracer) Start(ctx context.Context, name string, attributes ...client.Attribute) (context.Context, client.Span) {
+	kind := trace.SpanKindInternal
+	if name == client.SpanAttempt || name == client.SpanHandshake {
+		// the spans of single http requests
+		kind = trace.SpanKindClient
+	}
+	ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(keyValues(attributes)...))
+	return ctx, otelSpan{span: span}
+}
+
+func (t tracer) Inject(ctx context.Context, header http.Header) {
+	t.propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+type otelSpan struct {
+	span trace.Span
+}
+
+func (s otelSpan) SetAttributes(attributes ...client.Attribute) {
+	s.span.SetAttributes(keyValues(attributes)...)
+}
+
+func (s otelSpan) RecordError(err error) {
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s otelSpan) End() {
+	s.span.End()
+}
+
+func keyValues(attributes []client.Attribute) []attribute.KeyValue {
+	keyValues := make([]attribute.KeyValue, 0, len(attributes))
+	for _, a := range attributes {
+		switch value := a.Value.(type) {
+		case string:
+			keyValues = append(keyValues, attribute.String(a.Key, value))
+		case int:
+			keyValues = append(keyValues, attribute.Int(a.Key, value))
+		case int64:
+			keyValues = append(keyValues, attribute.Int64(a.Key, value))
+		case bool:
+			keyValues = append(keyValues, attribute.Bool(a.Key, value))
+		default:
+			keyValues = append(keyValues, attribute.String(a.Key, fmt.Sprint(value)))
+		}
+	}
+	return keyValues
+}
@@ -0,0 +1,43 @@
+package otel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/oldercn/restclient-go-sdk/client"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := NewTracer(provider, propagation.TraceContext{})
+
+	ctx, call := tracer.Start(context.Background(), client.SpanChainCall+"DEPOSIT", client.Attribute{Key: client.AttrBizID, Value: "biz"})
+	attemptCtx, attempt := tracer.Start(ctx, client.SpanAttempt, client.Attribute{Key: client.AttrAttempt, Value: 1})
+	header := http.Header{}
+	tracer.Inject(attemptCtx, header)
+	require.Contains(t, header.Get("traceparent"), trace.SpanContextFromContext(attemptCtx).TraceID().String())
+	attempt.RecordError(errors.New("timeout"))
+	attempt.End()
+	call.SetAttributes(client.Attribute{Key: client.AttrResultCode, Value: "200"})
+	call.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	require.Equal(t, client.SpanAttempt, spans[0].Name())
+	require.Equal(t, trace.SpanKindClient, spans[0].SpanKind())
+	require.Equal(t, codes.Error, spans[0].Status().Code)
+	require.Contains(t, spans[0].Attributes(), attribute.Int(client.AttrAttempt, 1))
+	require.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+	require.Equal(t, trace.SpanKindInternal, spans[1].SpanKind())
+	require.Equal(t, []attribute.KeyValue{attribute.String(client.AttrBizID, "biz"), attribute.String(client.AttrResultCode, "200")}, spans[1].Attributes())
+}
@@ -19,4 +19,5 @@ const (
 	String                        = "string"
 	EncodedBytes                  = "encodedbytes"
 	ListBytes                     = "list(bytes)"
+	Tuple                         = "tuple"
 )
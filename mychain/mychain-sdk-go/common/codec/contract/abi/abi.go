@@ -4,12 +4,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"reflect"
+	"sort"
+	"strings"
 )
 
+// ABI holds the information of a contract interface. Methods, events and errors are
+// keyed by their signature so overloads can live side by side, use MethodsByName,
+// LookupMethod, EventsByName, LookupEvent, ErrorsByName or LookupError to find them
+// by name.
 type ABI struct {
 	Constructor Method
+	Fallback    Method
+	Receive     Method
 	Methods     map[string]Method
 	Events      map[string]Event
+	Errors      map[string]Error
 }
 
 //func (abi ABI) UnpackIntoMap(valueMap map[string]interface{}, typeName string, originalData []byte) (err error) {
@@ -71,60 +81,391 @@ func JSON(reader io.Reader) (ABI, error) {
 	return abi, nil
 }
 
+// HasFallback reports whether the ABI declares a fallback function.
+func (abi ABI) HasFallback() bool {
+	return abi.Fallback.Type == Fallback
+}
+
+// HasReceive reports whether the ABI declares a receive function.
+func (abi ABI) HasReceive() bool {
+	return abi.Receive.Type == Receive
+}
+
+// MethodsByName returns all overloads of the named method ordered by signature.
+func (abi ABI) MethodsByName(name string) []Method {
+	var methods []Method
+	for _, method := range abi.Methods {
+		if method.Name == name {
+			methods = append(methods, method)
+		}
+	}
+	sort.Slice(methods, func(i, j int) bool {
+		return methods[i].Sig() < methods[j].Sig()
+	})
+	return methods
+}
+
+// MethodByName returns the method with the given name, it fails when the name is overloaded.
+func (abi ABI) MethodByName(name string) (Method, error) {
+	methods := abi.MethodsByName(name)
+	switch len(methods) {
+	case 0:
+		return Method{}, fmt.Errorf("abi: method '%s' not found", name)
+	case 1:
+		return methods[0], nil
+	}
+	sigs := make([]string, len(methods))
+	for i, method := range methods {
+		sigs[i] = method.Sig()
+	}
+	return Method{}, fmt.Errorf("abi: method '%s' is overloaded, use one of the signatures: %s", name, strings.Join(sigs, ", "))
+}
+
+// LookupMethod finds a method by its signature, e.g. SayHello(bytes,string), or by its name when it is not overloaded.
+func (abi ABI) LookupMethod(nameOrSig string) (Method, error) {
+	if method, ok := abi.Methods[nameOrSig]; ok {
+		return method, nil
+	}
+	if strings.Contains(nameOrSig, "(") {
+		sig := strings.Replace(nameOrSig, " ", "", -1)
+		if method, ok := abi.Methods[sig]; ok {
+			return method, nil
+		}
+		return Method{}, fmt.Errorf("abi: method '%s' not found", nameOrSig)
+	}
+	return abi.MethodByName(nameOrSig)
+}
+
+// EventsByName returns all overloads of the named event ordered by signature.
+func (abi ABI) EventsByName(name string) []Event {
+	var events []Event
+	for _, event := range abi.Events {
+		if event.Name == name {
+			events = append(events, event)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Sig() < events[j].Sig()
+	})
+	return events
+}
+
+// EventByName returns the event with the given name, it fails when the name is overloaded.
+func (abi ABI) EventByName(name string) (Event, error) {
+	events := abi.EventsByName(name)
+	switch len(events) {
+	case 0:
+		return Event{}, fmt.Errorf("abi: event '%s' not found", name)
+	case 1:
+		return events[0], nil
+	}
+	sigs := make([]string, len(events))
+	for i, event := range events {
+		sigs[i] = event.Sig()
+	}
+	return Event{}, fmt.Errorf("abi: event '%s' is overloaded, use one of the signatures: %s", name, strings.Join(sigs, ", "))
+}
+
+// LookupEvent finds an event by its signature, or by its name when it is not overloaded.
+func (abi ABI) LookupEvent(nameOrSig string) (Event, error) {
+	if event, ok := abi.Events[nameOrSig]; ok {
+		return event, nil
+	}
+	if strings.Contains(nameOrSig, "(") {
+		sig := strings.Replace(nameOrSig, " ", "", -1)
+		if event, ok := abi.Events[sig]; ok {
+			return event, nil
+		}
+		return Event{}, fmt.Errorf("abi: event '%s' not found", nameOrSig)
+	}
+	return abi.EventByName(nameOrSig)
+}
+
+// ErrorsByName returns all overloads of the named error ordered by signature.
+func (abi ABI) ErrorsByName(name string) []Error {
+	var errs []Error
+	for _, e := range abi.Errors {
+		if e.Name == name {
+			errs = append(errs, e)
+		}
+	}
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Sig() < errs[j].Sig()
+	})
+	return errs
+}
+
+// ErrorByName returns the error with the given name, it fails when the name is overloaded.
+func (abi ABI) ErrorByName(name string) (Error, error) {
+	errs := abi.ErrorsByName(name)
+	switch len(errs) {
+	case 0:
+		return Error{}, fmt.Errorf("abi: error '%s' not found", name)
+	case 1:
+		return errs[0], nil
+	}
+	sigs := make([]string, len(errs))
+	for i, e := range errs {
+		sigs[i] = e.Sig()
+	}
+	return Error{}, fmt.Errorf("abi: error '%s' is overloaded, use one of the signatures: %s", name, strings.Join(sigs, ", "))
+}
+
+// LookupError finds an error by its signature, or by its name when it is not overloaded.
+func (abi ABI) LookupError(nameOrSig string) (Error, error) {
+	if e, ok := abi.Errors[nameOrSig]; ok {
+		return e, nil
+	}
+	if strings.Contains(nameOrSig, "(") {
+		sig := strings.Replace(nameOrSig, " ", "", -1)
+		if e, ok := abi.Errors[sig]; ok {
+			return e, nil
+		}
+		return Error{}, fmt.Errorf("abi: error '%s' not found", nameOrSig)
+	}
+	return abi.ErrorByName(nameOrSig)
+}
+
 func (abi ABI) Unpack(v interface{}, name string, data []byte) (err error) {
 	if len(data) == 0 {
 		return fmt.Errorf("abi: unmarshalling empty output")
 	}
-	if method, ok := abi.Methods[name]; ok {
+	if method, err := abi.LookupMethod(name); err == nil {
 		if len(data)%32 != 0 {
 			return fmt.Errorf("abi: improperly formatted output: %s - Bytes: [%+v]", string(data), data)
 		}
 		return method.Outputs.Unpack(v, data)
+	} else if len(abi.MethodsByName(name)) > 1 {
+		return err
 	}
-	if event, ok := abi.Events[name]; ok {
+	if event, err := abi.LookupEvent(name); err == nil {
 		return event.Inputs.Unpack(v, data)
+	} else if len(abi.EventsByName(name)) > 1 {
+		return err
 	}
 	return fmt.Errorf("abi: could not locate named method or event")
 }
 
-func (abi *ABI) UnmarshalJSON(originalData []byte) error {
-	var fields []struct {
-		Type      string
-		Name      string
-		Constant  bool
-		Anonymous bool
-		Inputs    []Argument
-		Outputs   []Argument
+// Merge returns a new ABI holding the entries of both ABIs, identical entries are
+// allowed to appear in both while conflicting ones are reported.
+func (abi ABI) Merge(other ABI) (ABI, error) {
+	merged := ABI{
+		Constructor: abi.Constructor,
+		Fallback:    abi.Fallback,
+		Receive:     abi.Receive,
+		Methods:     make(map[string]Method, len(abi.Methods)+len(other.Methods)),
+		Events:      make(map[string]Event, len(abi.Events)+len(other.Events)),
+		Errors:      make(map[string]Error, len(abi.Errors)+len(other.Errors)),
+	}
+	for sig, method := range abi.Methods {
+		merged.Methods[sig] = method
+	}
+	for sig, event := range abi.Events {
+		merged.Events[sig] = event
+	}
+	for sig, e := range abi.Errors {
+		merged.Errors[sig] = e
 	}
 
+	special := []struct {
+		dst *Method
+		src Method
+	}{
+		{&merged.Constructor, other.Constructor},
+		{&merged.Fallback, other.Fallback},
+		{&merged.Receive, other.Receive},
+	}
+	for _, s := range special {
+		if s.src.Type == "" {
+			continue
+		}
+		if s.dst.Type != "" && !reflect.DeepEqual(*s.dst, s.src) {
+			return ABI{}, fmt.Errorf("abi: conflicting %v", s.src.Type)
+		}
+		*s.dst = s.src
+	}
+	for sig, method := range other.Methods {
+		if exist, ok := merged.Methods[sig]; ok && !reflect.DeepEqual(exist, method) {
+			return ABI{}, fmt.Errorf("abi: conflicting method '%s'", sig)
+		}
+		merged.Methods[sig] = method
+	}
+	for sig, event := range other.Events {
+		if exist, ok := merged.Events[sig]; ok && !reflect.DeepEqual(exist, event) {
+			return ABI{}, fmt.Errorf("abi: conflicting event '%s'", sig)
+		}
+		merged.Events[sig] = event
+	}
+	for sig, e := range other.Errors {
+		if exist, ok := merged.Errors[sig]; ok && !reflect.DeepEqual(exist, e) {
+			return ABI{}, fmt.Errorf("abi: conflicting error '%s'", sig)
+		}
+		merged.Errors[sig] = e
+	}
+	return merged, nil
+}
+
+// Filter returns a new ABI keeping only the methods and events accepted by the
+// given predicates, a nil predicate keeps everything. Constructor, fallback,
+// receive and errors are always kept.
+func (abi ABI) Filter(keepMethod func(Method) bool, keepEvent func(Event) bool) ABI {
+	filtered := ABI{
+		Constructor: abi.Constructor,
+		Fallback:    abi.Fallback,
+		Receive:     abi.Receive,
+		Methods:     make(map[string]Method),
+		Events:      make(map[string]Event),
+		Errors:      make(map[string]Error, len(abi.Errors)),
+	}
+	for sig, e := range abi.Errors {
+		filtered.Errors[sig] = e
+	}
+	for sig, method := range abi.Methods {
+		if keepMethod == nil || keepMethod(method) {
+			filtered.Methods[sig] = method
+		}
+	}
+	for sig, event := range abi.Events {
+		if keepEvent == nil || keepEvent(event) {
+			filtered.Events[sig] = event
+		}
+	}
+	return filtered
+}
+
+// abiField is the json representation of a single ABI entry.
+type abiField struct {
+	Type            string     `json:"type"`
+	Name            string     `json:"name,omitempty"`
+	Constant        bool       `json:"constant,omitempty"`
+	Anonymous       bool       `json:"anonymous,omitempty"`
+	StateMutability string     `json:"stateMutability,omitempty"`
+	Payable         bool       `json:"payable,omitempty"`
+	Inputs          []Argument `json:"inputs"`
+	Outputs         []Argument `json:"outputs,omitempty"`
+}
+
+func (abi *ABI) UnmarshalJSON(originalData []byte) error {
+	var fields []abiField
+
 	if err := json.Unmarshal(originalData, &fields); err != nil {
 		return err
 	}
+	return abi.load(fields)
+}
 
+// load fills the ABI from its entries, it is shared by the json and the human-readable parsers.
+func (abi *ABI) load(fields []abiField) error {
 	abi.Methods = make(map[string]Method)
 	abi.Events = make(map[string]Event)
+	abi.Errors = make(map[string]Error)
 
 	for _, field := range fields {
+		method := Method{
+			Name:            field.Name,
+			Type:            FunctionType(field.Type),
+			Const:           field.Constant,
+			StateMutability: field.StateMutability,
+			Payable:         field.Payable,
+			Inputs:          field.Inputs,
+			Outputs:         field.Outputs,
+		}
 		switch field.Type {
 		case "function", "":
-			abi.Methods[field.Name] = Method{
-				Name:    field.Name,
-				Const:   field.Constant,
-				Inputs:  field.Inputs,
-				Outputs: field.Outputs,
-			}
+			abi.Methods[method.Sig()] = method
 		case "constructor":
-			abi.Constructor = Method{
-				Inputs: field.Inputs,
-			}
+			abi.Constructor = method
+		case "fallback":
+			abi.Fallback = method
+		case "receive":
+			abi.Receive = method
 		case "event":
-			abi.Events[field.Name] = Event{
+			event := Event{
 				Name:      field.Name,
 				Anonymous: field.Anonymous,
 				Inputs:    field.Inputs,
 			}
+			abi.Events[event.Sig()] = event
+		case "error":
+			e := Error{
+				Name:   field.Name,
+				Inputs: field.Inputs,
+			}
+			abi.Errors[e.Sig()] = e
+		default:
+			return fmt.Errorf("abi: could not recognize type %v of field %v", field.Type, field.Name)
 		}
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// MarshalJSON emits the ABI as a json array. Entries are ordered as constructor,
+// fallback, receive, then functions, events and errors by signature.
+func (abi ABI) MarshalJSON() ([]byte, error) {
+	fields := make([]abiField, 0, len(abi.Methods)+len(abi.Events)+len(abi.Errors)+3)
+	for _, method := range []Method{abi.Constructor, abi.Fallback, abi.Receive} {
+		if method.Type != "" {
+			fields = append(fields, methodField(method))
+		}
+	}
+	sigs := make([]string, 0, len(abi.Methods))
+	for sig := range abi.Methods {
+		sigs = append(sigs, sig)
+	}
+	sort.Strings(sigs)
+	for _, sig := range sigs {
+		fields = append(fields, methodField(abi.Methods[sig]))
+	}
+	sigs = sigs[:0]
+	for sig := range abi.Events {
+		sigs = append(sigs, sig)
+	}
+	sort.Strings(sigs)
+	for _, sig := range sigs {
+		event := abi.Events[sig]
+		fields = append(fields, abiField{
+			Type:      "event",
+			Name:      event.Name,
+			Anonymous: event.Anonymous,
+			Inputs:    nonNilArguments(event.Inputs),
+		})
+	}
+	sigs = sigs[:0]
+	for sig := range abi.Errors {
+		sigs = append(sigs, sig)
+	}
+	sort.Strings(sigs)
+	for _, sig := range sigs {
+		e := abi.Errors[sig]
+		fields = append(fields, abiField{
+			Type:   "error",
+			Name:   e.Name,
+			Inputs: nonNilArguments(e.Inputs),
+		})
+	}
+	return json.Marshal(fields)
+}
+
+func methodField(method Method) abiField {
+	field := abiField{
+		Type:            string(method.Type),
+		Name:            method.Name,
+		Constant:        method.Const,
+		StateMutability: method.StateMutability,
+		Payable:         method.Payable,
+		Inputs:          nonNilArguments(method.Inputs),
+		Outputs:         method.Outputs,
+	}
+	if field.Type == "" {
+		field.Type = string(Function)
+	}
+	return field
+}
+
+func nonNilArguments(arguments Arguments) []Argument {
+	if arguments == nil {
+		return []Argument{}
+	}
+	return arguments
+}
@@ -0,0 +1,90 @@
+package abi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const fullABI = `[
+  {"type":"constructor","inputs":[{"name":"_greeting","type":"uint256"}],"outputs":[{"name":"ok","type":"bool"}],"stateMutability":"nonpayable"},
+  {"type":"fallback","stateMutability":"payable","payable":true,"inputs":[]},
+  {"type":"receive","stateMutability":"payable","payable":true,"inputs":[]},
+  {"type":"function","name":"transfer","inputs":[{"name":"to","type":"identity"},{"name":"value","type":"uint256"}],"outputs":[{"name":"","type":"bool"}],"stateMutability":"nonpayable"},
+  {"type":"function","name":"transfer","inputs":[{"name":"to","type":"identity"}],"outputs":[{"name":"","type":"bool"}],"stateMutability":"payable","payable":true},
+  {"type":"function","name":"get","constant":true,"inputs":[{"name":"p","type":"tuple","internalType":"struct Store.Point","components":[{"name":"x","type":"uint8","internalType":"uint8"},{"name":"who","type":"identity[]","internalType":"identity[]"}]}],"outputs":[{"name":"sum","type":"uint256","internalType":"uint256"}],"stateMutability":"view"},
+  {"type":"event","name":"Transfer","anonymous":false,"inputs":[{"name":"from","type":"identity","indexed":true},{"name":"value","type":"uint256"}]},
+  {"type":"event","name":"Transfer","anonymous":false,"inputs":[{"name":"from","type":"identity","indexed":true},{"name":"to","type":"identity","indexed":true},{"name":"value","type":"uint256"}]}
+]`
+
+func TestABIOverloadsAndLookup(t *testing.T) {
+	abi, err := JSON(strings.NewReader(fullABI))
+	require.NoError(t, err)
+
+	require.Len(t, abi.Methods, 3)
+	require.Len(t, abi.MethodsByName("transfer"), 2)
+	_, ok := abi.Methods["transfer(identity,uint256)"]
+	require.True(t, ok)
+
+	_, err = abi.MethodByName("transfer")
+	require.Error(t, err)
+	method, err := abi.LookupMethod("transfer(identity)")
+	require.NoError(t, err)
+	require.True(t, method.IsPayable())
+	method, err = abi.LookupMethod("get")
+	require.NoError(t, err)
+	require.True(t, method.IsConstant())
+	require.Equal(t, "get((uint8,identity[]))", method.Sig())
+	require.Equal(t, "struct Store.Point", method.Inputs[0].InternalType)
+
+	require.Len(t, abi.Events, 2)
+	require.Len(t, abi.EventsByName("Transfer"), 2)
+	_, err = abi.EventByName("Transfer")
+	require.Error(t, err)
+	event, err := abi.LookupEvent("Transfer(identity, identity, uint256)")
+	require.NoError(t, err)
+	require.Len(t, event.Inputs, 3)
+
+	require.True(t, abi.HasFallback())
+	require.True(t, abi.HasReceive())
+	require.Equal(t, "ok", abi.Constructor.Outputs[0].Name)
+}
+
+func TestABIMarshalRoundTrip(t *testing.T) {
+	abi, err := JSON(strings.NewReader(fullABI))
+	require.NoError(t, err)
+
+	encoded, err := json.Marshal(abi)
+	require.NoError(t, err)
+	decoded, err := JSON(strings.NewReader(string(encoded)))
+	require.NoError(t, err)
+	require.Equal(t, abi, decoded)
+
+	reencoded, err := json.Marshal(decoded)
+	require.NoError(t, err)
+	require.JSONEq(t, string(encoded), string(reencoded))
+}
+
+func TestABIMergeAndFilter(t *testing.T) {
+	abi, err := JSON(strings.NewReader(fullABI))
+	require.NoError(t, err)
+	other, err := JSON(strings.NewReader(`[{"type":"function","name":"extra","inputs":[],"outputs":[]}]`))
+	require.NoError(t, err)
+
+	merged, err := abi.Merge(other)
+	require.NoError(t, err)
+	require.Len(t, merged.Methods, 4)
+	_, err = merged.Merge(abi)
+	require.NoError(t, err)
+
+	conflict, err := JSON(strings.NewReader(`[{"type":"function","name":"extra","inputs":[],"outputs":[{"name":"","type":"bool"}]}]`))
+	require.NoError(t, err)
+	_, err = merged.Merge(conflict)
+	require.Error(t, err)
+
+	views := merged.Filter(Method.IsConstant, nil)
+	require.Len(t, views.Methods, 1)
+	require.Len(t, views.Events, 2)
+}
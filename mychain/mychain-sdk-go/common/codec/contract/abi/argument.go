@@ -8,18 +8,20 @@ import (
 )
 
 type Argument struct {
-	Name    string
-	Type    Type
-	Indexed bool
+	Name         string
+	Type         Type
+	InternalType string
+	Indexed      bool
 }
 
 type Arguments []Argument
 
 type ArgumentMarshaling struct {
-	Name       string
-	Type       string
-	Components []ArgumentMarshaling
-	Indexed    bool
+	Name         string               `json:"name"`
+	Type         string               `json:"type"`
+	InternalType string               `json:"internalType,omitempty"`
+	Components   []ArgumentMarshaling `json:"components,omitempty"`
+	Indexed      bool                 `json:"indexed,omitempty"`
 }
 
 func (argument *Argument) UnmarshalJSON(data []byte) error {
@@ -29,14 +31,31 @@ func (argument *Argument) UnmarshalJSON(data []byte) error {
 		return fmt.Errorf("argument json err: %v", err)
 	}
 
-	argument.Type, err = NewType(arg.Type, arg.Components)
+	*argument, err = newArgument(arg)
+	return err
+}
+
+func newArgument(arg ArgumentMarshaling) (Argument, error) {
+	typ, err := NewType(arg.Type, arg.Components)
 	if err != nil {
-		return err
+		return Argument{}, err
 	}
-	argument.Name = arg.Name
-	argument.Indexed = arg.Indexed
+	return Argument{
+		Name:         arg.Name,
+		Type:         typ,
+		InternalType: arg.InternalType,
+		Indexed:      arg.Indexed,
+	}, nil
+}
 
-	return nil
+func (argument Argument) MarshalJSON() ([]byte, error) {
+	return json.Marshal(ArgumentMarshaling{
+		Name:         argument.Name,
+		Type:         argument.Type.rawType,
+		InternalType: argument.InternalType,
+		Components:   argument.Type.components,
+		Indexed:      argument.Indexed,
+	})
 }
 
 func (arguments Arguments) LengthNonIndexed() int {
@@ -86,60 +105,10 @@ func (arguments Arguments) UnpackIntoMap(v map[string]interface{}, data []byte)
 	return arguments.unpackIntoMap(v, marshalledValues)
 }
 
-func unpack(t *Type, dst interface{}, src interface{}) error {
-	var (
-		dstVal = reflect.ValueOf(dst).Elem()
-		srcVal = reflect.ValueOf(src)
-	)
-
-	if t.T != TupleTy && !((t.T == SliceTy || t.T == ArrayTy) && t.Elem.T == TupleTy) {
-		return set(dstVal, srcVal)
-	}
-
-	switch t.T {
-	case TupleTy:
-		if dstVal.Kind() != reflect.Struct {
-			return fmt.Errorf("abi: invalid dst value for unpack, want struct, got %s", dstVal.Kind())
-		}
-		fieldmap, err := mapArgNamesToStructFields(t.TupleRawNames, dstVal)
-		if err != nil {
-			return err
-		}
-		for i, elem := range t.TupleElems {
-			fname := fieldmap[t.TupleRawNames[i]]
-			field := dstVal.FieldByName(fname)
-			if !field.IsValid() {
-				return fmt.Errorf("abi: field %s can't found in the given value", t.TupleRawNames[i])
-			}
-			if err := unpack(elem, field.Addr().Interface(), srcVal.Field(i).Interface()); err != nil {
-				return err
-			}
-		}
-		return nil
-	case SliceTy:
-		if dstVal.Kind() != reflect.Slice {
-			return fmt.Errorf("abi: invalid dst value for unpack, want slice, got %s", dstVal.Kind())
-		}
-		slice := reflect.MakeSlice(dstVal.Type(), srcVal.Len(), srcVal.Len())
-		for i := 0; i < slice.Len(); i++ {
-			if err := unpack(t.Elem, slice.Index(i).Addr().Interface(), srcVal.Index(i).Interface()); err != nil {
-				return err
-			}
-		}
-		dstVal.Set(slice)
-	case ArrayTy:
-		if dstVal.Kind() != reflect.Array {
-			return fmt.Errorf("abi: invalid dst value for unpack, want array, got %s", dstVal.Kind())
-		}
-		array := reflect.New(dstVal.Type()).Elem()
-		for i := 0; i < array.Len(); i++ {
-			if err := unpack(t.Elem, array.Index(i).Addr().Interface(), srcVal.Index(i).Interface()); err != nil {
-				return err
-			}
-		}
-		dstVal.Set(array)
-	}
-	return nil
+// unpack copies the decoded src into dst, a pointer. path names the argument
+// being unpacked and prefixes every error, e.g. "points[1].who".
+func unpack(t *Type, dst interface{}, src interface{}, path string) error {
+	return unpackValue(t, reflect.ValueOf(dst).Elem(), reflect.ValueOf(src), path)
 }
 
 func (arguments Arguments) unpackIntoMap(v map[string]interface{}, marshalledValues []interface{}) error {
@@ -161,7 +130,9 @@ func (arguments Arguments) unpackAtomic(v interface{}, marshalledValues interfac
 	argument := arguments.NonIndexed()[0]
 	elem := reflect.ValueOf(v).Elem()
 
-	if elem.Kind() == reflect.Struct {
+	// a single non-tuple output unpacked into a struct goes into the field of its
+	// name, a tuple output is unpacked into the struct itself below
+	if elem.Kind() == reflect.Struct && argument.Type.T != TupleTy && elem.Type() != derefbigT {
 		fieldmap, err := mapArgNamesToStructFields([]string{argument.Name}, elem)
 		if err != nil {
 			return err
@@ -170,9 +141,17 @@ func (arguments Arguments) unpackAtomic(v interface{}, marshalledValues interfac
 		if !field.IsValid() {
 			return fmt.Errorf("abi: field %s can't be found in the given value", argument.Name)
 		}
-		return unpack(&argument.Type, field.Addr().Interface(), marshalledValues)
+		return unpack(&argument.Type, field.Addr().Interface(), marshalledValues, argPath(argument, 0))
 	}
-	return unpack(&argument.Type, elem.Addr().Interface(), marshalledValues)
+	return unpack(&argument.Type, elem.Addr().Interface(), marshalledValues, argPath(argument, 0))
+}
+
+// argPath names an argument in unpack errors, unnamed arguments are named by position.
+func argPath(arg Argument, index int) string {
+	if arg.Name != "" {
+		return arg.Name
+	}
+	return fmt.Sprintf("#%d", index)
 }
 
 func (arguments Arguments) unpackTuple(v interface{}, marshalledValues []interface{}) error {
@@ -206,7 +185,7 @@ func (arguments Arguments) unpackTuple(v interface{}, marshalledValues []interfa
 			if !field.IsValid() {
 				return fmt.Errorf("abi: field %s can't be found in the given value", arg.Name)
 			}
-			if err := unpack(&arg.Type, field.Addr().Interface(), marshalledValues[i]); err != nil {
+			if err := unpack(&arg.Type, field.Addr().Interface(), marshalledValues[i], argPath(arg, i)); err != nil {
 				return err
 			}
 		case reflect.Slice, reflect.Array:
@@ -217,7 +196,7 @@ func (arguments Arguments) unpackTuple(v interface{}, marshalledValues []interfa
 			if err := requireAssignable(v, reflect.ValueOf(marshalledValues[i])); err != nil {
 				return err
 			}
-			if err := unpack(&arg.Type, v.Addr().Interface(), marshalledValues[i]); err != nil {
+			if err := unpack(&arg.Type, v.Addr().Interface(), marshalledValues[i], argPath(arg, i)); err != nil {
 				return err
 			}
 		default:
@@ -274,9 +253,14 @@ func ToCamelCase(input string) string {
 
 func (arguments Arguments) UnpackValues(data []byte) ([]interface{}, error) {
 	returnValue := make([]interface{}, 0, arguments.LengthNonIndexed())
+	head := 0
+	for _, arg := range arguments.NonIndexed() {
+		head += getTypeSize(arg.Type)
+	}
+	decoder, blk := newDecoder(data), newBlock(head)
 	virtualArgs := 0
 	for index, arg := range arguments.NonIndexed() {
-		marshalValue, err := toGoType((index+virtualArgs)*32, arg.Type, data)
+		marshalValue, err := decoder.toGoType((index+virtualArgs)*32, blk, arg.Type, data)
 		if arg.Type.T == ArrayTy && !isDynamicType(arg.Type) {
 			virtualArgs += getTypeSize(arg.Type)/32 - 1
 		} else if arg.Type.T == TupleTy && !isDynamicType(arg.Type) {
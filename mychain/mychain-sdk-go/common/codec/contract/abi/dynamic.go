@@ -0,0 +1,131 @@
+package abi
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+
+	"github.com/oldercn/restclient-go-sdk/mychain/mychain-sdk-go/domain"
+)
+
+// UnpackDynamic decodes data without a Go schema. The result maps every non indexed
+// argument name to its canonical value, unnamed arguments are keyed by their position.
+// Canonical values are:
+//
+//	int/uint  decimal string
+//	bool      bool
+//	string    string
+//	bytes     hex string, also for bytesN and function
+//	identity  hex string
+//	tuple     map[string]interface{} keyed by component name, or position when unnamed
+//	T[] T[k]  []interface{}
+func (arguments Arguments) UnpackDynamic(data []byte) (map[string]interface{}, error) {
+	values, err := arguments.UnpackValues(data)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]interface{}, len(values))
+	for i, arg := range arguments.NonIndexed() {
+		key := dynamicKey(result, arg.Name, i)
+		value, err := canonicalValue(arg.Type, reflect.ValueOf(values[i]))
+		if err != nil {
+			return nil, fmt.Errorf("abi: %s: %v", key, err)
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+// UnpackDynamic decodes the output of a method or the data of an event, looked up
+// like LookupMethod and LookupEvent do.
+func (abi ABI) UnpackDynamic(name string, data []byte) (map[string]interface{}, error) {
+	if method, err := abi.LookupMethod(name); err == nil {
+		if len(method.Outputs) == 0 {
+			return map[string]interface{}{}, nil
+		}
+		if len(data) == 0 {
+			return nil, fmt.Errorf("abi: unmarshalling empty output")
+		}
+		return method.Outputs.UnpackDynamic(data)
+	} else if len(abi.MethodsByName(name)) > 1 {
+		return nil, err
+	}
+	if event, err := abi.LookupEvent(name); err == nil {
+		return event.Inputs.UnpackDynamic(data)
+	} else if len(abi.EventsByName(name)) > 1 {
+		return nil, err
+	}
+	return nil, fmt.Errorf("abi: could not locate named method or event")
+}
+
+// UnpackJSON is UnpackDynamic encoded as canonical json.
+func (abi ABI) UnpackJSON(name string, data []byte) ([]byte, error) {
+	values, err := abi.UnpackDynamic(name, data)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(values)
+}
+
+func canonicalValue(t Type, value reflect.Value) (interface{}, error) {
+	switch t.T {
+	case IntTy, UintTy:
+		if bigValue, ok := value.Interface().(*big.Int); ok {
+			return bigValue.String(), nil
+		}
+		switch value.Kind() {
+		case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return strconv.FormatInt(value.Int(), 10), nil
+		case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return strconv.FormatUint(value.Uint(), 10), nil
+		}
+	case BoolTy:
+		return value.Bool(), nil
+	case StringTy:
+		return value.String(), nil
+	case BytesTy:
+		return hex.EncodeToString(value.Bytes()), nil
+	case FixedBytesTy, FunctionTy:
+		return hex.EncodeToString(mustArrayToByteSlice(value).Bytes()), nil
+	case IdentityTy:
+		identity := value.Interface().(domain.Identity)
+		return identity.ToHex(), nil
+	case HashTy:
+		hash := value.Interface().(domain.Hash)
+		return hash.ToHex(), nil
+	case SliceTy, ArrayTy:
+		list := make([]interface{}, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			elem, err := canonicalValue(*t.Elem, value.Index(i))
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %v", i, err)
+			}
+			list[i] = elem
+		}
+		return list, nil
+	case TupleTy:
+		fields := make(map[string]interface{}, len(t.TupleElems))
+		for i, elem := range t.TupleElems {
+			key := dynamicKey(fields, t.TupleRawNames[i], i)
+			field, err := canonicalValue(*elem, value.Field(i))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", key, err)
+			}
+			fields[key] = field
+		}
+		return fields, nil
+	}
+	return nil, fmt.Errorf("unsupported value %v of type %v", value.Type(), t)
+}
+
+// dynamicKey is the key of the value at index named name in values, its position
+// when it is unnamed or the name is taken.
+func dynamicKey(values map[string]interface{}, name string, index int) string {
+	if _, exist := values[name]; name == "" || exist {
+		return strconv.Itoa(index)
+	}
+	return name
+}
@@ -0,0 +1,67 @@
+package abi
+
+import (
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnpackDynamic(t *testing.T) {
+	abi, err := ParseHumanReadable("function get() view returns (uint256 amount, int8, identity who, (uint8 x, bool ok) p, bytes, string[] names)")
+	require.NoError(t, err)
+
+	identity := strings.Repeat("ab", 32)
+	data := mustHex(t, word("0de0b6b3a7640000")+ // amount
+		strings.Repeat("f", 64)+ // -1
+		identity+
+		word("07")+word("01")+ // p
+		word("e0")+ // bytes offset
+		word("120")+ // names offset
+		word("03")+word("010203"+strings.Repeat("0", 58))+ // bytes
+		word("01")+word("20")+word("02")+word(hex.EncodeToString([]byte("hi"))+strings.Repeat("0", 60))) // names
+
+	values, err := abi.UnpackDynamic("get", data)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{
+		"amount": "1000000000000000000",
+		"1":      "-1",
+		"who":    identity,
+		"p":      map[string]interface{}{"x": "7", "ok": true},
+		"4":      "010203",
+		"names":  []interface{}{"hi"},
+	}, values)
+
+	encoded, err := abi.UnpackJSON("get()", data)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"1":"-1","4":"010203","amount":"1000000000000000000","names":["hi"],"p":{"ok":true,"x":"7"},"who":"`+identity+`"}`, string(encoded))
+
+	_, err = abi.UnpackDynamic("missing", data)
+	require.Error(t, err)
+}
+
+func TestUnpackDynamicUnnamedComponents(t *testing.T) {
+	uint8T, err := NewType("uint8", nil)
+	require.NoError(t, err)
+	boolT, err := NewType("bool", nil)
+	require.NoError(t, err)
+	// NewType names every component, a tuple built by hand may not
+	tupleT := Type{
+		T:             TupleTy,
+		TupleElems:    []*Type{&uint8T, &boolT, &uint8T},
+		TupleRawNames: []string{"", "", "x"},
+		Type: reflect.StructOf([]reflect.StructField{
+			{Name: "A", Type: uint8T.Type}, {Name: "B", Type: boolT.Type}, {Name: "X", Type: uint8T.Type},
+		}),
+	}
+	value := reflect.New(tupleT.Type).Elem()
+	value.Field(0).SetUint(7)
+	value.Field(1).SetBool(true)
+	value.Field(2).SetUint(9)
+
+	canonical, err := canonicalValue(tupleT, value)
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"0": "7", "1": true, "x": "9"}, canonical)
+}
@@ -1,13 +1,149 @@
 package abi
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/oldercn/restclient-go-sdk/mychain/mychain-sdk-go/common/crypto"
 )
 
 var (
 	errBadBool = errors.New("abi: improperly encoded boolean value")
 )
 
+var (
+	// revertSelector is the selector of the standard Error(string) revert.
+	revertSelector = crypto.Keccak256([]byte("Error(string)"))[:4]
+	// panicSelector is the selector of the Panic(uint256) raised by failed assertions.
+	panicSelector = crypto.Keccak256([]byte("Panic(uint256)"))[:4]
+)
+
+// panicReasons maps the solidity Panic(uint256) codes to their meaning.
+var panicReasons = map[uint64]string{
+	0x00: "generic panic",
+	0x01: "assert(false)",
+	0x11: "arithmetic underflow or overflow",
+	0x12: "division or modulo by zero",
+	0x21: "enum overflow",
+	0x22: "invalid encoded storage byte array accessed",
+	0x31: "out-of-bounds array access; popping on an empty array",
+	0x32: "out-of-bounds access of an array or bytesN",
+	0x41: "out of memory",
+	0x51: "uninitialized function",
+}
+
+// Error is a custom error declared in the ABI, e.g. error InsufficientBalance(uint256 available).
+type Error struct {
+	Name   string
+	Inputs Arguments
+}
+
+func (e Error) String() string {
+	inputs := make([]string, len(e.Inputs))
+	for i, input := range e.Inputs {
+		inputs[i] = fmt.Sprintf("%v %v", input.Type, input.Name)
+	}
+	return fmt.Sprintf("error %v(%v)", e.Name, strings.Join(inputs, ", "))
+}
+
+// Sig returns the canonical signature of the error, e.g. InsufficientBalance(uint256).
+func (e Error) Sig() string {
+	types := make([]string, len(e.Inputs))
+	for i, input := range e.Inputs {
+		types[i] = input.Type.String()
+	}
+	return fmt.Sprintf("%v(%v)", e.Name, strings.Join(types, ","))
+}
+
+func (e Error) Id() []byte {
+	return crypto.Keccak256([]byte(e.Sig()))[:4]
+}
+
+// Revert is the decoded output of a reverted call. Kind tells which of the
+// remaining fields is set: "Error" sets Reason, "Panic" sets PanicCode and
+// Reason, "Custom" sets Error and Args.
+type Revert struct {
+	Kind      string
+	Reason    string
+	PanicCode *big.Int
+	Error     *Error
+	Args      []interface{}
+}
+
+func (r *Revert) String() string {
+	switch {
+	case r.Kind == "Error":
+		return fmt.Sprintf("execution reverted: %s", r.Reason)
+	case r.Kind == "Panic":
+		return fmt.Sprintf("execution panicked: %s (0x%x)", r.Reason, r.PanicCode)
+	case r.Kind == "Custom":
+		args := make([]string, len(r.Args))
+		for i, arg := range r.Args {
+			args[i] = fmt.Sprintf("%v", arg)
+		}
+		return fmt.Sprintf("execution reverted: %s(%s)", r.Error.Name, strings.Join(args, ", "))
+	}
+	return "execution reverted"
+}
+
+// UnpackRevert decodes the reason of a standard Error(string) revert.
+func UnpackRevert(data []byte) (string, error) {
+	if len(data) < 4 || !bytes.Equal(data[:4], revertSelector) {
+		return "", errors.New("abi: invalid data for unpacking revert reason")
+	}
+	reason, err := toGoType(0, Type{T: StringTy}, data[4:])
+	if err != nil {
+		return "", err
+	}
+	return reason.(string), nil
+}
+
+// UnpackRevert decodes the output of a reverted call into the standard revert
+// reason, the panic code or one of the custom errors declared in the ABI.
+func (abi ABI) UnpackRevert(data []byte) (*Revert, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("abi: revert data too short (%d bytes)", len(data))
+	}
+	selector := data[:4]
+	switch {
+	case bytes.Equal(selector, revertSelector):
+		reason, err := UnpackRevert(data)
+		if err != nil {
+			return nil, err
+		}
+		return &Revert{Kind: "Error", Reason: reason}, nil
+	case bytes.Equal(selector, panicSelector):
+		code, err := toGoType(0, Type{T: UintTy, Kind: reflect.Ptr, Type: bigT}, data[4:])
+		if err != nil {
+			return nil, err
+		}
+		panicCode := code.(*big.Int)
+		reason := "unknown panic code"
+		if panicCode.IsUint64() {
+			if r, ok := panicReasons[panicCode.Uint64()]; ok {
+				reason = r
+			}
+		}
+		return &Revert{Kind: "Panic", Reason: reason, PanicCode: panicCode}, nil
+	}
+	for _, e := range abi.Errors {
+		if !bytes.Equal(selector, e.Id()) {
+			continue
+		}
+		e := e
+		args, err := e.Inputs.UnpackValues(data[4:])
+		if err != nil {
+			return nil, err
+		}
+		return &Revert{Kind: "Custom", Error: &e, Args: args}, nil
+	}
+	return nil, fmt.Errorf("abi: no error with id: %#x", selector)
+}
+
 //func formatSliceString(kind reflect.Kind, sliceSize int) string {
 //	if sliceSize == -1 {
 //		return fmt.Sprintf("[]%v", kind)
@@ -0,0 +1,89 @@
+package abi
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func word(hexStr string) string {
+	return strings.Repeat("0", 64-len(hexStr)) + hexStr
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	require.NoError(t, err)
+	return b
+}
+
+func TestUnpackRevertReason(t *testing.T) {
+	// Error("not enough")
+	data := mustHex(t, "08c379a0"+word("20")+word("0a")+hex.EncodeToString([]byte("not enough"))+strings.Repeat("0", 44))
+	reason, err := UnpackRevert(data)
+	require.NoError(t, err)
+	require.Equal(t, "not enough", reason)
+
+	revert, err := ABI{}.UnpackRevert(data)
+	require.NoError(t, err)
+	require.Equal(t, "Error", revert.Kind)
+	require.Equal(t, "execution reverted: not enough", revert.String())
+
+	_, err = UnpackRevert(data[:10])
+	require.Error(t, err)
+}
+
+func TestUnpackRevertPanic(t *testing.T) {
+	revert, err := ABI{}.UnpackRevert(mustHex(t, "4e487b71"+word("11")))
+	require.NoError(t, err)
+	require.Equal(t, "Panic", revert.Kind)
+	require.Equal(t, int64(0x11), revert.PanicCode.Int64())
+	require.Equal(t, "arithmetic underflow or overflow", revert.Reason)
+}
+
+func TestUnpackRevertCustomError(t *testing.T) {
+	abi, err := ParseHumanReadable("error InsufficientBalance(uint256 available, uint256 required)")
+	require.NoError(t, err)
+	e, err := abi.LookupError("InsufficientBalance")
+	require.NoError(t, err)
+	require.Equal(t, "InsufficientBalance(uint256,uint256)", e.Sig())
+
+	data := append(e.Id(), mustHex(t, word("05")+word("0a"))...)
+	revert, err := abi.UnpackRevert(data)
+	require.NoError(t, err)
+	require.Equal(t, "Custom", revert.Kind)
+	require.Equal(t, "InsufficientBalance", revert.Error.Name)
+	require.Equal(t, "execution reverted: InsufficientBalance(5, 10)", revert.String())
+
+	_, err = abi.UnpackRevert(mustHex(t, "deadbeef"))
+	require.Error(t, err)
+}
+
+func TestOverloadedErrors(t *testing.T) {
+	abi, err := ParseHumanReadable(
+		"error Denied(uint256 code)",
+		"error Denied(string reason)",
+	)
+	require.NoError(t, err)
+	require.Len(t, abi.Errors, 2)
+	require.Len(t, abi.ErrorsByName("Denied"), 2)
+	_, err = abi.LookupError("Denied")
+	require.Error(t, err)
+	denied, err := abi.LookupError("Denied(uint256)")
+	require.NoError(t, err)
+
+	// both survive a round trip and a merge
+	data, err := abi.MarshalJSON()
+	require.NoError(t, err)
+	loaded, err := JSON(strings.NewReader(string(data)))
+	require.NoError(t, err)
+	require.Equal(t, abi.Errors, loaded.Errors)
+	merged, err := loaded.Merge(abi)
+	require.NoError(t, err)
+	require.Len(t, merged.Errors, 2)
+
+	revert, err := merged.UnpackRevert(append(denied.Id(), mustHex(t, word("07"))...))
+	require.NoError(t, err)
+	require.Equal(t, "Denied(uint256)", revert.Error.Sig())
+}
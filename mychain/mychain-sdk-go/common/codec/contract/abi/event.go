@@ -1,28 +1,39 @@
 package abi
 
+import (
+	"fmt"
+	"strings"
+
+	"github.com/oldercn/restclient-go-sdk/mychain/mychain-sdk-go/common/crypto"
+	"github.com/oldercn/restclient-go-sdk/mychain/mychain-sdk-go/domain"
+)
+
 type Event struct {
 	Name      string
 	Anonymous bool
 	Inputs    Arguments
 }
 
-//func (e Event) String() string {
-//	inputs := make([]string, len(e.Inputs))
-//	for i, input := range e.Inputs {
-//		inputs[i] = fmt.Sprintf("%v %v", input.Type, input.Name)
-//		if input.Indexed {
-//			inputs[i] = fmt.Sprintf("%v indexed %v", input.Type, input.Name)
-//		}
-//	}
-//	return fmt.Sprintf("event %v(%v)", e.Name, strings.Join(inputs, ", "))
-//}
-//
-//func (e Event) Id() domain.Hash {
-//	types := make([]string, len(e.Inputs))
-//	i := 0
-//	for _, input := range e.Inputs {
-//		types[i] = input.Type.String()
-//		i++
-//	}
-//	return domain.BytesToHash(crypto.Keccak256([]byte(fmt.Sprintf("%v(%v)", e.Name, strings.Join(types, ",")))))
-//}
+func (e Event) String() string {
+	inputs := make([]string, len(e.Inputs))
+	for i, input := range e.Inputs {
+		inputs[i] = fmt.Sprintf("%v %v", input.Type, input.Name)
+		if input.Indexed {
+			inputs[i] = fmt.Sprintf("%v indexed %v", input.Type, input.Name)
+		}
+	}
+	return fmt.Sprintf("event %v(%v)", e.Name, strings.Join(inputs, ", "))
+}
+
+// Sig returns the canonical signature of the event, e.g. Transfer(identity,identity,uint256).
+func (e Event) Sig() string {
+	types := make([]string, len(e.Inputs))
+	for i, input := range e.Inputs {
+		types[i] = input.Type.String()
+	}
+	return fmt.Sprintf("%v(%v)", e.Name, strings.Join(types, ","))
+}
+
+func (e Event) Id() domain.Hash {
+	return domain.BytesToHash(crypto.Keccak256([]byte(e.Sig())))
+}
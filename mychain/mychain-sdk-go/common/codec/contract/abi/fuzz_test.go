@@ -0,0 +1,44 @@
+//go:build go1.18
+// +build go1.18
+
+package abi
+
+import (
+	"reflect"
+	"testing"
+)
+
+// FuzzUnpack feeds arbitrary outputs to the decoder, which must return an error
+// instead of panicking or allocating beyond its input.
+func FuzzUnpack(f *testing.F) {
+	abi, err := ParseHumanReadable(
+		"function scalars() returns (uint8 a, int256 b, bool c, identity d, bytes4 e)",
+		"function dynamic() returns (bytes a, string b, uint256[] c)",
+		"function nested() returns (bytes[][] a, string[2] b, (uint8 x, bytes b)[] c, (uint16[2] a, string s) d)",
+	)
+	if err != nil {
+		f.Fatal(err)
+	}
+	// structs the outputs of the methods are unpacked into by reflection
+	structs := make(map[string]reflect.Type)
+	for sig, method := range abi.Methods {
+		fields := make([]reflect.StructField, len(method.Outputs))
+		for i, output := range method.Outputs {
+			fields[i] = reflect.StructField{Name: ToCamelCase(output.Name), Type: output.Type.Type}
+		}
+		structs[sig] = reflect.StructOf(fields)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for sig, method := range abi.Methods {
+			values, err := method.Outputs.UnpackDynamic(data)
+			if err == nil && len(values) != len(method.Outputs) {
+				t.Fatalf("%s: decoded %d values of %d outputs", method.Sig(), len(values), len(method.Outputs))
+			}
+			out := reflect.New(structs[sig])
+			if structErr := method.Outputs.Unpack(out.Interface(), data); (structErr == nil) != (err == nil) {
+				t.Fatalf("%s: unpacking into a struct returned %v, dynamically %v", method.Sig(), structErr, err)
+			}
+		}
+		_, _ = abi.UnpackRevert(data)
+	})
+}
@@ -0,0 +1,282 @@
+package abi
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseHumanReadable builds an ABI from human-readable fragments such as
+//
+//	function SayHello(bytes b, string s) view returns (bytes, string)
+//	event Transfer(identity indexed from, identity indexed to, uint256 value)
+//	error InsufficientBalance(uint256 available, uint256 required)
+//	constructor(uint256 _greeting, string a)
+//	function set((uint8 x, identity[] who)[] points)
+//
+// A fragment without a leading keyword is read as a function, so plain method
+// signatures like SayHello(bytes,string) are accepted as well. The result is the
+// same ABI that JSON produces for the equivalent json description: view and pure
+// functions are constant, as in legacy json, and a function without a modifier has
+// no stateMutability, as in json without the field.
+func ParseHumanReadable(fragments ...string) (ABI, error) {
+	fields := make([]abiField, 0, len(fragments))
+	for _, fragment := range fragments {
+		fragment = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(fragment), ";"))
+		if fragment == "" {
+			continue
+		}
+		field, err := parseFragment(fragment)
+		if err != nil {
+			return ABI{}, fmt.Errorf("abi: invalid fragment '%s': %v", fragment, err)
+		}
+		fields = append(fields, field)
+	}
+	var abi ABI
+	if err := abi.load(fields); err != nil {
+		return ABI{}, err
+	}
+	return abi, nil
+}
+
+type fragmentParser struct {
+	input string
+	pos   int
+}
+
+func parseFragment(fragment string) (abiField, error) {
+	p := &fragmentParser{input: fragment}
+	field := abiField{Type: string(Function)}
+
+	start := p.pos
+	word := p.ident()
+	switch word {
+	case "function", "event", "error", "constructor", "fallback", "receive":
+		field.Type = word
+	default:
+		// no keyword, the word is the function name
+		p.pos = start
+	}
+	if field.Type == "function" || field.Type == "event" || field.Type == "error" {
+		if field.Name = p.ident(); field.Name == "" {
+			return abiField{}, fmt.Errorf("missing name at %d", p.pos)
+		}
+	}
+
+	args, err := p.params(field.Type == "event")
+	if err != nil {
+		return abiField{}, err
+	}
+	field.Inputs, err = toArguments(args)
+	if err != nil {
+		return abiField{}, err
+	}
+
+	for {
+		p.skipSpace()
+		if p.done() {
+			break
+		}
+		modifier := p.ident()
+		switch modifier {
+		case "view", "pure":
+			field.Constant = true
+			field.StateMutability = modifier
+		case "payable", "nonpayable":
+			field.StateMutability = modifier
+		case "constant":
+			// legacy json carries no stateMutability with it
+			field.Constant = true
+		case "anonymous":
+			if field.Type != "event" {
+				return abiField{}, fmt.Errorf("anonymous is only allowed on events")
+			}
+			field.Anonymous = true
+		case "external", "public", "internal", "private", "virtual", "override":
+		case "returns":
+			outputs, err := p.params(false)
+			if err != nil {
+				return abiField{}, err
+			}
+			if field.Outputs, err = toArguments(outputs); err != nil {
+				return abiField{}, err
+			}
+		case "":
+			return abiField{}, fmt.Errorf("unexpected '%c' at %d", p.input[p.pos], p.pos)
+		default:
+			return abiField{}, fmt.Errorf("unknown modifier '%s'", modifier)
+		}
+	}
+
+	if field.Type == "error" && (field.StateMutability != "" || field.Outputs != nil) {
+		return abiField{}, fmt.Errorf("errors take no modifiers")
+	}
+	if field.Type != "event" && field.Type != "error" {
+		// like json without stateMutability, no modifier leaves it empty
+		field.Payable = field.StateMutability == "payable"
+		if field.Type == "function" && field.Outputs == nil {
+			field.Outputs = []Argument{}
+		}
+	}
+	return field, nil
+}
+
+func toArguments(args []ArgumentMarshaling) ([]Argument, error) {
+	arguments := make([]Argument, 0, len(args))
+	for _, arg := range args {
+		argument, err := newArgument(arg)
+		if err != nil {
+			return nil, err
+		}
+		arguments = append(arguments, argument)
+	}
+	return arguments, nil
+}
+
+// params parses a parenthesised, comma separated parameter list.
+func (p *fragmentParser) params(allowIndexed bool) ([]ArgumentMarshaling, error) {
+	if err := p.expect('('); err != nil {
+		return nil, err
+	}
+	args := make([]ArgumentMarshaling, 0)
+	p.skipSpace()
+	if p.peek() == ')' {
+		p.pos++
+		return args, nil
+	}
+	for {
+		arg, err := p.param(allowIndexed)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ')':
+			p.pos++
+			return args, nil
+		default:
+			return nil, fmt.Errorf("expect ',' or ')' at %d", p.pos)
+		}
+	}
+}
+
+// param parses a single parameter: a type followed by optional modifiers and a name.
+func (p *fragmentParser) param(allowIndexed bool) (ArgumentMarshaling, error) {
+	var arg ArgumentMarshaling
+	p.skipSpace()
+	start := p.pos
+	if p.peek() == '(' || p.ident() == "tuple" {
+		components, err := p.params(false)
+		if err != nil {
+			return arg, err
+		}
+		arg.Type = "tuple"
+		arg.Components = components
+	} else {
+		p.pos = start
+		arg.Type = normalizeType(p.ident())
+		if arg.Type == "" {
+			return arg, fmt.Errorf("missing type at %d", p.pos)
+		}
+	}
+	suffix, err := p.arraySuffix()
+	if err != nil {
+		return arg, err
+	}
+	arg.Type += suffix
+
+	for {
+		p.skipSpace()
+		if c := p.peek(); c == ',' || c == ')' || p.done() {
+			return arg, nil
+		}
+		word := p.ident()
+		switch word {
+		case "indexed":
+			if !allowIndexed {
+				return arg, fmt.Errorf("indexed is only allowed on event parameters")
+			}
+			arg.Indexed = true
+		case "memory", "calldata", "storage", "payable":
+		case "":
+			return arg, fmt.Errorf("unexpected '%c' at %d", p.peek(), p.pos)
+		default:
+			if arg.Name != "" {
+				return arg, fmt.Errorf("unexpected '%s' after parameter name '%s'", word, arg.Name)
+			}
+			arg.Name = word
+		}
+	}
+}
+
+func (p *fragmentParser) arraySuffix() (string, error) {
+	suffix := ""
+	for p.peek() == '[' {
+		end := strings.IndexByte(p.input[p.pos:], ']')
+		if end < 0 {
+			return "", fmt.Errorf("unclosed '[' at %d", p.pos)
+		}
+		size := p.input[p.pos+1 : p.pos+end]
+		for _, c := range size {
+			if c < '0' || c > '9' {
+				return "", fmt.Errorf("invalid array size '%s'", size)
+			}
+		}
+		suffix += p.input[p.pos : p.pos+end+1]
+		p.pos += end + 1
+	}
+	return suffix, nil
+}
+
+// normalizeType expands the solidity aliases to their canonical abi names.
+func normalizeType(typ string) string {
+	switch typ {
+	case "uint", "int":
+		return typ + "256"
+	case "byte":
+		return "bytes1"
+	}
+	return typ
+}
+
+func (p *fragmentParser) ident() string {
+	p.skipSpace()
+	start := p.pos
+	for !p.done() {
+		c := p.input[p.pos]
+		if c == '_' || c == '$' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+			p.pos++
+			continue
+		}
+		break
+	}
+	return p.input[start:p.pos]
+}
+
+func (p *fragmentParser) expect(c byte) error {
+	p.skipSpace()
+	if p.peek() != c {
+		return fmt.Errorf("expect '%c' at %d", c, p.pos)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *fragmentParser) skipSpace() {
+	for !p.done() && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t' || p.input[p.pos] == '\n' || p.input[p.pos] == '\r') {
+		p.pos++
+	}
+}
+
+func (p *fragmentParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+func (p *fragmentParser) done() bool {
+	return p.pos >= len(p.input)
+}
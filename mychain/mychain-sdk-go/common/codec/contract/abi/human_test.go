@@ -0,0 +1,79 @@
+package abi
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHumanReadableMatchesJSON(t *testing.T) {
+	expect, err := JSON(strings.NewReader(`[
+  {"type":"constructor","inputs":[{"name":"_greeting","type":"uint256"},{"name":"a","type":"string"}]},
+  {"type":"function","name":"SayHello","constant":true,"inputs":[{"name":"b","type":"bytes"},{"name":"s","type":"string"}],"outputs":[{"name":"","type":"bytes"},{"name":"","type":"string"}],"stateMutability":"view"},
+  {"type":"function","name":"set","inputs":[{"name":"points","type":"tuple[]","components":[{"name":"x","type":"uint8"},{"name":"who","type":"identity[2]"}]}],"outputs":[],"stateMutability":"payable","payable":true},
+  {"type":"event","name":"Transfer","anonymous":false,"inputs":[{"name":"from","type":"identity","indexed":true},{"name":"to","type":"identity","indexed":true},{"name":"value","type":"uint256"}]}
+]`))
+	require.NoError(t, err)
+
+	abi, err := ParseHumanReadable(
+		"constructor(uint _greeting, string memory a)",
+		"function SayHello(bytes b, string s) external view returns (bytes, string)",
+		"function set((uint8 x, identity[2] who)[] calldata points) payable;",
+		"event Transfer(identity indexed from, identity indexed to, uint256 value)",
+	)
+	require.NoError(t, err)
+	require.Equal(t, expect, abi)
+
+	abi, err = ParseHumanReadable("function set(tuple(uint8 x, identity[2] who)[] points) payable")
+	require.NoError(t, err)
+	require.Equal(t, expect.Methods["set((uint8,identity[2])[])"], abi.Methods["set((uint8,identity[2])[])"])
+}
+
+func TestParseHumanReadableLegacyJSON(t *testing.T) {
+	// json from before stateMutability
+	expect, err := JSON(strings.NewReader(`[
+  {"type":"function","name":"get","constant":true,"inputs":[],"outputs":[{"name":"","type":"uint256"}],"payable":false},
+  {"type":"function","name":"set","constant":false,"inputs":[{"name":"v","type":"uint256"}],"outputs":[],"payable":false}
+]`))
+	require.NoError(t, err)
+
+	abi, err := ParseHumanReadable(
+		"function get() constant returns (uint256)",
+		"function set(uint256 v)",
+	)
+	require.NoError(t, err)
+	require.Equal(t, expect, abi)
+
+	// view and pure are constant too
+	abi, err = ParseHumanReadable("function get() view returns (uint256)", "function add(uint256 a) pure returns (uint256)")
+	require.NoError(t, err)
+	for _, method := range abi.Methods {
+		require.Truef(t, method.Const, "%v is not constant", method.Sig())
+	}
+}
+
+func TestParseHumanReadableSignature(t *testing.T) {
+	abi, err := ParseHumanReadable("SayHello(bytes,string)")
+	require.NoError(t, err)
+	method, err := abi.LookupMethod("SayHello")
+	require.NoError(t, err)
+	require.Equal(t, "SayHello(bytes,string)", method.Sig())
+	require.Empty(t, method.Outputs)
+}
+
+func TestParseHumanReadableErrors(t *testing.T) {
+	for _, fragment := range []string{
+		"function (uint256 a)",
+		"function f(uint256 a",
+		"function f(uint256 indexed a)",
+		"function f(uint256[x] a)",
+		"function f(uint256 a b)",
+		"function f() returns uint256",
+		"function f() sometimes",
+		"event E(uint256 a) view returns",
+	} {
+		_, err := ParseHumanReadable(fragment)
+		require.Errorf(t, err, "fragment %q should fail", fragment)
+	}
+}
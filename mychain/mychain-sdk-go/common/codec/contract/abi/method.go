@@ -1,39 +1,75 @@
 package abi
 
+import (
+	"fmt"
+	"strings"
+
+	"github.com/oldercn/restclient-go-sdk/mychain/mychain-sdk-go/common/crypto"
+)
+
+type FunctionType string
+
+const (
+	Function    FunctionType = "function"
+	Constructor FunctionType = "constructor"
+	Fallback    FunctionType = "fallback"
+	Receive     FunctionType = "receive"
+)
+
 type Method struct {
-	Name    string
-	Const   bool
-	Inputs  Arguments
-	Outputs Arguments
+	Name            string
+	Type            FunctionType
+	Const           bool
+	StateMutability string
+	Payable         bool
+	Inputs          Arguments
+	Outputs         Arguments
 }
 
-//func (method Method) Id() []byte {
-//	return crypto.Keccak256([]byte(method.Sig()))[:4]
-//}
-//
-//func (method Method) String() string {
-//	inputs := make([]string, len(method.Inputs))
-//	for i, input := range method.Inputs {
-//		inputs[i] = fmt.Sprintf("%v %v", input.Type, input.Name)
-//	}
-//	outputs := make([]string, len(method.Outputs))
-//	for i, output := range method.Outputs {
-//		outputs[i] = output.Type.String()
-//		if len(output.Name) > 0 {
-//			outputs[i] += fmt.Sprintf(" %v", output.Name)
-//		}
-//	}
-//	constant := ""
-//	if method.Const {
-//		constant = "constant "
-//	}
-//	return fmt.Sprintf("function %v(%v) %sreturns(%v)", method.Name, strings.Join(inputs, ", "), constant, strings.Join(outputs, ", "))
-//}
-//
-//func (method Method) Sig() string {
-//	types := make([]string, len(method.Inputs))
-//	for i, input := range method.Inputs {
-//		types[i] = input.Type.String()
-//	}
-//	return fmt.Sprintf("%v(%v)", method.Name, strings.Join(types, ","))
-//}
\ No newline at end of file
+func (method Method) Id() []byte {
+	return crypto.Keccak256([]byte(method.Sig()))[:4]
+}
+
+// IsConstant reports whether the method does not modify the contract state.
+func (method Method) IsConstant() bool {
+	return method.Const || method.StateMutability == "view" || method.StateMutability == "pure"
+}
+
+// IsPayable reports whether the method accepts value transfers.
+func (method Method) IsPayable() bool {
+	return method.Payable || method.StateMutability == "payable"
+}
+
+func (method Method) String() string {
+	inputs := make([]string, len(method.Inputs))
+	for i, input := range method.Inputs {
+		inputs[i] = fmt.Sprintf("%v %v", input.Type, input.Name)
+	}
+	outputs := make([]string, len(method.Outputs))
+	for i, output := range method.Outputs {
+		outputs[i] = output.Type.String()
+		if len(output.Name) > 0 {
+			outputs[i] += fmt.Sprintf(" %v", output.Name)
+		}
+	}
+	mutability := ""
+	if method.StateMutability != "" && method.StateMutability != "nonpayable" {
+		mutability = method.StateMutability + " "
+	} else if method.Const {
+		mutability = "constant "
+	}
+	switch method.Type {
+	case Constructor, Fallback, Receive:
+		return fmt.Sprintf("%v(%v) %v", method.Type, strings.Join(inputs, ", "), strings.TrimSpace(mutability))
+	}
+	return fmt.Sprintf("function %v(%v) %sreturns(%v)", method.Name, strings.Join(inputs, ", "), mutability, strings.Join(outputs, ", "))
+}
+
+// Sig returns the canonical signature of the method, e.g. SayHello(bytes,string).
+func (method Method) Sig() string {
+	types := make([]string, len(method.Inputs))
+	for i, input := range method.Inputs {
+		types[i] = input.Type.String()
+	}
+	return fmt.Sprintf("%v(%v)", method.Name, strings.Join(types, ","))
+}
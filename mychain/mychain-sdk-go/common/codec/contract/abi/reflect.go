@@ -45,32 +45,151 @@ func mustArrayToByteSlice(value reflect.Value) reflect.Value {
 	return slice
 }
 
-func set(dst, src reflect.Value) error {
-	dstType, srcType := dst.Type(), src.Type()
+// unpackValue copies the decoded value src of abi type t into dst, following
+// nested tuples, slices and arrays. Tuples go into structs, matched by abi tag or
+// camel cased name, or into maps keyed by the component names.
+func unpackValue(t *Type, dst, src reflect.Value, path string) error {
 	switch {
-	case dstType.Kind() == reflect.Interface && dst.Elem().IsValid():
-		return set(dst.Elem(), src)
-	case dstType.Kind() == reflect.Ptr && dstType.Elem() != derefbigT:
-		return set(dst.Elem(), src)
-	case srcType.AssignableTo(dstType) && dst.CanSet():
+	case dst.Kind() == reflect.Interface:
+		if dst.Elem().IsValid() && dst.Elem().Kind() == reflect.Ptr {
+			return unpackValue(t, dst.Elem(), src, path)
+		}
+		if dst.NumMethod() != 0 || !dst.CanSet() {
+			return fmt.Errorf("abi: %s: cannot unmarshal %v in to %v", path, src.Type(), dst.Type())
+		}
+		dst.Set(reflect.ValueOf(genericValue(t, src)))
+		return nil
+	case dst.Kind() == reflect.Ptr && !(src.Type().AssignableTo(dst.Type()) && dst.CanSet()):
+		if dst.IsNil() {
+			if !dst.CanSet() {
+				return fmt.Errorf("abi: %s: cannot unmarshal %v in to nil %v", path, src.Type(), dst.Type())
+			}
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return unpackValue(t, dst.Elem(), src, path)
+	case !dst.CanSet():
+		return fmt.Errorf("abi: %s: cannot unmarshal %v in to unaddressable %v", path, src.Type(), dst.Type())
+	}
+
+	switch t.T {
+	case TupleTy:
+		return unpackTupleValue(t, dst, src, path)
+	case SliceTy, ArrayTy:
+		if src.Type().AssignableTo(dst.Type()) {
+			dst.Set(src)
+			return nil
+		}
+		switch dst.Kind() {
+		case reflect.Slice:
+			dst.Set(reflect.MakeSlice(dst.Type(), src.Len(), src.Len()))
+		case reflect.Array:
+			if dst.Len() != src.Len() {
+				return fmt.Errorf("abi: %s: cannot unmarshal %d elements in to %v", path, src.Len(), dst.Type())
+			}
+		default:
+			return fmt.Errorf("abi: %s: cannot unmarshal %v in to %v, want slice or array", path, src.Type(), dst.Type())
+		}
+		for i := 0; i < src.Len(); i++ {
+			if err := unpackValue(t.Elem, dst.Index(i), src.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	switch srcType, dstType := src.Type(), dst.Type(); {
+	case srcType.AssignableTo(dstType):
 		dst.Set(src)
-	case dstType.Kind() == reflect.Slice && srcType.Kind() == reflect.Slice:
-		return setSlice(dst, src)
+	case srcType == bigT && dstType == derefbigT:
+		dst.Set(src.Elem())
+	case srcType.Kind() == dstType.Kind() && srcType.Kind() != reflect.Array && srcType.ConvertibleTo(dstType):
+		// named types of the same kind, e.g. type Balance uint64
+		dst.Set(src.Convert(dstType))
+	case srcType.Kind() == reflect.Array && srcType.Elem().Kind() == reflect.Uint8 &&
+		dstType.Kind() == reflect.Array && dstType.Elem().Kind() == reflect.Uint8 && dst.Len() == src.Len():
+		reflect.Copy(dst, src)
+	case srcType.Kind() == reflect.Array && srcType.Elem().Kind() == reflect.Uint8 &&
+		dstType.Kind() == reflect.Slice && dstType.Elem().Kind() == reflect.Uint8:
+		dst.Set(mustArrayToByteSlice(src).Convert(dstType))
 	default:
-		return fmt.Errorf("abi: cannot unmarshal %v in to %v", src.Type(), dst.Type())
+		return fmt.Errorf("abi: %s: cannot unmarshal %v in to %v", path, srcType, dstType)
 	}
 	return nil
 }
 
-func setSlice(dst, src reflect.Value) error {
-	slice := reflect.MakeSlice(dst.Type(), src.Len(), src.Len())
-	for i := 0; i < src.Len(); i++ {
-		v := src.Index(i)
-		reflect.Copy(slice.Index(i), v)
+func unpackTupleValue(t *Type, dst, src reflect.Value, path string) error {
+	switch dst.Kind() {
+	case reflect.Struct:
+		fieldmap, err := mapArgNamesToStructFields(t.TupleRawNames, dst)
+		if err != nil {
+			return fmt.Errorf("abi: %s: %v", path, err)
+		}
+		for i, elem := range t.TupleElems {
+			name := t.TupleRawNames[i]
+			field := dst.FieldByName(fieldmap[name])
+			if !field.IsValid() {
+				return fmt.Errorf("abi: %s: field %s can't be found in %v", path, name, dst.Type())
+			}
+			if err := unpackValue(elem, field, src.Field(i), joinPath(path, name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		if dst.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("abi: %s: cannot unmarshal tuple in to %v, want string keys", path, dst.Type())
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMap(dst.Type()))
+		}
+		for i, elem := range t.TupleElems {
+			name := t.TupleRawNames[i]
+			value := reflect.New(dst.Type().Elem()).Elem()
+			if err := unpackValue(elem, value, src.Field(i), joinPath(path, name)); err != nil {
+				return err
+			}
+			dst.SetMapIndex(reflect.ValueOf(name).Convert(dst.Type().Key()), value)
+		}
+		return nil
+	}
+	return fmt.Errorf("abi: %s: cannot unmarshal tuple in to %v, want struct or map", path, dst.Type())
+}
+
+// genericValue converts a decoded value for an interface{} destination, tuples
+// become map[string]interface{} and lists holding tuples []interface{}.
+func genericValue(t *Type, src reflect.Value) interface{} {
+	if !containsTuple(t) {
+		return src.Interface()
 	}
+	if t.T == TupleTy {
+		fields := make(map[string]interface{}, len(t.TupleElems))
+		for i, elem := range t.TupleElems {
+			fields[t.TupleRawNames[i]] = genericValue(elem, src.Field(i))
+		}
+		return fields
+	}
+	list := make([]interface{}, src.Len())
+	for i := range list {
+		list[i] = genericValue(t.Elem, src.Index(i))
+	}
+	return list
+}
 
-	dst.Set(slice)
-	return nil
+func containsTuple(t *Type) bool {
+	switch t.T {
+	case TupleTy:
+		return true
+	case SliceTy, ArrayTy:
+		return containsTuple(t.Elem)
+	}
+	return false
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
 }
 
 func requireAssignable(dst, src reflect.Value) error {
@@ -0,0 +1,78 @@
+package abi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnpackNestedTuple(t *testing.T) {
+	abi, err := ParseHumanReadable("function get() view returns ((uint8 x, (bool ok, uint16 n) inner) p)")
+	require.NoError(t, err)
+	data := mustHex(t, word("07")+word("01")+word("05"))
+
+	type inner struct {
+		Ok    bool
+		Count uint16 `abi:"n"`
+	}
+	var point struct {
+		X     uint8
+		Inner *inner
+	}
+	require.NoError(t, abi.Unpack(&point, "get", data))
+	require.Equal(t, uint8(7), point.X)
+	require.Equal(t, &inner{Ok: true, Count: 5}, point.Inner)
+
+	var fields map[string]interface{}
+	require.NoError(t, abi.Unpack(&fields, "get", data))
+	require.Equal(t, map[string]interface{}{"x": uint8(7), "inner": map[string]interface{}{"ok": true, "n": uint16(5)}}, fields)
+
+	var generic interface{}
+	require.NoError(t, abi.Unpack(&generic, "get", data))
+	require.Equal(t, fields, generic)
+
+	var mismatch struct {
+		X     uint8
+		Inner struct {
+			Ok bool
+			N  string
+		}
+	}
+	err = abi.Unpack(&mismatch, "get", data)
+	require.EqualError(t, err, "abi: p.inner.n: cannot unmarshal uint16 in to string")
+}
+
+func TestUnpackMultiDimensionalTuples(t *testing.T) {
+	abi, err := ParseHumanReadable("function get() view returns ((uint8 x, bool ok)[2][] grid, uint64 total)")
+	require.NoError(t, err)
+	data := mustHex(t, word("40")+word("03")+
+		word("02")+
+		word("01")+word("01")+word("02")+word("00")+
+		word("03")+word("01")+word("04")+word("00"))
+
+	type cell struct {
+		X  uint8
+		Ok bool
+	}
+	var out struct {
+		Grid  [][]cell
+		Total uint64
+	}
+	require.NoError(t, abi.Unpack(&out, "get", data))
+	require.Equal(t, [][]cell{{{1, true}, {2, false}}, {{3, true}, {4, false}}}, out.Grid)
+	require.Equal(t, uint64(3), out.Total)
+
+	var fixed struct {
+		Grid  [][2]cell
+		Total uint64
+	}
+	require.NoError(t, abi.Unpack(&fixed, "get", data))
+	require.Equal(t, [][2]cell{{{1, true}, {2, false}}, {{3, true}, {4, false}}}, fixed.Grid)
+
+	var short struct {
+		Grid  [][1]cell
+		Total uint64
+	}
+	err = abi.Unpack(&short, "get", data)
+	require.EqualError(t, err, "abi: grid[0]: cannot unmarshal 2 elements in to [1]abi.cell")
+}
@@ -33,6 +33,8 @@ type Type struct {
 	T    byte
 
 	stringKind string
+	rawType    string
+	components []ArgumentMarshaling
 
 	TupleElems    []*Type
 	TupleRawNames []string
@@ -48,6 +50,10 @@ func NewType(myType string, myArgs []ArgumentMarshaling) (finalType Type, err er
 	}
 
 	finalType.stringKind = myType
+	finalType.rawType = myType
+	if strings.HasPrefix(myType, "tuple") {
+		finalType.components = myArgs
+	}
 
 	if strings.Count(myType, "[") != 0 {
 		i := strings.LastIndex(myType, "[")
@@ -67,6 +73,10 @@ func NewType(myType string, myArgs []ArgumentMarshaling) (finalType Type, err er
 			if err != nil {
 				return Type{}, fmt.Errorf("abi: error parsing variable size: %v", err)
 			}
+			if finalType.Size == 0 {
+				// takes no bytes, the decoder could not tell how many of them a slice holds
+				return Type{}, fmt.Errorf("abi: zero length array type '%v'", myType)
+			}
 			finalType.Type = reflect.ArrayOf(finalType.Size, embeddedType.Type)
 			if embeddedType.T == TupleTy {
 				finalType.stringKind = embeddedType.stringKind + sliced
@@ -147,6 +157,9 @@ func NewType(myType string, myArgs []ArgumentMarshaling) (finalType Type, err er
 			names      []string
 			expression string
 		)
+		if len(myArgs) == 0 {
+			return Type{}, errors.New("abi: empty tuple is not supported")
+		}
 		expression += "("
 		for idx, c := range myArgs {
 			cType, err := NewType(c.Type, c.Components)
@@ -272,10 +285,7 @@ func isDynamicType(t Type) bool {
 
 func getTypeSize(t Type) int {
 	if t.T == ArrayTy && !isDynamicType(*t.Elem) {
-		if t.Elem.T == ArrayTy {
-			return t.Size * getTypeSize(*t.Elem)
-		}
-		return t.Size * 32
+		return t.Size * getTypeSize(*t.Elem)
 	} else if t.T == TupleTy && !isDynamicType(t) {
 		total := 0
 		for _, elem := range t.TupleElems {
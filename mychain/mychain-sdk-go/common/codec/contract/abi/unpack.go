@@ -3,7 +3,6 @@ package abi
 import (
 	"encoding/binary"
 	"fmt"
-	"github.com/oldercn/restclient-go-sdk/mychain/mychain-sdk-go/common/codec"
 	"github.com/oldercn/restclient-go-sdk/mychain/mychain-sdk-go/domain"
 	"math/big"
 	"reflect"
@@ -51,37 +50,80 @@ func readInteger(myType byte, myKind reflect.Kind, myBytes []byte) interface{} {
 	}
 }
 
-func lengthPrefixPointsTo(myIndex int, inputBytets []byte) (finalStart int, finalLen int, err error) {
-	bigOffsetEnd := big.NewInt(0).SetBytes(inputBytets[myIndex : myIndex+32])
-	bigOffsetEnd.Add(bigOffsetEnd, codec.Big32)
-	outputLength := big.NewInt(int64(len(inputBytets)))
+// decoder walks an abi encoded input. Every decoded word and byte is charged
+// against budget, which starts at the input length: a well formed encoding never
+// decodes to more than it holds, while offsets shared by several elements would,
+// so such inputs are rejected before they can blow up the allocations.
+type decoder struct {
+	budget int
+}
 
-	if bigOffsetEnd.Cmp(outputLength) > 0 {
-		return 0, 0, fmt.Errorf("abi: cannot marshal in to go slice: offset %v would go over slice boundary (len=%v)",
-			bigOffsetEnd, outputLength)
-	}
+func newDecoder(output []byte) *decoder {
+	return &decoder{budget: len(output)}
+}
 
-	if bigOffsetEnd.BitLen() > 63 {
-		return 0, 0, fmt.Errorf("abi offset larger than int64: %v", bigOffsetEnd)
+func (d *decoder) charge(size int) error {
+	if size > d.budget {
+		return fmt.Errorf("abi: cannot marshal in to go type: decoded value is larger than its input, offsets overlap")
 	}
+	d.budget -= size
+	return nil
+}
 
-	offsetEnd := int(bigOffsetEnd.Uint64())
-	lengthBig := big.NewInt(0).SetBytes(inputBytets[offsetEnd-32 : offsetEnd])
+// block describes the encoding offsets are relative to: its static head takes
+// head bytes, the tails follow in order and last is the latest offset read.
+type block struct {
+	head, last int
+}
+
+func newBlock(head int) *block {
+	return &block{head: head, last: -1}
+}
 
-	totalSize := big.NewInt(0)
-	totalSize.Add(totalSize, bigOffsetEnd)
-	totalSize.Add(totalSize, lengthBig)
-	if totalSize.BitLen() > 63 {
-		return 0, 0, fmt.Errorf("abi: length larger than int64: %v", totalSize)
+// offsetAt reads the offset stored in the word at index. The offset must point
+// past the static head of blk and inside output, and beyond every offset read
+// before it, so two values never share or overlap a tail.
+func offsetAt(index int, blk *block, output []byte) (int, error) {
+	if index < 0 || index+32 > len(output) {
+		return 0, fmt.Errorf("abi: cannot marshal in to go type: length insufficient %d require %d", len(output), index+32)
 	}
+	offset := big.NewInt(0).SetBytes(output[index : index+32])
+	if offset.BitLen() > 63 {
+		return 0, fmt.Errorf("abi offset larger than int64: %v", offset)
+	}
+	if offset.Cmp(big.NewInt(int64(len(output)))) > 0 {
+		return 0, fmt.Errorf("abi: cannot marshal in to go slice: offset %v would go over slice boundary (len=%v)", offset, len(output))
+	}
+	if offset.Int64() < int64(blk.head) {
+		return 0, fmt.Errorf("abi: cannot marshal in to go type: offset %v at %d points in to the static head (len=%d)", offset, index, blk.head)
+	}
+	if offset.Int64() <= int64(blk.last) {
+		return 0, fmt.Errorf("abi: cannot marshal in to go type: offset %v at %d overlaps the previous tail at %d", offset, index, blk.last)
+	}
+	blk.last = int(offset.Int64())
+	return blk.last, nil
+}
 
-	if totalSize.Cmp(outputLength) > 0 {
+func lengthPrefixPointsTo(myIndex int, blk *block, inputBytets []byte) (finalStart int, finalLen int, err error) {
+	offset, err := offsetAt(myIndex, blk, inputBytets)
+	if err != nil {
+		return 0, 0, err
+	}
+	if offset+32 > len(inputBytets) {
+		return 0, 0, fmt.Errorf("abi: cannot marshal in to go slice: offset %v would go over slice boundary (len=%v)",
+			offset+32, len(inputBytets))
+	}
+	lengthBig := big.NewInt(0).SetBytes(inputBytets[offset : offset+32])
+	remaining := int64(len(inputBytets) - offset - 32)
+	if lengthBig.BitLen() > 63 || lengthBig.Int64() > remaining {
 		return 0, 0, fmt.Errorf("abi: cannot marshal in to go type: length insufficient %v require %v",
-			outputLength, totalSize)
+			len(inputBytets), lengthBig.String())
 	}
-	finalStart = int(bigOffsetEnd.Uint64())
-	finalLen = int(lengthBig.Uint64())
-	return
+	return offset + 32, int(lengthBig.Int64()), nil
+}
+
+func tuplePointsTo(index int, blk *block, output []byte) (start int, err error) {
+	return offsetAt(index, blk, output)
 }
 
 func readFunctionType(myType Type, word []byte) (funcTy [24]byte, err error) {
@@ -96,37 +138,29 @@ func readFunctionType(myType Type, word []byte) (funcTy [24]byte, err error) {
 	return
 }
 
-func forTupleUnpack(t Type, output []byte) (interface{}, error) {
+func (d *decoder) forTupleUnpack(t Type, output []byte) (interface{}, error) {
 	retval := reflect.New(t.Type).Elem()
+	head := 0
+	for _, elem := range t.TupleElems {
+		head += getTypeSize(*elem)
+	}
+	blk := newBlock(head)
 	virtualArgs := 0
 	for index, elem := range t.TupleElems {
-		marshalledValue, err := toGoType((index+virtualArgs)*32, *elem, output)
+		marshalledValue, err := d.toGoType((index+virtualArgs)*32, blk, *elem, output)
 		if elem.T == ArrayTy && !isDynamicType(*elem) {
 			virtualArgs += getTypeSize(*elem)/32 - 1
 		} else if elem.T == TupleTy && !isDynamicType(*elem) {
 			virtualArgs += getTypeSize(*elem)/32 - 1
 		}
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("%v (tuple field %s)", err, t.TupleRawNames[index])
 		}
 		retval.Field(index).Set(reflect.ValueOf(marshalledValue))
 	}
 	return retval.Interface(), nil
 }
 
-func tuplePointsTo(index int, output []byte) (start int, err error) {
-	offset := big.NewInt(0).SetBytes(output[index : index+32])
-	outputLen := big.NewInt(int64(len(output)))
-
-	if offset.Cmp(big.NewInt(int64(len(output)))) > 0 {
-		return 0, fmt.Errorf("abi: cannot marshal in to go slice: offset %v would go over slice boundary (len=%v)", offset, outputLen)
-	}
-	if offset.BitLen() > 63 {
-		return 0, fmt.Errorf("abi offset larger than int64: %v", offset)
-	}
-	return int(offset.Uint64()), nil
-}
-
 func readFixedBytes(myType Type, word []byte) (interface{}, error) {
 	if myType.T != FixedBytesTy {
 		return nil, fmt.Errorf("abi: invalid type in call to make fixed byte array")
@@ -154,8 +188,15 @@ func readBool(word []byte) (bool, error) {
 	}
 }
 
+// toGoType decodes a single value of myType whose head word is at index.
 func toGoType(index int, myType Type, output []byte) (interface{}, error) {
-	if index+32 > len(output) {
+	return newDecoder(output).toGoType(index, newBlock(getTypeSize(myType)), myType, output)
+}
+
+// toGoType decodes the value of myType whose head word is at index of output,
+// the encoding described by blk.
+func (d *decoder) toGoType(index int, blk *block, myType Type, output []byte) (interface{}, error) {
+	if index < 0 || index+32 > len(output) {
 		return nil, fmt.Errorf("abi: cannot marshal in to go type: length insufficient %d require %d", len(output), index+32)
 	}
 	var (
@@ -164,32 +205,57 @@ func toGoType(index int, myType Type, output []byte) (interface{}, error) {
 		err           error
 	)
 	if myType.requiresLengthPrefix() {
-		begin, length, err = lengthPrefixPointsTo(index, output)
+		begin, length, err = lengthPrefixPointsTo(index, blk, output)
 		if err != nil {
 			return nil, err
 		}
+		if err := d.charge(32); err != nil {
+			return nil, err
+		}
 	} else {
 		returnOutput = output[index : index+32]
 	}
 	switch myType.T {
 	case TupleTy:
 		if isDynamicType(myType) {
-			begin, err := tuplePointsTo(index, output)
+			begin, err := tuplePointsTo(index, blk, output)
 			if err != nil {
 				return nil, err
 			}
-			return forTupleUnpack(myType, output[begin:])
-		} else {
-			return forTupleUnpack(myType, output[index:])
+			if err := d.charge(32); err != nil {
+				return nil, err
+			}
+			return d.forTupleUnpack(myType, output[begin:])
 		}
+		return d.forTupleUnpack(myType, output[index:])
 	case SliceTy:
-		return forEachUnpack(myType, output[begin:], 0, length)
+		return d.forEachUnpack(myType, output[begin:], 0, length)
 	case ArrayTy:
 		if isDynamicType(*myType.Elem) {
-			offset := int64(binary.BigEndian.Uint64(returnOutput[len(returnOutput)-8:]))
-			return forEachUnpack(myType, output[offset:], 0, myType.Size)
+			offset, err := offsetAt(index, blk, output)
+			if err != nil {
+				return nil, err
+			}
+			if err := d.charge(32); err != nil {
+				return nil, err
+			}
+			return d.forEachUnpack(myType, output[offset:], 0, myType.Size)
+		}
+		return d.forEachUnpack(myType, output[index:], 0, myType.Size)
+	case BytesTy, StringTy:
+		if err := d.charge(length); err != nil {
+			return nil, err
+		}
+		if myType.T == StringTy {
+			return string(output[begin : begin+length]), nil
 		}
-		return forEachUnpack(myType, output[index:], 0, myType.Size)
+		return output[begin : begin+length], nil
+	}
+
+	if err := d.charge(32); err != nil {
+		return nil, err
+	}
+	switch myType.T {
 	case BoolTy:
 		return readBool(returnOutput)
 	case IntTy, UintTy:
@@ -200,45 +266,45 @@ func toGoType(index int, myType Type, output []byte) (interface{}, error) {
 		return domain.BytesToHash(returnOutput), nil
 	case IdentityTy:
 		return domain.BytesToIdentity(returnOutput), nil
-	case BytesTy:
-		return output[begin : begin+length], nil
 	case FunctionTy:
 		return readFunctionType(myType, returnOutput)
-	case StringTy:
-		return string(output[begin : begin+length]), nil
 	default:
 		return nil, fmt.Errorf("abi: unknown type %v", myType.T)
 	}
 }
 
-func forEachUnpack(myType Type, myOutputBytes []byte, myStart, size int) (interface{}, error) {
+func (d *decoder) forEachUnpack(myType Type, myOutputBytes []byte, myStart, size int) (interface{}, error) {
 	if size < 0 {
 		return nil, fmt.Errorf("cannot marshal input to array, size is negative (%d)", size)
 	}
-	if myStart+32*size > len(myOutputBytes) {
-		return nil, fmt.Errorf("abi: cannot marshal in to go array: offset %d would go over slice boundary (len=%d)", len(myOutputBytes), myStart+32*size)
+	if myType.T != SliceTy && myType.T != ArrayTy {
+		return nil, fmt.Errorf("abi: invalid type in array/slice unpacking stage")
+	}
+	elemSize := getTypeSize(*myType.Elem)
+	if elemSize == 0 {
+		return nil, fmt.Errorf("abi: cannot unpack %v, its elements take no bytes", myType)
+	}
+	// checked before allocating, size may be any length prefix read from the input
+	if size > (len(myOutputBytes)-myStart)/elemSize {
+		return nil, fmt.Errorf("abi: cannot marshal in to go array: %d elements of %d bytes would go over slice boundary (len=%d)", size, elemSize, len(myOutputBytes)-myStart)
 	}
 
 	var refSlice reflect.Value
-
 	if myType.T == SliceTy {
 		refSlice = reflect.MakeSlice(myType.Type, size, size)
-	} else if myType.T == ArrayTy {
-		refSlice = reflect.New(myType.Type).Elem()
 	} else {
-		return nil, fmt.Errorf("abi: invalid type in array/slice unpacking stage")
+		refSlice = reflect.New(myType.Type).Elem()
 	}
 
-	elemSize := getTypeSize(*myType.Elem)
-
+	blk := newBlock(size * elemSize)
 	for i, j := myStart, 0; j < size; i, j = i+elemSize, j+1 {
-		inter, err := toGoType(i, *myType.Elem, myOutputBytes)
+		inter, err := d.toGoType(i, blk, *myType.Elem, myOutputBytes)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("%v (element %d)", err, j)
 		}
 
 		refSlice.Index(j).Set(reflect.ValueOf(inter))
 	}
 
 	return refSlice.Interface(), nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,83 @@
+package abi
+
+import (
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnpackMalformed(t *testing.T) {
+	tests := []struct {
+		name    string
+		outputs string
+		data    string
+		err     string
+	}{
+		{"short head", "uint256", "01", "length insufficient"},
+		{"offset out of range", "bytes", word("40"), "would go over slice boundary"},
+		{"offset over int64", "bytes", strings.Repeat("f", 64), "larger than int64"},
+		{"offset in to head", "bytes", word("00") + word("00"), "points in to the static head"},
+		{"length out of range", "bytes", word("20") + word("ff"), "length insufficient"},
+		{"huge length", "uint256[]", word("20") + strings.Repeat("f", 64), "length insufficient"},
+		{"elements over boundary", "(uint256 a, uint256 b)[]", word("20") + word("01") + word("01"), "would go over slice boundary"},
+		{"dynamic array offset out of range", "string[2]", word("ffff"), "would go over slice boundary"},
+		{"bad bool", "bool", word("02"), "improperly encoded boolean"},
+		// both elements share one tail, the decoder must not decode it twice
+		{"shared tails", "bytes[]", word("20") + word("02") + word("40") + word("40") + word("01") + word("ff"), "overlaps the previous tail"},
+	}
+	// eight overlapping inner lists each reusing the same eight words
+	amplified := word("20") + word("08")
+	for i := 0; i < 8; i++ {
+		amplified += word(fmt.Sprintf("%x", (8+i)*32))
+	}
+	amplified += strings.Repeat(word("08"), 16)
+	tests = append(tests, struct {
+		name    string
+		outputs string
+		data    string
+		err     string
+	}{"amplified tails", "uint256[][]", amplified, "offsets overlap"})
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			abi, err := ParseHumanReadable("function get() returns (" + test.outputs + ")")
+			require.NoError(t, err)
+			_, err = abi.Methods["get()"].Outputs.UnpackValues(mustHex(t, test.data))
+			require.Error(t, err)
+			require.Contains(t, err.Error(), test.err)
+		})
+	}
+}
+
+func TestUnpackNestedDynamic(t *testing.T) {
+	abi, err := ParseHumanReadable("function get() returns (bytes[] list, string s)")
+	require.NoError(t, err)
+	data := mustHex(t, word("40")+word("120")+
+		word("02")+word("40")+word("80")+
+		word("01")+word("aa"+strings.Repeat("0", 62))+
+		word("02")+word("bbcc"+strings.Repeat("0", 60))+
+		word("02")+word(hex.EncodeToString([]byte("hi"))+strings.Repeat("0", 60)))
+	values, err := abi.Methods["get()"].Outputs.UnpackValues(data)
+	require.NoError(t, err)
+	require.Equal(t, []interface{}{[][]byte{{0xaa}, {0xbb, 0xcc}}, "hi"}, values)
+}
+
+func TestUnpackZeroSizeElements(t *testing.T) {
+	for _, outputs := range []string{"uint8[0][] a", "()[] a"} {
+		_, err := ParseHumanReadable("function f() returns (" + outputs + ")")
+		require.Errorf(t, err, "%s parsed", outputs)
+	}
+
+	// a type built by hand is refused by the decoder instead
+	elem, err := NewType("uint8", nil)
+	require.NoError(t, err)
+	empty := Type{T: ArrayTy, Kind: reflect.Array, Elem: &elem, Type: reflect.ArrayOf(0, elem.Type), stringKind: "uint8[0]"}
+	slice := Type{T: SliceTy, Kind: reflect.Slice, Elem: &empty, Type: reflect.SliceOf(empty.Type), stringKind: "uint8[0][]"}
+	_, err = Arguments{{Name: "a", Type: slice}}.UnpackValues(mustHex(t, word("20")+word("05")+word("00")))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "take no bytes")
+}
@@ -4,4 +4,7 @@ type BaseResp struct {
 	Success bool   `json:"success"`
 	Code    string `json:"code"`
 	Data    string `json:"data"`
+	// OrderId is the orderId the call was sent with, the given one or the one the
+	// client generated. It is set when the call fails as well, with the error.
+	OrderId string `json:"orderId,omitempty"`
 }
@@ -1,56 +1,159 @@
 package utils
 
 import (
-	"crypto"
-	"crypto/rand"
-	"crypto/rsa"
-	"crypto/sha256"
+	"crypto/tls"
 	"crypto/x509"
 	"encoding/pem"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"sync"
+	"time"
 )
 
-func Sign(plain, priKey string) (string, error) {
-	h := sha256.New()
-	h.Write([]byte(plain))
-	d := h.Sum(nil)
+type cachedSigner struct {
+	signer  Signer
+	modTime time.Time
+	size    int64
+}
+
+// signers caches the parsed key of every file Sign was given, by path.
+var signers sync.Map
 
-	privateKey, err := getPrivateKey(priKey)
+// Sign signs plain with the private key in the PEM file priKey and returns the hex
+// encoded signature, made with SM2 for SM2 keys, see NewKeySigner. The parsed key is
+// cached until the file changes.
+func Sign(plain, priKey string) (string, error) {
+	signer, err := fileSigner(priKey)
 	if err != nil {
 		return "", err
 	}
-	sig, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, d)
+	sig, err := signer.Sign([]byte(plain))
 	if err != nil {
-		return "", fmt.Errorf("fail to SignPKCS1v15 plain:%+v priKey:%+v err:%+v", plain, priKey, err)
+		return "", fmt.Errorf("fail to sign plain:%+v priKey:%+v err:%+v", plain, priKey, err)
 	}
-
 	return fmt.Sprintf("%x", sig), nil
 }
 
-func getPrivateKey(priKey string) (*rsa.PrivateKey, error) {
-	priv, err := ioutil.ReadFile(priKey)
+func fileSigner(priKey string) (Signer, error) {
+	info, err := os.Stat(priKey)
 	if err != nil {
 		return nil, fmt.Errorf("fail to read priKey priKey:%+v err:%+v", priKey, err)
 	}
+	if cached, ok := signers.Load(priKey); ok {
+		if entry := cached.(cachedSigner); entry.modTime.Equal(info.ModTime()) && entry.size == info.Size() {
+			return entry.signer, nil
+		}
+	}
+	signer, err := NewPEMFileSigner(priKey, nil)
+	if err != nil {
+		return nil, err
+	}
+	signers.Store(priKey, cachedSigner{signer: signer, modTime: info.ModTime(), size: info.Size()})
+	return signer, nil
+}
+
+// ParseCertificates parses every CERTIFICATE block of pemData, in order.
+func ParseCertificates(pemData []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, pemData = pem.Decode(pemData)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("fail to parse certificate err:%+v", err)
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate found")
+	}
+	return certs, nil
+}
 
-	privPem, _ := pem.Decode(priv)
-	var privPemBytes []byte
-	if privPem.Type != "PRIVATE KEY" {
-		return nil, fmt.Errorf("RSA private key is of the wrong type,actual Pem Type:%+v expect Pem Type:%+v", privPem.Type, "PRIVATE KEY")
+// LoadCertificates reads the certificates of the PEM file path.
+func LoadCertificates(path string) ([]*x509.Certificate, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read certificates path:%+v err:%+v", path, err)
+	}
+	certs, err := ParseCertificates(data)
+	if err != nil {
+		return nil, fmt.Errorf("%v in %s", err, path)
 	}
-	privPemBytes = privPem.Bytes
-	var parsedKey interface{}
-	if parsedKey, err = x509.ParsePKCS1PrivateKey(privPemBytes); err != nil {
-		if parsedKey, err = x509.ParsePKCS8PrivateKey(privPemBytes); err != nil { // note this returns type `interface{}`
-			return nil, err
+	return certs, nil
+}
+
+// LoadCertPool returns a pool of the certificates of the PEM file path, a CA bundle,
+// and the certificates themselves.
+func LoadCertPool(path string) (*x509.CertPool, []*x509.Certificate, error) {
+	certs, err := LoadCertificates(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	pool := x509.NewCertPool()
+	for _, cert := range certs {
+		pool.AddCert(cert)
+	}
+	return pool, certs, nil
+}
+
+// LoadKeyPair loads a client certificate for mutual TLS: the chain in certFile, leaf
+// first, and the private key of the leaf in keyFile. The chain is checked with
+// VerifyChain.
+func LoadKeyPair(certFile, keyFile string) (tls.Certificate, []*x509.Certificate, error) {
+	keyPair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("fail to load key pair certFile:%+v err:%+v", certFile, err)
+	}
+	chain := make([]*x509.Certificate, 0, len(keyPair.Certificate))
+	for _, der := range keyPair.Certificate {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return tls.Certificate{}, nil, fmt.Errorf("fail to parse certificate certFile:%+v err:%+v", certFile, err)
 		}
+		chain = append(chain, cert)
+	}
+	if err := VerifyChain(chain, time.Now()); err != nil {
+		return tls.Certificate{}, nil, fmt.Errorf("%v in %s", err, certFile)
 	}
-	var privateKey *rsa.PrivateKey
-	var ok bool
-	privateKey, ok = parsedKey.(*rsa.PrivateKey)
-	if !ok {
-		return nil, fmt.Errorf("unable to convert to  RSA private key")
+	keyPair.Leaf = chain[0]
+	return keyPair, chain, nil
+}
+
+// VerifyChain checks that every certificate of chain is valid at now and, but for
+// the last, signed by the one after it.
+func VerifyChain(chain []*x509.Certificate, now time.Time) error {
+	for i, cert := range chain {
+		if now.Before(cert.NotBefore) {
+			return fmt.Errorf("certificate %s is not valid before %v", cert.Subject, cert.NotBefore)
+		}
+		if now.After(cert.NotAfter) {
+			return fmt.Errorf("certificate %s expired at %v", cert.Subject, cert.NotAfter)
+		}
+		if i+1 < len(chain) {
+			if err := cert.CheckSignatureFrom(chain[i+1]); err != nil {
+				return fmt.Errorf("certificate %s is not signed by %s: %v", cert.Subject, chain[i+1].Subject, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ExpiringCertificates returns the certificates of certs that expire within the
+// given duration after now.
+func ExpiringCertificates(certs []*x509.Certificate, now time.Time, within time.Duration) []*x509.Certificate {
+	var expiring []*x509.Certificate
+	for _, cert := range certs {
+		if cert.NotAfter.Before(now.Add(within)) {
+			expiring = append(expiring, cert)
+		}
 	}
-	return privateKey, nil
+	return expiring
 }
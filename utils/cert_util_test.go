@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// issue creates a certificate for name valid from notBefore to notAfter, signed by
+// parent or self signed when parent is nil.
+func issue(t *testing.T, name string, isCA bool, notBefore, notAfter time.Time, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
+
+func writePEM(t *testing.T, path string, certs ...*x509.Certificate) string {
+	var data []byte
+	for _, cert := range certs {
+		data = append(data, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})...)
+	}
+	require.NoError(t, ioutil.WriteFile(path, data, 0600))
+	return path
+}
+
+func TestLoadCertificates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restclient-cert")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	now := time.Now()
+	ca, _ := issue(t, "ca", true, now.Add(-time.Hour), now.Add(time.Hour), nil, nil)
+	other, _ := issue(t, "other", true, now.Add(-time.Hour), now.Add(time.Hour), nil, nil)
+
+	pool, certs, err := LoadCertPool(writePEM(t, filepath.Join(dir, "ca.pem"), ca, other))
+	require.NoError(t, err)
+	require.Len(t, certs, 2)
+	require.Len(t, pool.Subjects(), 2)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "empty.pem"), []byte("no pem"), 0600))
+	_, _, err = LoadCertPool(filepath.Join(dir, "empty.pem"))
+	require.Error(t, err)
+	_, err = LoadCertificates(filepath.Join(dir, "missing.pem"))
+	require.Error(t, err)
+}
+
+func TestVerifyChain(t *testing.T) {
+	now := time.Now()
+	ca, caKey := issue(t, "ca", true, now.Add(-time.Hour), now.Add(24*time.Hour), nil, nil)
+	leaf, _ := issue(t, "leaf", false, now.Add(-time.Hour), now.Add(time.Hour), ca, caKey)
+	other, _ := issue(t, "other", true, now.Add(-time.Hour), now.Add(time.Hour), nil, nil)
+
+	require.NoError(t, VerifyChain([]*x509.Certificate{leaf, ca}, now))
+	require.Error(t, VerifyChain([]*x509.Certificate{leaf, other}, now))
+	err := VerifyChain([]*x509.Certificate{leaf, ca}, now.Add(2*time.Hour))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "expired")
+	require.Error(t, VerifyChain([]*x509.Certificate{leaf, ca}, now.Add(-2*time.Hour)))
+
+	expiring := ExpiringCertificates([]*x509.Certificate{leaf, ca}, now, 2*time.Hour)
+	require.Equal(t, []*x509.Certificate{leaf}, expiring)
+	require.Empty(t, ExpiringCertificates([]*x509.Certificate{leaf, ca}, now, time.Minute))
+}
+
+func TestLoadKeyPair(t *testing.T) {
+	dir, err := ioutil.TempDir("", "restclient-cert")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	now := time.Now()
+	ca, caKey := issue(t, "ca", true, now.Add(-time.Hour), now.Add(time.Hour), nil, nil)
+	leaf, leafKey := issue(t, "leaf", false, now.Add(-time.Hour), now.Add(time.Hour), ca, caKey)
+	der, err := x509.MarshalECPrivateKey(leafKey)
+	require.NoError(t, err)
+	keyFile := filepath.Join(dir, "client.key")
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600))
+
+	keyPair, chain, err := LoadKeyPair(writePEM(t, filepath.Join(dir, "client.pem"), leaf, ca), keyFile)
+	require.NoError(t, err)
+	require.Equal(t, leaf, keyPair.Leaf)
+	require.Len(t, chain, 2)
+
+	// the chain must be in order
+	_, _, err = LoadKeyPair(writePEM(t, filepath.Join(dir, "reversed.pem"), ca, leaf), keyFile)
+	require.Error(t, err)
+	expired, expiredKey := issue(t, "leaf", false, now.Add(-2*time.Hour), now.Add(-time.Hour), ca, caKey)
+	der, err = x509.MarshalECPrivateKey(expiredKey)
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0600))
+	_, _, err = LoadKeyPair(writePEM(t, filepath.Join(dir, "expired.pem"), expired, ca), keyFile)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "expired")
+}
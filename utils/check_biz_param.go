@@ -1,171 +1,335 @@
 package utils
 
 import (
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
 	"github.com/oldercn/restclient-go-sdk/model"
 	"github.com/oldercn/restclient-go-sdk/response"
 )
 
-func CheckCallRestBizParams(callRestBizParam model.CallRestBizParam) response.BaseResp {
-	passChecked := true
-	data := ""
-
-	if callRestBizParam.AccessId == "" {
-		return response.BaseResp{Success: false, Data: "no access id"}
-	}
-	if callRestBizParam.Token == "" {
-		return response.BaseResp{Success: false, Data: "no token"}
-	}
-	if callRestBizParam.BizId == "" {
-		return response.BaseResp{Success: false, Data: "no bizid"}
-	}
-	method := callRestBizParam.Method
-	if method != model.QUERYTENANTKMSLIST && method != model.DEPOSITWITHADMIN && method != model.APPLYKEY &&
-		method != model.QUERYACCESSLIST && method != model.RESETAPPLYKEY && method != model.CREATEACCOUNT &&
-		method != model.DEPLOYNATIVECONTRACT && method != model.QUERYACCOUNT && method != model.QUERYRECEIPT &&
-		method != model.QUERYTRANSACTION && method != model.QUERYRECEIPTBIZ && method != model.QUERYTRANSACTIONBIZ &&
-		method != model.FROZENTENANT && method != model.UNFROZENTENANT {
-		if callRestBizParam.Uid == "" && callRestBizParam.MykmsKeyId == "" {
-			return response.BaseResp{Success: false, Data: "uid or mykmsKeyId must be not null"}
-		}
+// Format checks the value of a string field, see HexFormat for an example.
+type Format func(value string) error
+
+// ParamRule declares what a method needs from a model.CallRestBizParam. Fields are
+// named by their json names, e.g. "orderId" or "mykmsKeyId"; a field is present
+// when it is not the zero value.
+type ParamRule struct {
+	// Required fields must all be present.
+	Required []string
+	// OneOf lists groups of which at least one field must be present.
+	OneOf [][]string
+	// Exclusive lists groups of which at most one field may be present.
+	Exclusive [][]string
+	// Formats are checked on present fields only.
+	Formats map[string]Format
+	// MaxLength limits the length of string fields.
+	MaxLength map[string]int
+}
+
+// Violation is a single broken rule of a call.
+type Violation struct {
+	Field   string
+	Message string
+}
+
+func (v Violation) String() string {
+	return v.Field + " " + v.Message
+}
+
+// ParamError lists every rule a model.CallRestBizParam breaks.
+type ParamError struct {
+	Method     model.Method
+	Violations []Violation
+}
+
+func (e *ParamError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = violation.String()
 	}
-	if method != model.APPLYKEY && method != model.QUERYACCESSLIST && method != model.RESETAPPLYKEY &&
-		method != model.QUERYRECEIPT && method != model.QUERYTRANSACTION && method != model.FROZENTENANT &&
-		method != model.UNFROZENTENANT &&
-		callRestBizParam.OrderId == "" {
-		passChecked = false
-		data = fmt.Sprintf("%v method must has orderId", callRestBizParam.Method)
+	return fmt.Sprintf("invalid %v params: %s", e.Method, strings.Join(messages, "; "))
+}
+
+// HexFormat accepts hex strings of even length with an optional 0x prefix.
+func HexFormat(value string) error {
+	if _, err := hex.DecodeString(strings.TrimPrefix(value, "0x")); err != nil {
+		return fmt.Errorf("must be hex: %v", err)
 	}
+	return nil
+}
 
-	switch method {
-	case model.DEPOSIT:
-		if callRestBizParam.Account == "" {
-			passChecked = false
-			data = fmt.Sprintf("%v method must has account", callRestBizParam.Method)
-		}
-		if callRestBizParam.Content == "" {
-			passChecked = false
-			data = fmt.Sprintf("%v method must has content", callRestBizParam.Method)
+// JSONArrayFormat accepts json arrays, as inputParamListStr and outTypes are.
+func JSONArrayFormat(value string) error {
+	var array []json.RawMessage
+	if err := json.Unmarshal([]byte(value), &array); err != nil {
+		return fmt.Errorf("must be a json array: %v", err)
+	}
+	return nil
+}
+
+var methodSignatureRegexp = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*\(.*\)$`)
+
+// MethodSignatureFormat accepts method signatures like SayHello(bytes,string).
+func MethodSignatureFormat(value string) error {
+	if !methodSignatureRegexp.MatchString(value) {
+		return fmt.Errorf("must be a method signature like name(type1,type2)")
+	}
+	return nil
+}
+
+var (
+	// baseRule applies to every method.
+	baseRule = ParamRule{Required: []string{"accessId", "token", "bizid"}}
+
+	signer    = ParamRule{OneOf: [][]string{{"uid", "mykmsKeyId"}}}
+	withOrder = ParamRule{Required: []string{"orderId"}}
+	// defaultRule applies to methods without a rule of their own.
+	defaultRule = merge(withOrder, signer)
+
+	deposit = merge(defaultRule, ParamRule{Required: []string{"account", "content"}})
+	call    = merge(defaultRule, ParamRule{
+		Required: []string{"account", "contractName", "methodSignature", "inputParamListStr", "outTypes"},
+		Formats: map[string]Format{
+			"methodSignature":   MethodSignatureFormat,
+			"inputParamListStr": JSONArrayFormat,
+			"outTypes":          JSONArrayFormat,
+		},
+	})
+	nativeCall = merge(defaultRule, ParamRule{
+		Required: []string{"account", "contractName", "methodSignature", "nativeContractData"},
+		Formats:  map[string]Format{"methodSignature": MethodSignatureFormat},
+	})
+	deploy = merge(defaultRule, ParamRule{
+		Required: []string{"account", "contractName", "contractCode"},
+		Formats:  map[string]Format{"contractCode": HexFormat},
+	})
+	query = ParamRule{
+		Required: []string{"hash"},
+		Formats:  map[string]Format{"hash": HexFormat},
+	}
+	account = merge(defaultRule, ParamRule{Required: []string{"account"}})
+
+	paramRulesLock sync.RWMutex
+	paramRules     = map[model.Method]ParamRule{
+		model.DEPOSIT:                        deposit,
+		model.DEPOSITTEST:                    deposit,
+		model.DEPOSITWITHADMIN:               merge(withOrder, ParamRule{Required: []string{"content"}}),
+		model.QUERYRECEIPT:                   query,
+		model.QUERYTRANSACTION:               query,
+		model.QUERYTRANSACTIONFROMBLOCKCHAIN: merge(defaultRule, query),
+		model.QUERYRECEIPTBIZ:                withOrder, // looked up by orderId
+		model.QUERYTRANSACTIONBIZ:            withOrder,
+		model.CALLCONTRACT:                   call,
+		model.CALLCONTRACTBIZ:                call,
+		model.CALLCONTRACTBIZASYNC:           call,
+		model.CALLWASMCONTRACT:               call,
+		model.CALLWASMCONTRACTASYNC:          call,
+		model.CALLNATIVECONTRACT:             nativeCall,
+		model.CALLNATIVECONTRACTASYNC:        nativeCall,
+		model.CALLNATIVECONTRACTFORBIZ:       nativeCall,
+		model.CALLNATIVECONTRACTFORBIZASYNC:  nativeCall,
+		model.DEPLOYCONTRACT:                 deploy,
+		model.DEPLOYCONTRACTFORBIZ:           deploy,
+		model.DEPLOYWASMCONTRACT:             deploy,
+		model.UPDATECONTRACT:                 deploy,
+		model.UPDATECONTRACTFORBIZ:           deploy,
+		model.DEPLOYNATIVECONTRACT:           withOrder,
+		model.QUERYBLOCK:                     defaultRule,
+		model.QUERYBLOCKBODY:                 defaultRule,
+		model.QUERYLASTBLOCK:                 defaultRule,
+		model.QUERYBLOCKHEADERINFOSRAW:       defaultRule,
+		model.CREATEACCOUNT:                  merge(withOrder, ParamRule{Required: []string{"account", "mykmsKeyId"}}),
+		model.TENANTCREATEACCOUNT:            merge(account, ParamRule{Required: []string{"newAccountId", "newAccountKmsId"}}),
+		model.FREEZEACCOUNTASYN:              account,
+		model.UNFREEZEACCOUNTASYN:            account,
+		model.QUERYACCOUNT:                   withOrder,
+		model.SIGNHASH:                       merge(defaultRule, query),
+		model.PARSEOUTPUT:                    defaultRule,
+		model.INVITEUSER:                     defaultRule,
+		model.NEWCHAIN:                       defaultRule,
+		model.GETMYTFINFO:                    defaultRule,
+		model.GETTAPPINFO:                    defaultRule,
+		model.INSTALLTAPP:                    defaultRule,
+		model.EXECUTETAPP:                    defaultRule,
+		model.EXECUTETAPPPRIVATE:             defaultRule,
+		model.UPDATERESOURCEMAP:              defaultRule,
+		model.GETRESOURCEMAP:                 defaultRule,
+		model.SETRESOURCEMAP:                 defaultRule,
+		model.GETEVENTTOPICBLOCKNUM:          defaultRule,
+		model.UPDATEEVENTTOPICBLOCKNUM:       defaultRule,
+		model.APPLYKEY:                       {},
+		model.QUERYACCESSLIST:                {},
+		model.RESETAPPLYKEY:                  {},
+		model.QUERYTENANTKMSLIST:             withOrder,
+		model.FROZENTENANT:                   {},
+		model.UNFROZENTENANT:                 {},
+		model.REGISTERBLOCKCHAINCONFIG:       defaultRule,
+	}
+)
+
+// RegisterParamRule sets the rule of method, replacing the built-in one. The
+// access id, token and bizid are always required on top of it.
+func RegisterParamRule(method model.Method, rule ParamRule) {
+	paramRulesLock.Lock()
+	defer paramRulesLock.Unlock()
+	paramRules[method] = rule
+}
+
+// LookupParamRule returns the rule of method, methods without one get the default
+// rule requiring an orderId and either uid or mykmsKeyId.
+func LookupParamRule(method model.Method) ParamRule {
+	paramRulesLock.RLock()
+	defer paramRulesLock.RUnlock()
+	if rule, ok := paramRules[method]; ok {
+		return rule
+	}
+	return defaultRule
+}
+
+// ValidateCallRestBizParams checks callRestBizParam against the rule of its method
+// and returns every violation in a *ParamError.
+func ValidateCallRestBizParams(callRestBizParam model.CallRestBizParam) error {
+	rule := merge(baseRule, LookupParamRule(callRestBizParam.Method))
+	fields := paramFields(callRestBizParam)
+	var violations []Violation
+
+	for _, name := range rule.Required {
+		if !fields.present(name) {
+			violations = append(violations, Violation{Field: name, Message: "is required"})
 		}
-	case model.CALLCONTRACTBIZ:
-	case model.CALLCONTRACTBIZASYNC:
-		if callRestBizParam.Account == "" {
-			passChecked = false
-			data = fmt.Sprintf("%v method must has account", callRestBizParam.Method)
+	}
+	for _, group := range rule.OneOf {
+		if fields.count(group) == 0 {
+			violations = append(violations, Violation{Field: strings.Join(group, "|"), Message: "requires one of the fields"})
 		}
-		if callRestBizParam.ContractName == "" {
-			passChecked = false
-			data = fmt.Sprintf("%v method must has contract name", callRestBizParam.Method)
+	}
+	for _, group := range rule.Exclusive {
+		if fields.count(group) > 1 {
+			violations = append(violations, Violation{Field: strings.Join(group, "|"), Message: "are mutually exclusive"})
 		}
-		if callRestBizParam.OutTypes == "" {
-			passChecked = false
-			data = fmt.Sprintf("%v method must has outTypes", callRestBizParam.Method)
+	}
+	for _, name := range sortedKeys(rule.MaxLength) {
+		if value := fields.str(name); len(value) > rule.MaxLength[name] {
+			violations = append(violations, Violation{Field: name, Message: fmt.Sprintf("is longer than %d", rule.MaxLength[name])})
 		}
-		if callRestBizParam.MethodSignature == "" {
-			passChecked = false
-			data = fmt.Sprintf("%v method must has methodSignature", callRestBizParam.Method)
+	}
+	for _, name := range sortedKeys(rule.Formats) {
+		if value := fields.str(name); value != "" {
+			if err := rule.Formats[name](value); err != nil {
+				violations = append(violations, Violation{Field: name, Message: err.Error()})
+			}
 		}
-		if callRestBizParam.InputParamListStr == "" {
-			passChecked = false
-			data = fmt.Sprintf("%v method must has inputParamListStr", callRestBizParam.Method)
+	}
+
+	if len(violations) > 0 {
+		return &ParamError{Method: callRestBizParam.Method, Violations: violations}
+	}
+	return nil
+}
+
+// Deprecated: use ValidateCallRestBizParams, which reports every violation.
+func CheckCallRestBizParams(callRestBizParam model.CallRestBizParam) response.BaseResp {
+	if err := ValidateCallRestBizParams(callRestBizParam); err != nil {
+		return response.BaseResp{Success: false, Data: err.Error()}
+	}
+	return response.BaseResp{Success: true}
+}
+
+// merge combines rules, a field required by any of them is required by the result.
+func merge(rules ...ParamRule) ParamRule {
+	var merged ParamRule
+	for _, rule := range rules {
+		for _, name := range rule.Required {
+			if !contains(merged.Required, name) {
+				merged.Required = append(merged.Required, name)
+			}
 		}
-	//case model.CALLNATIVECONTRACTFORBIZASYNC:
-	//case model.CALLNATIVECONTRACTFORBIZ:
-	//	if callRestBizParam.Account == "" {
-	//		passChecked = false
-	//		data = fmt.Sprintf("%v method must has account", callRestBizParam.Method)
-	//	}
-	//	if callRestBizParam.ContractName == "" {
-	//		passChecked = false
-	//		data = fmt.Sprintf("%v method must has contract name", callRestBizParam.Method)
-	//	}
-	//	if callRestBizParam.MethodSignature == "" {
-	//		passChecked = false
-	//		data = fmt.Sprintf("%v method must has methodSignature", callRestBizParam.Method)
-	//	}
-	//	if callRestBizParam.NativeContractData == "" {
-	//		passChecked = false
-	//		data = fmt.Sprintf("%v method must has nativeContractData", callRestBizParam.Method)
-	//	}
-	case model.QUERYRECEIPT:
-	case model.QUERYTRANSACTION:
-		if callRestBizParam.Hash == "" {
-			passChecked = false
-			data = fmt.Sprintf("%v method must has hash", callRestBizParam.Method)
+		merged.OneOf = append(merged.OneOf, rule.OneOf...)
+		merged.Exclusive = append(merged.Exclusive, rule.Exclusive...)
+		for name, format := range rule.Formats {
+			if merged.Formats == nil {
+				merged.Formats = make(map[string]Format)
+			}
+			merged.Formats[name] = format
 		}
-	case model.DEPLOYCONTRACTFORBIZ:
-		if callRestBizParam.Account == "" {
-			passChecked = false
-			data = fmt.Sprintf("%v method must has account", callRestBizParam.Method)
+		for name, length := range rule.MaxLength {
+			if merged.MaxLength == nil {
+				merged.MaxLength = make(map[string]int)
+			}
+			merged.MaxLength[name] = length
 		}
-		if callRestBizParam.ContractName == "" {
-			passChecked = false
-			data = fmt.Sprintf("%v method must has contract name", callRestBizParam.Method)
+	}
+	return merged
+}
+
+// fieldValues maps the json names of a CallRestBizParam to their values.
+type fieldValues map[string]reflect.Value
+
+func paramFields(param model.CallRestBizParam) fieldValues {
+	fields := make(fieldValues)
+	collectFields(reflect.ValueOf(param), fields)
+	return fields
+}
+
+func collectFields(value reflect.Value, fields fieldValues) {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			collectFields(value.Field(i), fields)
+			continue
 		}
-		if callRestBizParam.ContractCode == "" {
-			passChecked = false
-			data = fmt.Sprintf("%v method must has contractCode", callRestBizParam.Method)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = field.Name
 		}
-	//case model.DEPLOYWASMCONTRACT:
-	//	if callRestBizParam.Account == "" {
-	//		passChecked = false
-	//		data = fmt.Sprintf("%v method must has account", callRestBizParam.Method)
-	//	}
-	//	if callRestBizParam.ContractName == "" {
-	//		passChecked = false
-	//		data = fmt.Sprintf("%v method must has contract name", callRestBizParam.Method)
-	//	}
-	//	if callRestBizParam.ContractCode == "" {
-	//		passChecked = false
-	//		data = fmt.Sprintf("%v method must has contractCode", callRestBizParam.Method)
-	//	}
-	//	break
-	//case model.CALLWASMCONTRACT:
-	//case model.CALLWASMCONTRACTASYNC:
-	//	if callRestBizParam.Account == "" {
-	//		passChecked = false
-	//		data = fmt.Sprintf("%v method must has account", callRestBizParam.Method)
-	//	}
-	//	if callRestBizParam.ContractName == "" {
-	//		passChecked = false
-	//		data = fmt.Sprintf("%v method must has contract name", callRestBizParam.Method)
-	//	}
-	//	if callRestBizParam.OutTypes == "" {
-	//		passChecked = false
-	//		data = fmt.Sprintf("%v method must has outTypes", callRestBizParam.Method)
-	//	}
-	//	if callRestBizParam.MethodSignature == "" {
-	//		passChecked = false
-	//		data = fmt.Sprintf("%v method must has methodSignature", callRestBizParam.Method)
-	//	}
-	//	if callRestBizParam.InputParamListStr == "" {
-	//		passChecked = false
-	//		data = fmt.Sprintf("%v method must has inputParamListStr", callRestBizParam.Method)
-	//	}
-	//case model.UPDATECONTRACTFORBIZ:
-	//	if callRestBizParam.Account == "" {
-	//		passChecked = false
-	//		data = fmt.Sprintf("%v method must has account", callRestBizParam.Method)
-	//	}
-	//	if callRestBizParam.ContractName == "" {
-	//		passChecked = false
-	//		data = fmt.Sprintf("%v method must has contract name", callRestBizParam.Method)
-	//	}
-	//	if callRestBizParam.ContractCode == "" {
-	//		passChecked = false
-	//		data = fmt.Sprintf("%v method must has contractCode", callRestBizParam.Method)
-	//	}
-	case model.CREATEACCOUNT:
-		if callRestBizParam.Account == "" {
-			passChecked = false
-			data = fmt.Sprintf("%v method must has account", callRestBizParam.Method)
+		fields[name] = value.Field(i)
+	}
+}
+
+func (fields fieldValues) present(name string) bool {
+	value, ok := fields[name]
+	return ok && !reflect.DeepEqual(value.Interface(), reflect.Zero(value.Type()).Interface())
+}
+
+func (fields fieldValues) count(names []string) int {
+	n := 0
+	for _, name := range names {
+		if fields.present(name) {
+			n++
 		}
-		if callRestBizParam.MykmsKeyId == "" {
-			passChecked = false
-			data = fmt.Sprintf("%v method must has mykmsKeyId", callRestBizParam.Method)
+	}
+	return n
+}
+
+func (fields fieldValues) str(name string) string {
+	if value, ok := fields[name]; ok && value.Kind() == reflect.String {
+		return value.String()
+	}
+	return ""
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
 		}
 	}
+	return false
+}
 
-	resp := response.BaseResp{Success: passChecked, Data: data}
-	return resp
+func sortedKeys(m interface{}) []string {
+	keys := reflect.ValueOf(m).MapKeys()
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = key.String()
+	}
+	sort.Strings(names)
+	return names
 }
@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/oldercn/restclient-go-sdk/model"
+	"github.com/stretchr/testify/require"
+)
+
+func validParam(method model.Method) model.CallRestBizParam {
+	return model.CallRestBizParam{
+		BaseParam:  model.BaseParam{AccessId: "access", Token: "token", BizId: "biz", Method: method},
+		OrderId:    "order_1",
+		MykmsKeyId: "kms",
+	}
+}
+
+func TestValidateCallRestBizParams(t *testing.T) {
+	param := validParam(model.CALLCONTRACTBIZASYNC)
+	param.Account = "alice"
+	param.ContractName = "hello"
+	param.MethodSignature = "SayHello(bytes,string)"
+	param.InputParamListStr = `["AAEC","hello"]`
+	param.OutTypes = `["bytes","string"]`
+	require.NoError(t, ValidateCallRestBizParams(param))
+
+	param.Uid = "uid"
+	param.MethodSignature = "SayHello"
+	param.OutTypes = "bytes"
+	param.Account = ""
+	err := ValidateCallRestBizParams(param)
+	require.IsType(t, &ParamError{}, err)
+	require.Equal(t, []Violation{
+		{Field: "account", Message: "is required"},
+		{Field: "methodSignature", Message: "must be a method signature like name(type1,type2)"},
+		{Field: "outTypes", Message: "must be a json array: invalid character 'b' looking for beginning of value"},
+	}, err.(*ParamError).Violations)
+}
+
+func TestValidateEveryMethod(t *testing.T) {
+	err := ValidateCallRestBizParams(model.CallRestBizParam{BaseParam: model.BaseParam{Method: model.QUERYRECEIPT}})
+	require.EqualError(t, err, "invalid QUERYRECEIPT params: accessId is required; token is required; bizid is required; hash is required")
+
+	param := validParam(model.DEPLOYCONTRACTFORBIZ)
+	param.Account = "alice"
+	param.ContractName = "hello"
+	param.ContractCode = "0x60zz"
+	err = ValidateCallRestBizParams(param)
+	require.EqualError(t, err, "invalid DEPLOYCONTRACTFORBIZ params: contractCode must be hex: encoding/hex: invalid byte: U+007A 'z'")
+
+	param = validParam(model.DEPOSIT)
+	param.MykmsKeyId = ""
+	param.OrderId = ""
+	err = ValidateCallRestBizParams(param)
+	require.EqualError(t, err, "invalid DEPOSIT params: orderId is required; account is required; content is required; uid|mykmsKeyId requires one of the fields")
+
+	// the biz queries look up by orderId alone
+	for _, method := range []model.Method{model.QUERYRECEIPTBIZ, model.QUERYTRANSACTIONBIZ} {
+		param = validParam(method)
+		param.MykmsKeyId = ""
+		require.NoError(t, ValidateCallRestBizParams(param))
+	}
+
+	require.NoError(t, ValidateCallRestBizParams(model.CallRestBizParam{BaseParam: model.BaseParam{AccessId: "access", Token: "token", BizId: "biz", Method: model.APPLYKEY}}))
+}
+
+func TestRegisterParamRule(t *testing.T) {
+	const custom model.Method = "CUSTOMMETHOD"
+	param := validParam(custom)
+	require.NoError(t, ValidateCallRestBizParams(param))
+
+	RegisterParamRule(custom, ParamRule{Required: []string{"content"}, Formats: map[string]Format{"content": HexFormat}})
+	defer func() {
+		paramRulesLock.Lock()
+		delete(paramRules, custom)
+		paramRulesLock.Unlock()
+	}()
+	require.EqualError(t, ValidateCallRestBizParams(param), "invalid CUSTOMMETHOD params: content is required")
+	param.Content = "abcd"
+	require.NoError(t, ValidateCallRestBizParams(param))
+
+	RegisterParamRule(custom, ParamRule{Exclusive: [][]string{{"uid", "mykmsKeyId"}}, MaxLength: map[string]int{"orderId": 4}})
+	param.Uid = "uid"
+	require.EqualError(t, ValidateCallRestBizParams(param), "invalid CUSTOMMETHOD params: uid|mykmsKeyId are mutually exclusive; orderId is longer than 4")
+}
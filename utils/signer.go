@@ -0,0 +1,276 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/oldercn/restclient-go-sdk/utils/sm2"
+)
+
+const (
+	// SignAlgorithmSHA256 signs the sha256 digest with an RSA or ECDSA key.
+	SignAlgorithmSHA256 = "SHA256"
+	// SignAlgorithmSM2 signs with an SM2 key and SM3 digest, see package sm2.
+	SignAlgorithmSM2 = "SM2"
+)
+
+// Signer signs the handshake of a rest client with the private key of its access id.
+type Signer interface {
+	// Sign returns the signature of plain, see NewKeySigner for the formats.
+	Sign(plain []byte) ([]byte, error)
+	// Public returns the key that verifies the signatures, see VerifySignature.
+	Public() crypto.PublicKey
+}
+
+type keySigner struct {
+	key crypto.Signer
+}
+
+// NewKeySigner signs with key, an *rsa.PrivateKey, an *ecdsa.PrivateKey, an
+// *sm2.PrivateKey or any other crypto.Signer such as a key kept in an HSM. RSA keys
+// sign PKCS#1 v1.5, ECDSA and SM2 keys return ASN.1 encoded signatures. SM2 keys sign
+// as sm2.DefaultUID.
+func NewKeySigner(key crypto.Signer) Signer {
+	return keySigner{key: key}
+}
+
+func (s keySigner) Sign(plain []byte) ([]byte, error) {
+	if key, ok := s.key.(*sm2.PrivateKey); ok {
+		// SM2 digests the message itself
+		return key.Sign(rand.Reader, plain, nil)
+	}
+	digest := sha256.Sum256(plain)
+	return s.key.Sign(rand.Reader, digest[:], crypto.SHA256)
+}
+
+func (s keySigner) Public() crypto.PublicKey {
+	return s.key.Public()
+}
+
+// NewPEMSigner signs with the PEM encoded private key in pemData. PKCS#1, PKCS#8 and
+// SEC 1 EC keys are supported, as well as PKCS#8 keys encrypted with passphrase. Keys
+// on the curve sm2p256v1 sign with SM2.
+func NewPEMSigner(pemData, passphrase []byte) (Signer, error) {
+	key, err := parsePrivateKey(pemData, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeySigner(key), nil
+}
+
+// NewPEMFileSigner is NewPEMSigner with the key read from path. The file is read
+// once, later changes to it are not picked up, see NewRotatingPEMFileSigner.
+func NewPEMFileSigner(path string, passphrase []byte) (Signer, error) {
+	pemData, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read priKey priKey:%+v err:%+v", path, err)
+	}
+	signer, err := NewPEMSigner(pemData, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("fail to parse priKey priKey:%+v err:%+v", path, err)
+	}
+	return signer, nil
+}
+
+// NewRotatingPEMFileSigner is NewPEMFileSigner for an unencrypted key that is read
+// again when the modification time or size of path changes, like Sign does, so a key
+// rotated in place signs from the next Sign on.
+func NewRotatingPEMFileSigner(path string) (Signer, error) {
+	signer, err := fileSigner(path)
+	if err != nil {
+		return nil, err
+	}
+	rotating := &rotatingFileSigner{path: path}
+	rotating.last.Store(signer)
+	return rotating, nil
+}
+
+type rotatingFileSigner struct {
+	path string
+	last atomic.Value // Signer of the key read last
+}
+
+func (s *rotatingFileSigner) Sign(plain []byte) ([]byte, error) {
+	signer, err := fileSigner(s.path)
+	if err != nil {
+		return nil, err
+	}
+	s.last.Store(signer)
+	return signer.Sign(plain)
+}
+
+// Public returns the key of the last Sign, the key file is not read again.
+func (s *rotatingFileSigner) Public() crypto.PublicKey {
+	return s.last.Load().(Signer).Public()
+}
+
+// NewEnvSigner is NewPEMSigner with the key read from the environment variable name.
+// As not every environment holds multiple lines, an escaped \n counts as a newline.
+func NewEnvSigner(name string, passphrase []byte) (Signer, error) {
+	pemData, ok := os.LookupEnv(name)
+	if !ok {
+		return nil, fmt.Errorf("environment variable %s is not set", name)
+	}
+	signer, err := NewPEMSigner([]byte(strings.Replace(pemData, `\n`, "\n", -1)), passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("fail to parse priKey from %s err:%+v", name, err)
+	}
+	return signer, nil
+}
+
+func parsePrivateKey(pemData, passphrase []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("no PEM encoded private key found")
+	}
+	var parsedKey interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		// some tools put PKCS#1 keys in PRIVATE KEY blocks
+		if parsedKey, err = parsePKCS8PrivateKey(block.Bytes); err != nil {
+			if key, pkcs1Err := x509.ParsePKCS1PrivateKey(block.Bytes); pkcs1Err == nil {
+				parsedKey, err = key, nil
+			}
+		}
+	case "RSA PRIVATE KEY":
+		parsedKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY", "SM2 PRIVATE KEY":
+		if parsedKey, err = x509.ParseECPrivateKey(block.Bytes); err != nil {
+			if key, sm2Err := sm2.ParseECPrivateKey(block.Bytes); sm2Err == nil {
+				parsedKey, err = key, nil
+			}
+		}
+	case "ENCRYPTED PRIVATE KEY":
+		if len(passphrase) == 0 {
+			return nil, errors.New("private key is encrypted but no passphrase is given")
+		}
+		der, decryptErr := decryptPKCS8(block.Bytes, passphrase)
+		if decryptErr != nil {
+			return nil, decryptErr
+		}
+		parsedKey, err = parsePKCS8PrivateKey(der)
+	default:
+		return nil, fmt.Errorf("unsupported private key Pem Type:%+v", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsedKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unable to sign with a %T", parsedKey)
+	}
+	return key, nil
+}
+
+func parsePKCS8PrivateKey(der []byte) (interface{}, error) {
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		if sm2Key, sm2Err := sm2.ParsePKCS8PrivateKey(der); sm2Err == nil {
+			return sm2Key, nil
+		}
+	}
+	return key, err
+}
+
+// SignerAlgorithm returns the algorithm signer signs with, SignAlgorithmSM2 for SM2
+// keys and SignAlgorithmSHA256 for all others.
+func SignerAlgorithm(signer Signer) string {
+	if _, ok := signer.Public().(*sm2.PublicKey); ok {
+		return SignAlgorithmSM2
+	}
+	return SignAlgorithmSHA256
+}
+
+// CheckSignAlgorithm returns an error unless signer signs with algorithm, the empty
+// algorithm accepts every signer.
+func CheckSignAlgorithm(signer Signer, algorithm string) error {
+	if algorithm == "" || SignerAlgorithm(signer) == algorithm {
+		return nil
+	}
+	return fmt.Errorf("key signs with %s, not %s", SignerAlgorithm(signer), algorithm)
+}
+
+// Verify checks sig, a signature of plain as returned by Sign, against the public key
+// or certificate in the PEM file pubKey.
+func Verify(plain, sig, pubKey string) error {
+	pemData, err := ioutil.ReadFile(pubKey)
+	if err != nil {
+		return fmt.Errorf("fail to read pubKey pubKey:%+v err:%+v", pubKey, err)
+	}
+	publicKey, err := ParsePublicKey(pemData)
+	if err != nil {
+		return err
+	}
+	signature, err := hex.DecodeString(sig)
+	if err != nil {
+		return fmt.Errorf("signature is not hex: %v", err)
+	}
+	return VerifySignature(publicKey, []byte(plain), signature)
+}
+
+// ParsePublicKey parses a PEM encoded PKIX or PKCS#1 public key or a certificate.
+func ParsePublicKey(pemData []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("no PEM encoded public key found")
+	}
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			if sm2Key, sm2Err := sm2.ParsePKIXPublicKey(block.Bytes); sm2Err == nil {
+				return sm2Key, nil
+			}
+		}
+		return key, err
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	}
+	return nil, fmt.Errorf("unsupported public key Pem Type:%+v", block.Type)
+}
+
+// VerifySignature checks signature is a signature of plain made by a Signer holding
+// the private key of publicKey.
+func VerifySignature(publicKey crypto.PublicKey, plain, signature []byte) error {
+	digest := sha256.Sum256(plain)
+	switch key := publicKey.(type) {
+	case *sm2.PublicKey:
+		if !sm2.VerifyASN1(key, sm2.DefaultUID, plain, signature) {
+			return errors.New("sm2 verification error")
+		}
+		return nil
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature)
+	case *ecdsa.PublicKey:
+		var sig struct{ R, S *big.Int }
+		if rest, err := asn1.Unmarshal(signature, &sig); err != nil || len(rest) > 0 {
+			return errors.New("malformed ecdsa signature")
+		}
+		if !ecdsa.Verify(key, digest[:], sig.R, sig.S) {
+			return errors.New("ecdsa verification error")
+		}
+		return nil
+	}
+	return fmt.Errorf("unable to verify with a %T", publicKey)
+}
@@ -0,0 +1,190 @@
+package sm2
+
+import (
+	"math/big"
+	"sync"
+)
+
+// Curve is a short Weierstrass curve y² = x³ + ax + b over GF(p). Unlike
+// elliptic.CurveParams it does not assume a = -3, so the example curves of
+// GB/T 32918 can be used too. The arithmetic is plain big.Int and not constant time.
+type Curve struct {
+	Name    string
+	P, A, B *big.Int
+	N       *big.Int // order of the base point
+	Gx, Gy  *big.Int
+	BitSize int
+}
+
+var (
+	initOnce sync.Once
+	p256     *Curve
+)
+
+func hexInt(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("sm2: bad curve constant " + s)
+	}
+	return n
+}
+
+// P256 returns the curve sm2p256v1 of GB/T 32918.5.
+func P256() *Curve {
+	initOnce.Do(func() {
+		p256 = &Curve{
+			Name:    "sm2p256v1",
+			P:       hexInt("FFFFFFFEFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF00000000FFFFFFFFFFFFFFFF"),
+			A:       hexInt("FFFFFFFEFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF00000000FFFFFFFFFFFFFFFC"),
+			B:       hexInt("28E9FA9E9D9F5E344D5A9E4BCF6509A7F39789F515AB8F92DDBCBD414D940E93"),
+			N:       hexInt("FFFFFFFEFFFFFFFFFFFFFFFFFFFFFFFF7203DF6B21C6052B53BBF40939D54123"),
+			Gx:      hexInt("32C4AE2C1F1981195F9904466A39C9948FE30BBFF2660BE1715A4589334C74C7"),
+			Gy:      hexInt("BC3736A2F4F6779C59BDCEE36B692153D0A9877CC62A474002DF32E52139F0A0"),
+			BitSize: 256,
+		}
+	})
+	return p256
+}
+
+// byteLen is the length of a coordinate in bytes.
+func (curve *Curve) byteLen() int {
+	return (curve.P.BitLen() + 7) / 8
+}
+
+// IsOnCurve reports whether (x, y) is an affine point of the curve.
+func (curve *Curve) IsOnCurve(x, y *big.Int) bool {
+	if x == nil || y == nil || x.Sign() < 0 || x.Cmp(curve.P) >= 0 || y.Sign() < 0 || y.Cmp(curve.P) >= 0 {
+		return false
+	}
+	left := new(big.Int).Mul(y, y)
+	left.Mod(left, curve.P)
+	right := new(big.Int).Mul(x, x)
+	right.Add(right, curve.A)
+	right.Mul(right, x)
+	right.Add(right, curve.B)
+	right.Mod(right, curve.P)
+	return left.Cmp(right) == 0
+}
+
+// jacobian is the point (X/Z², Y/Z³), Z = 0 is the point at infinity.
+type jacobian struct {
+	x, y, z *big.Int
+}
+
+func (curve *Curve) toJacobian(x, y *big.Int) jacobian {
+	return jacobian{new(big.Int).Set(x), new(big.Int).Set(y), big.NewInt(1)}
+}
+
+// toAffine returns 0, 0 for the point at infinity.
+func (curve *Curve) toAffine(p jacobian) (*big.Int, *big.Int) {
+	if p.z.Sign() == 0 {
+		return new(big.Int), new(big.Int)
+	}
+	zInv := new(big.Int).ModInverse(p.z, curve.P)
+	zInv2 := new(big.Int).Mul(zInv, zInv)
+	x := new(big.Int).Mul(p.x, zInv2)
+	x.Mod(x, curve.P)
+	y := zInv2.Mul(zInv2, zInv)
+	y.Mul(y, p.y)
+	y.Mod(y, curve.P)
+	return x, y
+}
+
+func (curve *Curve) double(p jacobian) jacobian {
+	if p.z.Sign() == 0 || p.y.Sign() == 0 {
+		return jacobian{new(big.Int), new(big.Int), new(big.Int)}
+	}
+	mod := func(n *big.Int) *big.Int { return n.Mod(n, curve.P) }
+	xx := mod(new(big.Int).Mul(p.x, p.x))
+	yy := mod(new(big.Int).Mul(p.y, p.y))
+	zz := mod(new(big.Int).Mul(p.z, p.z))
+	// s = 4xy², m = 3x² + az⁴
+	s := mod(new(big.Int).Lsh(new(big.Int).Mul(p.x, yy), 2))
+	m := new(big.Int).Mul(zz, zz)
+	m.Mul(m, curve.A)
+	m.Add(m, new(big.Int).Mul(xx, big.NewInt(3)))
+	mod(m)
+
+	x3 := new(big.Int).Mul(m, m)
+	x3.Sub(x3, new(big.Int).Lsh(s, 1))
+	mod(x3)
+	y3 := new(big.Int).Sub(s, x3)
+	y3.Mul(y3, m)
+	y3.Sub(y3, new(big.Int).Lsh(new(big.Int).Mul(yy, yy), 3))
+	mod(y3)
+	z3 := new(big.Int).Mul(p.y, p.z)
+	z3.Lsh(z3, 1)
+	mod(z3)
+	return jacobian{x3, y3, z3}
+}
+
+func (curve *Curve) add(p, q jacobian) jacobian {
+	if p.z.Sign() == 0 {
+		return q
+	}
+	if q.z.Sign() == 0 {
+		return p
+	}
+	mod := func(n *big.Int) *big.Int { return n.Mod(n, curve.P) }
+	z1z1 := mod(new(big.Int).Mul(p.z, p.z))
+	z2z2 := mod(new(big.Int).Mul(q.z, q.z))
+	u1 := mod(new(big.Int).Mul(p.x, z2z2))
+	u2 := mod(new(big.Int).Mul(q.x, z1z1))
+	s1 := mod(new(big.Int).Mul(new(big.Int).Mul(p.y, q.z), z2z2))
+	s2 := mod(new(big.Int).Mul(new(big.Int).Mul(q.y, p.z), z1z1))
+	if u1.Cmp(u2) == 0 {
+		if s1.Cmp(s2) == 0 {
+			return curve.double(p)
+		}
+		return jacobian{new(big.Int), new(big.Int), new(big.Int)}
+	}
+	h := mod(new(big.Int).Sub(u2, u1))
+	r := mod(new(big.Int).Sub(s2, s1))
+	hh := mod(new(big.Int).Mul(h, h))
+	hhh := mod(new(big.Int).Mul(h, hh))
+	v := mod(new(big.Int).Mul(u1, hh))
+
+	x3 := new(big.Int).Mul(r, r)
+	x3.Sub(x3, hhh)
+	x3.Sub(x3, new(big.Int).Lsh(v, 1))
+	mod(x3)
+	y3 := new(big.Int).Sub(v, x3)
+	y3.Mul(y3, r)
+	y3.Sub(y3, new(big.Int).Mul(s1, hhh))
+	mod(y3)
+	z3 := new(big.Int).Mul(p.z, q.z)
+	z3.Mul(z3, h)
+	mod(z3)
+	return jacobian{x3, y3, z3}
+}
+
+// Add returns the sum of the affine points (x1, y1) and (x2, y2).
+func (curve *Curve) Add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	return curve.toAffine(curve.add(curve.toJacobian(x1, y1), curve.toJacobian(x2, y2)))
+}
+
+// ScalarMult returns k·(x, y), k is a big endian integer.
+func (curve *Curve) ScalarMult(x, y *big.Int, k []byte) (*big.Int, *big.Int) {
+	return curve.toAffine(curve.scalarMult(curve.toJacobian(x, y), new(big.Int).SetBytes(k)))
+}
+
+// ScalarBaseMult returns k·G, k is a big endian integer.
+func (curve *Curve) ScalarBaseMult(k []byte) (*big.Int, *big.Int) {
+	return curve.ScalarMult(curve.Gx, curve.Gy, k)
+}
+
+// scalarMult is a Montgomery ladder, which does the same operations for every bit.
+func (curve *Curve) scalarMult(p jacobian, k *big.Int) jacobian {
+	r0 := jacobian{new(big.Int), new(big.Int), new(big.Int)}
+	r1 := p
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		if k.Bit(i) == 0 {
+			r1 = curve.add(r0, r1)
+			r0 = curve.double(r0)
+		} else {
+			r0 = curve.add(r0, r1)
+			r1 = curve.double(r1)
+		}
+	}
+	return r0
+}
@@ -0,0 +1,206 @@
+// Package sm2 implements SM2 signatures with SM3 digests as specified in
+// GB/T 32918.2-2016, and the encoding of SM2 keys in PKCS#8, SEC 1 and PKIX.
+package sm2
+
+import (
+	"crypto"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/oldercn/restclient-go-sdk/utils/sm3"
+)
+
+// DefaultUID is the signer id GB/T 35276 recommends when the parties agreed on none.
+var DefaultUID = []byte("1234567812345678")
+
+var one = big.NewInt(1)
+
+type PublicKey struct {
+	Curve *Curve
+	X, Y  *big.Int
+}
+
+type PrivateKey struct {
+	PublicKey
+	D *big.Int
+}
+
+// SignerOpts selects the signer id of PrivateKey.Sign, which uses DefaultUID without.
+type SignerOpts struct {
+	UID []byte
+}
+
+// HashFunc returns 0 since SM2 hashes the message itself.
+func (opts *SignerOpts) HashFunc() crypto.Hash {
+	return 0
+}
+
+// Public returns the public key of priv.
+func (priv *PrivateKey) Public() crypto.PublicKey {
+	return &priv.PublicKey
+}
+
+// Sign returns the ASN.1 encoded signature of msg. Unlike for other crypto.Signers
+// msg is the message, not its digest, since the SM3 digest covers the public key and
+// signer id as well. opts may be a *SignerOpts.
+func (priv *PrivateKey) Sign(rand io.Reader, msg []byte, opts crypto.SignerOpts) ([]byte, error) {
+	uid := DefaultUID
+	if sm2Opts, ok := opts.(*SignerOpts); ok && sm2Opts.UID != nil {
+		uid = sm2Opts.UID
+	}
+	r, s, err := Sign(rand, priv, uid, msg)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(signature{r, s})
+}
+
+type signature struct {
+	R, S *big.Int
+}
+
+// GenerateKey returns a new key of curve.
+func GenerateKey(curve *Curve, rand io.Reader) (*PrivateKey, error) {
+	// d+1 must be invertible, so d < n-1
+	d, err := randScalar(curve, rand, new(big.Int).Sub(curve.N, one))
+	if err != nil {
+		return nil, err
+	}
+	return NewPrivateKey(curve, d)
+}
+
+// NewPrivateKey returns the key of curve with the secret scalar d.
+func NewPrivateKey(curve *Curve, d *big.Int) (*PrivateKey, error) {
+	if d.Sign() <= 0 || d.Cmp(new(big.Int).Sub(curve.N, one)) >= 0 {
+		return nil, errors.New("sm2: private key out of range")
+	}
+	priv := &PrivateKey{D: new(big.Int).Set(d)}
+	priv.Curve = curve
+	priv.X, priv.Y = curve.ScalarBaseMult(d.Bytes())
+	return priv, nil
+}
+
+// randScalar returns a uniform random integer in [1, max).
+func randScalar(curve *Curve, rand io.Reader, max *big.Int) (*big.Int, error) {
+	// 64 more bits than needed make the modulo bias negligible
+	b := make([]byte, curve.N.BitLen()/8+8)
+	if _, err := io.ReadFull(rand, b); err != nil {
+		return nil, err
+	}
+	k := new(big.Int).SetBytes(b)
+	k.Mod(k, new(big.Int).Sub(max, one))
+	return k.Add(k, one), nil
+}
+
+// ZA returns the digest of the signer id and public key that SM2 prefixes to the
+// message, see GB/T 32918.2 5.5.
+func ZA(pub *PublicKey, uid []byte) ([]byte, error) {
+	bitLen := len(uid) * 8
+	if bitLen > 0xffff {
+		return nil, errors.New("sm2: signer id too long")
+	}
+	h := sm3.New()
+	h.Write([]byte{byte(bitLen >> 8), byte(bitLen)})
+	h.Write(uid)
+	size := pub.Curve.byteLen()
+	for _, n := range []*big.Int{pub.Curve.A, pub.Curve.B, pub.Curve.Gx, pub.Curve.Gy, pub.X, pub.Y} {
+		h.Write(padded(n, size))
+	}
+	return h.Sum(nil), nil
+}
+
+func padded(n *big.Int, size int) []byte {
+	b := n.Bytes()
+	if len(b) >= size {
+		return b
+	}
+	return append(make([]byte, size-len(b)), b...)
+}
+
+// digest returns e = SM3(ZA || msg) as an integer.
+func digest(pub *PublicKey, uid, msg []byte) (*big.Int, error) {
+	za, err := ZA(pub, uid)
+	if err != nil {
+		return nil, err
+	}
+	h := sm3.New()
+	h.Write(za)
+	h.Write(msg)
+	return new(big.Int).SetBytes(h.Sum(nil)), nil
+}
+
+// Sign signs msg as the signer uid and returns the signature as a pair of integers.
+func Sign(rand io.Reader, priv *PrivateKey, uid, msg []byte) (r, s *big.Int, err error) {
+	e, err := digest(&priv.PublicKey, uid, msg)
+	if err != nil {
+		return nil, nil, err
+	}
+	for {
+		k, err := randScalar(priv.Curve, rand, priv.Curve.N)
+		if err != nil {
+			return nil, nil, err
+		}
+		if r, s = signWithK(priv, e, k); r != nil {
+			return r, s, nil
+		}
+	}
+}
+
+// signWithK returns nil if k does not give a valid signature and another is needed.
+func signWithK(priv *PrivateKey, e, k *big.Int) (r, s *big.Int) {
+	n := priv.Curve.N
+	x1, _ := priv.Curve.ScalarBaseMult(k.Bytes())
+	r = new(big.Int).Add(e, x1)
+	r.Mod(r, n)
+	if r.Sign() == 0 || new(big.Int).Add(r, k).Cmp(n) == 0 {
+		return nil, nil
+	}
+	// s = (1+d)⁻¹ (k - rd) mod n
+	s = new(big.Int).Mul(r, priv.D)
+	s.Sub(k, s)
+	dInv := new(big.Int).Add(priv.D, one)
+	dInv.ModInverse(dInv, n)
+	s.Mul(s, dInv)
+	s.Mod(s, n)
+	if s.Sign() == 0 {
+		return nil, nil
+	}
+	return r, s
+}
+
+// Verify reports whether r, s is a signature of msg by the signer uid holding pub.
+func Verify(pub *PublicKey, uid, msg []byte, r, s *big.Int) bool {
+	curve := pub.Curve
+	n := curve.N
+	if r.Sign() <= 0 || s.Sign() <= 0 || r.Cmp(n) >= 0 || s.Cmp(n) >= 0 || !curve.IsOnCurve(pub.X, pub.Y) {
+		return false
+	}
+	e, err := digest(pub, uid, msg)
+	if err != nil {
+		return false
+	}
+	t := new(big.Int).Add(r, s)
+	t.Mod(t, n)
+	if t.Sign() == 0 {
+		return false
+	}
+	point := curve.add(curve.scalarMult(curve.toJacobian(curve.Gx, curve.Gy), s), curve.scalarMult(curve.toJacobian(pub.X, pub.Y), t))
+	if point.z.Sign() == 0 {
+		return false
+	}
+	x1, _ := curve.toAffine(point)
+	x1.Add(x1, e)
+	x1.Mod(x1, n)
+	return x1.Cmp(r) == 0
+}
+
+// VerifyASN1 is Verify with the signature encoded as PrivateKey.Sign does.
+func VerifyASN1(pub *PublicKey, uid, msg, sig []byte) bool {
+	var parsed signature
+	if rest, err := asn1.Unmarshal(sig, &parsed); err != nil || len(rest) > 0 {
+		return false
+	}
+	return Verify(pub, uid, msg, parsed.R, parsed.S)
+}
@@ -0,0 +1,166 @@
+package sm2
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func hexBytes(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// testCurve is the example curve of GB/T 32918.2 appendix A, whose a is not -3.
+var testCurve = &Curve{
+	Name:    "example Fp-256",
+	P:       hexInt("8542D69E4C044F18E8B92435BF6FF7DE457283915C45517D722EDB8B08F1DFC3"),
+	A:       hexInt("787968B4FA32C3FD2417842E73BBFEFF2F3C848B6831D7E0EC65228B3937E498"),
+	B:       hexInt("63E4C6D3B23B0C849CF84241484BFE48F61D59A5B16BA06E6E12D1DA27C5249A"),
+	N:       hexInt("8542D69E4C044F18E8B92435BF6FF7DD297720630485628D5AE74EE7C32E79B7"),
+	Gx:      hexInt("421DEBD61B62EAB6746434EBC3CC315E32220B3BADD50BDC4C4E6C147FEDD43D"),
+	Gy:      hexInt("0680512BCBB42C07D47349D2153B70C4E5D7FDFCBFA36EA1A85841B9E46E09A2"),
+	BitSize: 256,
+}
+
+func TestCurve(t *testing.T) {
+	for _, curve := range []*Curve{P256(), testCurve} {
+		require.True(t, curve.IsOnCurve(curve.Gx, curve.Gy), curve.Name)
+		x, y := curve.ScalarBaseMult(curve.N.Bytes())
+		require.Zero(t, x.Sign(), "nG is the point at infinity")
+		require.Zero(t, y.Sign())
+
+		// 2G + 3G = 5G = G + 4G
+		x2, y2 := curve.ScalarBaseMult([]byte{2})
+		x3, y3 := curve.ScalarBaseMult([]byte{3})
+		x5, y5 := curve.ScalarBaseMult([]byte{5})
+		sumX, sumY := curve.Add(x2, y2, x3, y3)
+		require.Equal(t, x5, sumX)
+		require.Equal(t, y5, sumY)
+		require.True(t, curve.IsOnCurve(x5, y5))
+	}
+}
+
+func TestSignVectors(t *testing.T) {
+	for _, tc := range []struct {
+		curve             *Curve
+		uid, d, k         string
+		x, y, za, e, r, s string
+	}{
+		{
+			// GB/T 32918.2 appendix A.2
+			curve: testCurve,
+			uid:   "ALICE123@YAHOO.COM",
+			d:     "128B2FA8BD433C6C068C8D803DFF79792A519A55171B1B650C23661D15897263",
+			k:     "6CB28D99385C175C94F94E934817663FC176D925DD72B727260DBAAE1FB2F96F",
+			x:     "0AE4C7798AA0F119471BEE11825BE46202BB79E2A5844495E97C04FF4DF2548A",
+			y:     "7C0240F88F1CD4E16352A73C17B7F16F07353E53A176D684A9FE0C6BB798E857",
+			za:    "F4A38489E32B45B6F876E3AC2168CA392362DC8F23459C1D1146FC3DBFB7BC9A",
+			e:     "B524F552CD82B8B028476E005C377FB19A87E6FC682D48BB5D42E3D9B9EFFE76",
+			r:     "40F1EC59F793D9F49E09DCEF49130D4194F79FB1EED2CAA55BACDB49C4E755D1",
+			s:     "6FC6DAC32C5D5CF10C77DFB20F7C2EB667A457872FB09EC56327A67EC7DEEBE7",
+		},
+		{
+			// GM/T 0003.5 on sm2p256v1
+			curve: P256(),
+			uid:   "1234567812345678",
+			d:     "3945208F7B2144B13F36E38AC6D39F95889393692860B51A42FB81EF4DF7C5B8",
+			k:     "59276E27D506861A16680F3AD9C02DCCEF3CC1FA3CDBE4CE6D54B80DEAC1BC21",
+			x:     "09F9DF311E5421A150DD7D161E4BC5C672179FAD1833FC076BB08FF356F35020",
+			y:     "CCEA490CE26775A52DC6EA718CC1AA600AED05FBF35E084A6632F6072DA9AD13",
+			e:     "F0B43E94BA45ACCAACE692ED534382EB17E6AB5A19CE7B31F4486FDFC0D28640",
+			r:     "F5A03B0648D2C4630EEAC513E1BB81A15944DA3827D5B74143AC7EACEEE720B3",
+			s:     "B1B6AA29DF212FD8763182BC0D421CA1BB9038FD1F7F42D4840B69C485BBC1AA",
+		},
+	} {
+		msg := []byte("message digest")
+		priv, err := NewPrivateKey(tc.curve, hexInt(tc.d))
+		require.NoError(t, err)
+		require.Equal(t, hexInt(tc.x), priv.X)
+		require.Equal(t, hexInt(tc.y), priv.Y)
+		if tc.za != "" {
+			za, err := ZA(&priv.PublicKey, []byte(tc.uid))
+			require.NoError(t, err)
+			require.Equal(t, hexBytes(tc.za), za)
+		}
+		e, err := digest(&priv.PublicKey, []byte(tc.uid), msg)
+		require.NoError(t, err)
+		require.Equal(t, hexInt(tc.e), e)
+
+		r, s := signWithK(priv, e, hexInt(tc.k))
+		require.Equal(t, hexInt(tc.r), r)
+		require.Equal(t, hexInt(tc.s), s)
+		require.True(t, Verify(&priv.PublicKey, []byte(tc.uid), msg, r, s))
+		require.False(t, Verify(&priv.PublicKey, []byte("another id"), msg, r, s))
+		require.False(t, Verify(&priv.PublicKey, []byte(tc.uid), []byte("message digesT"), r, s))
+		require.False(t, Verify(&priv.PublicKey, []byte(tc.uid), msg, s, r))
+	}
+}
+
+func TestSignRandom(t *testing.T) {
+	priv, err := GenerateKey(P256(), rand.Reader)
+	require.NoError(t, err)
+	sig, err := priv.Sign(rand.Reader, []byte("plain"), nil)
+	require.NoError(t, err)
+	require.True(t, VerifyASN1(&priv.PublicKey, DefaultUID, []byte("plain"), sig))
+	require.False(t, VerifyASN1(&priv.PublicKey, DefaultUID, []byte("plain"), sig[1:]))
+
+	sig, err = priv.Sign(rand.Reader, []byte("plain"), &SignerOpts{UID: []byte("ALICE123@YAHOO.COM")})
+	require.NoError(t, err)
+	require.True(t, VerifyASN1(&priv.PublicKey, []byte("ALICE123@YAHOO.COM"), []byte("plain"), sig))
+	require.False(t, VerifyASN1(&priv.PublicKey, DefaultUID, []byte("plain"), sig))
+
+	_, err = NewPrivateKey(P256(), new(big.Int).Sub(P256().N, one))
+	require.Error(t, err)
+}
+
+// testdata was written by OpenSSL 3:
+//
+//	openssl genpkey -algorithm SM2 -out sm2.pem
+//	openssl pkeyutl -sign -in msg.txt -inkey sm2.pem -rawin -digest sm3 -pkeyopt distid:1234567812345678 -out msg.sig
+func TestOpenSSLCompatibility(t *testing.T) {
+	readPEM := func(path string) []byte {
+		data, err := ioutil.ReadFile(path)
+		require.NoError(t, err)
+		block, _ := pem.Decode(data)
+		require.NotNil(t, block, path)
+		return block.Bytes
+	}
+	priv, err := ParsePKCS8PrivateKey(readPEM("testdata/sm2.pem"))
+	require.NoError(t, err)
+	sec1, err := ParseECPrivateKey(readPEM("testdata/sm2_sec1.pem"))
+	require.NoError(t, err)
+	require.Equal(t, priv.D, sec1.D)
+	pub, err := ParsePKIXPublicKey(readPEM("testdata/sm2_pub.pem"))
+	require.NoError(t, err)
+	require.Equal(t, priv.PublicKey, *pub)
+
+	msg, err := ioutil.ReadFile("testdata/msg.txt")
+	require.NoError(t, err)
+	sig, err := ioutil.ReadFile("testdata/msg.sig")
+	require.NoError(t, err)
+	require.True(t, VerifyASN1(pub, DefaultUID, msg, sig))
+
+	// what we marshal parses back
+	der, err := MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	parsed, err := ParsePKCS8PrivateKey(der)
+	require.NoError(t, err)
+	require.Equal(t, priv, parsed)
+	der, err = MarshalECPrivateKey(priv)
+	require.NoError(t, err)
+	parsed, err = ParseECPrivateKey(der)
+	require.NoError(t, err)
+	require.Equal(t, priv, parsed)
+	der, err = MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	require.Equal(t, readPEM("testdata/sm2_pub.pem"), der)
+}
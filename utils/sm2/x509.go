@@ -0,0 +1,162 @@
+package sm2
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var (
+	oidPublicKeyEC = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	// OIDCurveP256 names sm2p256v1, some encoders use it as key algorithm as well.
+	OIDCurveP256 = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 301}
+)
+
+type pkcs8 struct {
+	Version    int
+	Algo       pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+// ecPrivateKey is the SEC 1 structure of RFC 5915.
+type ecPrivateKey struct {
+	Version       int
+	PrivateKey    []byte
+	NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+type pkixPublicKey struct {
+	Algo      pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// isSM2 reports whether algo is an SM2 key, as an ecPublicKey on sm2p256v1 or by its own OID.
+func isSM2(algo pkix.AlgorithmIdentifier) bool {
+	if algo.Algorithm.Equal(OIDCurveP256) {
+		return true
+	}
+	var curve asn1.ObjectIdentifier
+	if !algo.Algorithm.Equal(oidPublicKeyEC) {
+		return false
+	}
+	if _, err := asn1.Unmarshal(algo.Parameters.FullBytes, &curve); err != nil {
+		return false
+	}
+	return curve.Equal(OIDCurveP256)
+}
+
+func algorithmIdentifier() (pkix.AlgorithmIdentifier, error) {
+	params, err := asn1.Marshal(OIDCurveP256)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+	return pkix.AlgorithmIdentifier{Algorithm: oidPublicKeyEC, Parameters: asn1.RawValue{FullBytes: params}}, nil
+}
+
+// ParsePKCS8PrivateKey parses an unencrypted PKCS#8 SM2 private key.
+func ParsePKCS8PrivateKey(der []byte) (*PrivateKey, error) {
+	var key pkcs8
+	if _, err := asn1.Unmarshal(der, &key); err != nil {
+		return nil, fmt.Errorf("sm2: malformed PKCS#8 private key: %v", err)
+	}
+	if !isSM2(key.Algo) {
+		return nil, errors.New("sm2: PKCS#8 private key is not an SM2 key")
+	}
+	return ParseECPrivateKey(key.PrivateKey)
+}
+
+// MarshalPKCS8PrivateKey encodes key as unencrypted PKCS#8.
+func MarshalPKCS8PrivateKey(key *PrivateKey) ([]byte, error) {
+	algo, err := algorithmIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	// the curve is named by the algorithm already
+	ecKey, err := marshalECPrivateKey(key, nil)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(pkcs8{Algo: algo, PrivateKey: ecKey})
+}
+
+// ParseECPrivateKey parses a SEC 1 SM2 private key, as found in EC PRIVATE KEY blocks.
+func ParseECPrivateKey(der []byte) (*PrivateKey, error) {
+	var key ecPrivateKey
+	if _, err := asn1.Unmarshal(der, &key); err != nil {
+		return nil, fmt.Errorf("sm2: malformed EC private key: %v", err)
+	}
+	if key.Version != 1 {
+		return nil, fmt.Errorf("sm2: unknown EC private key version %d", key.Version)
+	}
+	if len(key.NamedCurveOID) > 0 && !key.NamedCurveOID.Equal(OIDCurveP256) {
+		return nil, fmt.Errorf("sm2: EC private key is on curve %v, not sm2p256v1", key.NamedCurveOID)
+	}
+	curve := P256()
+	if len(key.PrivateKey) > curve.byteLen() {
+		return nil, errors.New("sm2: EC private key too long")
+	}
+	return NewPrivateKey(curve, new(big.Int).SetBytes(key.PrivateKey))
+}
+
+// MarshalECPrivateKey encodes key as SEC 1.
+func MarshalECPrivateKey(key *PrivateKey) ([]byte, error) {
+	return marshalECPrivateKey(key, OIDCurveP256)
+}
+
+func marshalECPrivateKey(key *PrivateKey, curveOID asn1.ObjectIdentifier) ([]byte, error) {
+	point := marshalPoint(&key.PublicKey)
+	return asn1.Marshal(ecPrivateKey{
+		Version:       1,
+		PrivateKey:    padded(key.D, key.Curve.byteLen()),
+		NamedCurveOID: curveOID,
+		PublicKey:     asn1.BitString{Bytes: point, BitLength: len(point) * 8},
+	})
+}
+
+// ParsePKIXPublicKey parses an SM2 public key in PKIX form, as found in PUBLIC KEY blocks.
+func ParsePKIXPublicKey(der []byte) (*PublicKey, error) {
+	var key pkixPublicKey
+	if _, err := asn1.Unmarshal(der, &key); err != nil {
+		return nil, fmt.Errorf("sm2: malformed public key: %v", err)
+	}
+	if !isSM2(key.Algo) {
+		return nil, errors.New("sm2: public key is not an SM2 key")
+	}
+	return unmarshalPoint(key.PublicKey.RightAlign())
+}
+
+// MarshalPKIXPublicKey encodes pub in PKIX form.
+func MarshalPKIXPublicKey(pub *PublicKey) ([]byte, error) {
+	algo, err := algorithmIdentifier()
+	if err != nil {
+		return nil, err
+	}
+	point := marshalPoint(pub)
+	return asn1.Marshal(pkixPublicKey{Algo: algo, PublicKey: asn1.BitString{Bytes: point, BitLength: len(point) * 8}})
+}
+
+// marshalPoint returns the uncompressed form 04 || x || y.
+func marshalPoint(pub *PublicKey) []byte {
+	size := pub.Curve.byteLen()
+	return append(append([]byte{4}, padded(pub.X, size)...), padded(pub.Y, size)...)
+}
+
+func unmarshalPoint(data []byte) (*PublicKey, error) {
+	curve := P256()
+	size := curve.byteLen()
+	if len(data) != 1+2*size || data[0] != 4 {
+		return nil, errors.New("sm2: public key is not an uncompressed point")
+	}
+	pub := &PublicKey{
+		Curve: curve,
+		X:     new(big.Int).SetBytes(data[1 : 1+size]),
+		Y:     new(big.Int).SetBytes(data[1+size:]),
+	}
+	if !curve.IsOnCurve(pub.X, pub.Y) {
+		return nil, errors.New("sm2: public key is not on the curve")
+	}
+	return pub, nil
+}
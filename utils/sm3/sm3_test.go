@@ -0,0 +1,35 @@
+package sm3
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSum(t *testing.T) {
+	// GB/T 32905-2016 appendix A, plus lengths around the padding boundary
+	for _, tc := range []struct {
+		in, out string
+	}{
+		{"abc", "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"},
+		{strings.Repeat("abcd", 16), "debe9ff92275b8a138604889c18e5a4d6fdb70e5387e5765293dcba39c0c5732"},
+		{"", "1ab21d8355cfa17f8e61194831e81a8f22bec8c728fefb747ed035eb5082aa2b"},
+	} {
+		sum := Sum([]byte(tc.in))
+		require.Equal(t, tc.out, hex.EncodeToString(sum[:]), tc.in)
+
+		// the same through a hash.Hash fed in uneven pieces
+		h := New()
+		for i := 0; i < len(tc.in); i += 7 {
+			end := i + 7
+			if end > len(tc.in) {
+				end = len(tc.in)
+			}
+			h.Write([]byte(tc.in[i:end]))
+		}
+		require.Equal(t, tc.out, hex.EncodeToString(h.Sum(nil)))
+		require.Equal(t, tc.out, hex.EncodeToString(h.Sum(nil)), "Sum must not change the state")
+	}
+}